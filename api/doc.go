@@ -0,0 +1,5 @@
+/*
+Package api contains the JSON types of the admin endpoints of the switchboard server, which are shared by the
+server and the client used by the CLI.
+*/
+package api
@@ -0,0 +1,183 @@
+package api
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Error is the response of a failed request. Diagnostics are only set when a configuration is invalid
+type Error struct {
+	Error       string       `json:"error"`
+	Diagnostics []Diagnostic `json:"diagnostics,omitempty"`
+}
+
+// Diagnostic is the JSON representation of an hcl.Diagnostic
+type Diagnostic struct {
+	Severity string `json:"severity"`
+	Summary  string `json:"summary"`
+	Detail   string `json:"detail,omitempty"`
+	Range    *Range `json:"range,omitempty"`
+}
+
+// Range is the location of a diagnostic in a configuration file
+type Range struct {
+	Filename string   `json:"filename"`
+	Start    Position `json:"start"`
+	End      Position `json:"end"`
+}
+
+type Position struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+// Deployment is the configuration running on the server
+type Deployment struct {
+	ID         string    `json:"id"`
+	DeployedAt time.Time `json:"deployed_at"`
+	DeployedBy string    `json:"deployed_by"`
+	Hash       string    `json:"hash"`
+	// Local is true when the server runs the configuration in its working directory, because nothing was deployed
+	Local     bool     `json:"local"`
+	Workflows []string `json:"workflows"`
+	Triggers  []string `json:"triggers"`
+}
+
+// Triggered is the response to a trigger request
+type Triggered struct {
+	Trigger string `json:"trigger"`
+	// Workflows are the workflows started by the trigger
+	Workflows []string `json:"workflows"`
+}
+
+// Destroyed is the response of destroying the running configuration
+type Destroyed struct {
+	// ID is the id of the deployment that records the destroy
+	ID string `json:"id"`
+	// Workflows are the workflows that were stopped
+	Workflows []string `json:"workflows"`
+	// Triggers are the triggers that were deregistered
+	Triggers []string `json:"triggers"`
+	// Plugins are the provider plugins that were killed
+	Plugins []string `json:"plugins"`
+}
+
+// Trigger is a trigger of the running configuration
+type Trigger struct {
+	Name string `json:"name"`
+	// Type is either 'schedule' or 'provider'
+	Type     string    `json:"type"`
+	Provider string    `json:"provider,omitempty"`
+	Function string    `json:"function,omitempty"`
+	URL      string    `json:"url,omitempty"`
+	Schedule *Schedule `json:"schedule,omitempty"`
+	// Registration is nil if the trigger is not registered with its integration
+	Registration *Registration `json:"registration,omitempty"`
+	Workflows    []string      `json:"workflows"`
+}
+
+type Schedule struct {
+	Expression string    `json:"expression"`
+	Timezone   string    `json:"timezone"`
+	MissedRuns string    `json:"missed_runs"`
+	NextRunAt  time.Time `json:"next_run_at"`
+}
+
+// Registration is the registration of a trigger with its integration
+type Registration struct {
+	ExternalID   string    `json:"external_id"`
+	RegisteredAt time.Time `json:"registered_at"`
+}
+
+// Workflow is a workflow of the running configuration
+type Workflow struct {
+	Name      string `json:"name"`
+	Trigger   string `json:"trigger,omitempty"`
+	Steps     []Step `json:"steps"`
+	OnFailure []Step `json:"on_failure"`
+}
+
+// Step is a node of the step graph of a workflow, with edges to the steps it depends on
+type Step struct {
+	Name        string   `json:"name"`
+	Provider    string   `json:"provider"`
+	Action      string   `json:"action"`
+	DependsOn   []string `json:"depends_on"`
+	Conditional bool     `json:"conditional"`
+	ForEach     bool     `json:"for_each"`
+	Idempotent  bool     `json:"idempotent"`
+	OnError     string   `json:"on_error"`
+	OnErrorStep string   `json:"on_error_step,omitempty"`
+}
+
+// Plugin is a provider plugin loaded by the server
+type Plugin struct {
+	Name    string `json:"name"`
+	Source  string `json:"source"`
+	Version string `json:"version"`
+}
+
+// Run is a single execution of a workflow
+type Run struct {
+	ID           string     `json:"id"`
+	Workflow     string     `json:"workflow"`
+	DeploymentID string     `json:"deployment_id"`
+	Status       string     `json:"status"`
+	Error        string     `json:"error,omitempty"`
+	StartedAt    time.Time  `json:"started_at"`
+	FinishedAt   *time.Time `json:"finished_at"`
+	// Steps are only set when a single run is requested
+	Steps []StepResult `json:"steps,omitempty"`
+}
+
+// StepResult is the result of a single step in a run
+type StepResult struct {
+	Step       string          `json:"step"`
+	Status     string          `json:"status"`
+	Input      json.RawMessage `json:"input,omitempty"`
+	Output     json.RawMessage `json:"output,omitempty"`
+	Error      string          `json:"error,omitempty"`
+	Attempts   int             `json:"attempts"`
+	StartedAt  time.Time       `json:"started_at"`
+	FinishedAt *time.Time      `json:"finished_at"`
+}
+
+// EventType is the kind of change an Event reports
+type EventType string
+
+const (
+	// EventRun is sent when a run starts or finishes
+	EventRun EventType = "run"
+	// EventStep is sent when a step of a run starts or finishes
+	EventStep EventType = "step"
+	// EventPlugin is sent when a provider plugin is loaded or killed
+	EventPlugin EventType = "plugin"
+	// EventDeployment is sent when a new configuration is deployed
+	EventDeployment EventType = "deployment"
+)
+
+// Event is a change on the server, sent by the event stream
+type Event struct {
+	Type EventType `json:"type"`
+	Time time.Time `json:"time"`
+	// Workflow and RunID are set for run and step events
+	Workflow string `json:"workflow,omitempty"`
+	RunID    string `json:"run_id,omitempty"`
+	Step     string `json:"step,omitempty"`
+	// Plugin is set for plugin events
+	Plugin string `json:"plugin,omitempty"`
+	// Status is the status of the run or step, or 'loaded', 'failed' or 'killed' for plugins
+	Status  string `json:"status,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+// EventFilter limits the events of a stream. Zero values are ignored, while set values only match events
+// with the same value, so filtering by workflow leaves out plugin and deployment events.
+type EventFilter struct {
+	Workflow string
+	RunID    string
+}
+
+func (f EventFilter) Matches(event Event) bool {
+	return (f.Workflow == "" || f.Workflow == event.Workflow) && (f.RunID == "" || f.RunID == event.RunID)
+}
@@ -0,0 +1,191 @@
+package bundle
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/switchboard-org/switchboard/parsecfg"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+const (
+	// MaxFileSize is the largest file a bundle can contain
+	MaxFileSize = 8 << 20
+	// MaxSize is the largest total size of the files in a bundle, after decompression
+	MaxSize = 32 << 20
+	// MaxFiles is the largest number of files a bundle can contain
+	MaxFiles = 1024
+)
+
+// Read extracts a tar archive of a configuration directory, which may be gzip compressed, into an
+// in-memory file system. Only regular files and directories are allowed, and every path must stay inside
+// the root of the archive. Archives with more than MaxFiles files or MaxSize bytes are rejected.
+func Read(r io.Reader) (fs.FS, error) {
+	reader := bufio.NewReader(r)
+	if magic, err := reader.Peek(2); err == nil && bytes.Equal(magic, []byte{0x1f, 0x8b}) {
+		gzipReader, err := gzip.NewReader(reader)
+		if err != nil {
+			return nil, fmt.Errorf("invalid gzip data: %w", err)
+		}
+		defer gzipReader.Close()
+		return readTar(gzipReader)
+	}
+	return readTar(reader)
+}
+
+func readTar(r io.Reader) (fs.FS, error) {
+	files := memFS{}
+	var size int64
+	archive := tar.NewReader(r)
+	for {
+		header, err := archive.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid tar archive: %w", err)
+		}
+		name, err := cleanPath(header.Name)
+		if err != nil {
+			return nil, err
+		}
+		switch header.Typeflag {
+		case tar.TypeDir:
+			continue
+		case tar.TypeReg:
+			if name == "." {
+				return nil, fmt.Errorf("'%s' is not a valid file name", header.Name)
+			}
+		default:
+			return nil, fmt.Errorf("'%s' is not a regular file", header.Name)
+		}
+		if header.Size > MaxFileSize {
+			return nil, fmt.Errorf("'%s' is larger than %d bytes", header.Name, MaxFileSize)
+		}
+		if len(files) >= MaxFiles {
+			return nil, fmt.Errorf("archive has more than %d files", MaxFiles)
+		}
+		if size += header.Size; size > MaxSize {
+			return nil, fmt.Errorf("archive files are larger than %d bytes", MaxSize)
+		}
+		data, err := io.ReadAll(io.LimitReader(archive, MaxFileSize))
+		if err != nil {
+			return nil, fmt.Errorf("could not read '%s': %w", header.Name, err)
+		}
+		if _, ok := files[name]; ok {
+			return nil, fmt.Errorf("'%s' is in the archive more than once", name)
+		}
+		files[name] = data
+	}
+	if len(files) == 0 {
+		return nil, errors.New("archive has no files")
+	}
+	for name := range files {
+		for dir := path.Dir(name); dir != "."; dir = path.Dir(dir) {
+			if _, ok := files[dir]; ok {
+				return nil, fmt.Errorf("'%s' is both a file and a directory", dir)
+			}
+		}
+	}
+	return files, nil
+}
+
+// cleanPath returns the slash separated path of an archive entry relative to the root of the archive
+func cleanPath(name string) (string, error) {
+	cleaned := path.Clean(name)
+	if !fs.ValidPath(cleaned) {
+		return "", fmt.Errorf("'%s' is outside of the archive root", name)
+	}
+	return cleaned, nil
+}
+
+// Hash returns the sha256 content hash of a file system, which only depends on the paths and contents of
+// its files
+func Hash(fsys fs.FS) (string, error) {
+	var names []string
+	err := fs.WalkDir(fsys, ".", func(name string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.Type().IsRegular() {
+			names = append(names, name)
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	sort.Strings(names)
+	hash := sha256.New()
+	for _, name := range names {
+		data, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(hash, "%s\x00%d\x00", name, len(data))
+		hash.Write(data)
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// Pack creates a gzip compressed tar archive of the configuration files in root, which can be read with Read.
+// The archive holds the files found by parsecfg.FindConfigFiles, which pin the provider versions in their
+// required_provider blocks. It is deterministic: the same files always produce the same bytes, as files are sorted by name and their
+// modification times, owners and permissions are not kept.
+func Pack(root string) ([]byte, error) {
+	files := make(map[string]string)
+	for _, file := range parsecfg.FindConfigFiles(root) {
+		name, err := filepath.Rel(root, file)
+		if err != nil {
+			return nil, err
+		}
+		files[filepath.ToSlash(name)] = file
+	}
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var buf bytes.Buffer
+	gzipWriter := gzip.NewWriter(&buf)
+	archive := tar.NewWriter(gzipWriter)
+	for _, name := range names {
+		data, err := os.ReadFile(files[name])
+		if err != nil {
+			return nil, err
+		}
+		header := &tar.Header{
+			Name:     name,
+			Typeflag: tar.TypeReg,
+			Mode:     0644,
+			Size:     int64(len(data)),
+			ModTime:  time.Unix(0, 0),
+			Format:   tar.FormatUSTAR,
+		}
+		if err = archive.WriteHeader(header); err != nil {
+			return nil, err
+		}
+		if _, err = archive.Write(data); err != nil {
+			return nil, err
+		}
+	}
+	if err := archive.Close(); err != nil {
+		return nil, err
+	}
+	if err := gzipWriter.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
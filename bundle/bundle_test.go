@@ -0,0 +1,238 @@
+package bundle
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+type testEntry struct {
+	name     string
+	typeflag byte
+	data     string
+}
+
+func testArchive(t *testing.T, compress bool, entries ...testEntry) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	var gzipWriter *gzip.Writer
+	writer := tar.NewWriter(&buf)
+	if compress {
+		gzipWriter = gzip.NewWriter(&buf)
+		writer = tar.NewWriter(gzipWriter)
+	}
+	for _, entry := range entries {
+		header := &tar.Header{Name: entry.name, Typeflag: entry.typeflag, Mode: 0644, Size: int64(len(entry.data))}
+		if entry.typeflag == tar.TypeSymlink {
+			header.Linkname = entry.data
+			header.Size = 0
+		}
+		if err := writer.WriteHeader(header); err != nil {
+			t.Fatal(err)
+		}
+		if entry.typeflag == tar.TypeReg {
+			if _, err := writer.Write([]byte(entry.data)); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if gzipWriter != nil {
+		if err := gzipWriter.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return buf.Bytes()
+}
+
+// testEntries returns count files of size bytes each
+func testEntries(count int, size int) []testEntry {
+	entries := make([]testEntry, count)
+	for i := range entries {
+		entries[i] = testEntry{name: fmt.Sprintf("file_%d.hcl", i), typeflag: tar.TypeReg, data: strings.Repeat("#", size)}
+	}
+	return entries
+}
+
+func TestRead(t *testing.T) {
+	tests := []struct {
+		name      string
+		archive   []byte
+		wantFiles map[string]string
+		wantErr   bool
+	}{
+		{
+			name: "should read a tar archive",
+			archive: testArchive(t, false,
+				testEntry{name: "./", typeflag: tar.TypeDir},
+				testEntry{name: "./main.hcl", typeflag: tar.TypeReg, data: "workflow"},
+				testEntry{name: "./workflows/", typeflag: tar.TypeDir},
+				testEntry{name: "./workflows/charge.hcl", typeflag: tar.TypeReg, data: "step"},
+			),
+			wantFiles: map[string]string{"main.hcl": "workflow", "workflows/charge.hcl": "step"},
+		},
+		{
+			name:      "should read a gzip compressed tar archive",
+			archive:   testArchive(t, true, testEntry{name: "main.hcl", typeflag: tar.TypeReg, data: "workflow"}),
+			wantFiles: map[string]string{"main.hcl": "workflow"},
+		},
+		{
+			name:    "should fail for paths outside of the archive root",
+			archive: testArchive(t, false, testEntry{name: "../main.hcl", typeflag: tar.TypeReg, data: "workflow"}),
+			wantErr: true,
+		},
+		{
+			name:    "should fail for absolute paths",
+			archive: testArchive(t, false, testEntry{name: "/etc/main.hcl", typeflag: tar.TypeReg, data: "workflow"}),
+			wantErr: true,
+		},
+		{
+			name:    "should fail for symlinks",
+			archive: testArchive(t, false, testEntry{name: "main.hcl", typeflag: tar.TypeSymlink, data: "/etc/passwd"}),
+			wantErr: true,
+		},
+		{
+			name:    "should fail for an archive without files",
+			archive: testArchive(t, false, testEntry{name: "./", typeflag: tar.TypeDir}),
+			wantErr: true,
+		},
+		{
+			name:    "should fail for a path that is both a file and a directory",
+			archive: testArchive(t, false, testEntry{name: "main.hcl", typeflag: tar.TypeReg, data: "workflow"}, testEntry{name: "main.hcl/charge.hcl", typeflag: tar.TypeReg, data: "step"}),
+			wantErr: true,
+		},
+		{
+			name:    "should fail for an archive with too many files",
+			archive: testArchive(t, true, testEntries(MaxFiles+1, 1)...),
+			wantErr: true,
+		},
+		{
+			name:    "should fail for an archive with too many bytes",
+			archive: testArchive(t, true, testEntries(MaxSize/MaxFileSize+1, MaxFileSize)...),
+			wantErr: true,
+		},
+		{
+			name:    "should fail for data that is not an archive",
+			archive: []byte("not an archive"),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Read(bytes.NewReader(tt.archive))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Read() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			for name, want := range tt.wantFiles {
+				data, err := fs.ReadFile(got, name)
+				if err != nil || string(data) != want {
+					t.Errorf("Read() file %s = %q, %v, want %q", name, data, err, want)
+				}
+			}
+			var names []string
+			for name := range tt.wantFiles {
+				names = append(names, name)
+			}
+			if err = fstest.TestFS(got, names...); err != nil {
+				t.Errorf("Read() file system is invalid: %v", err)
+			}
+		})
+	}
+}
+
+func TestHash(t *testing.T) {
+	files := fstest.MapFS{
+		"main.hcl":             {Data: []byte("workflow")},
+		"workflows/charge.hcl": {Data: []byte("step")},
+	}
+	hash, err := Hash(files)
+	if err != nil {
+		t.Fatal(err)
+	}
+	archive := testArchive(t, true,
+		testEntry{name: "workflows/charge.hcl", typeflag: tar.TypeReg, data: "step"},
+		testEntry{name: "main.hcl", typeflag: tar.TypeReg, data: "workflow"},
+	)
+	read, err := Read(bytes.NewReader(archive))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := Hash(read); got != hash {
+		t.Errorf("Hash() = %s, want %s for the same files in a different order", got, hash)
+	}
+	files["main.hcl"] = &fstest.MapFile{Data: []byte("changed")}
+	if got, _ := Hash(files); got == hash {
+		t.Errorf("Hash() = %s, want a different hash for changed files", got)
+	}
+}
+
+func TestPack(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		"main.hcl":               "workflow",
+		"workflows/charge.hcl":   "step",
+		"checkout.sbtest.hcl":    "test",
+		"variables.json":         "{}",
+		"workflows/notes.md":     "notes",
+		"workflows/nested/a.hcl": "nested",
+		"switchboard.lock":       "ignored",
+	}
+	for name, data := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	archive, err := Pack(root)
+	if err != nil {
+		t.Fatalf("Pack() error = %v", err)
+	}
+	got, err := Read(bytes.NewReader(archive))
+	if err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+	var names []string
+	fs.WalkDir(got, ".", func(name string, entry fs.DirEntry, err error) error {
+		if err == nil && !entry.IsDir() {
+			names = append(names, name)
+		}
+		return err
+	})
+	want := []string{"main.hcl", "workflows/charge.hcl", "workflows/nested/a.hcl"}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("Pack() files = %v, want %v", names, want)
+	}
+
+	// modification times and permissions do not change the bundle
+	modified := time.Now().Add(time.Hour)
+	if err = os.Chtimes(filepath.Join(root, "main.hcl"), modified, modified); err != nil {
+		t.Fatal(err)
+	}
+	if err = os.Chmod(filepath.Join(root, "workflows", "charge.hcl"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	again, err := Pack(root)
+	if err != nil {
+		t.Fatalf("Pack() error = %v", err)
+	}
+	if !bytes.Equal(archive, again) {
+		t.Errorf("Pack() is not deterministic")
+	}
+}
@@ -0,0 +1,5 @@
+/*
+Package bundle reads configuration bundles, the tar archives of a configuration directory that are
+deployed to a switchboard server.
+*/
+package bundle
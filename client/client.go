@@ -0,0 +1,200 @@
+package client
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/switchboard-org/switchboard/api"
+	"github.com/switchboard-org/switchboard/plan"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxEventSize is the largest event the event stream can send
+const maxEventSize = 1 << 20
+
+// Client calls the admin endpoints of a server, authenticated with an API token
+type Client struct {
+	host  string
+	token string
+	http  *http.Client
+	// streamHTTP has no timeout, as event streams stay open until they are cancelled
+	streamHTTP *http.Client
+}
+
+// NewClient creates a client for the server at host, such as 'https://switchboard.example.com'
+func NewClient(host string, token string) *Client {
+	return &Client{
+		host:       strings.TrimSuffix(host, "/"),
+		token:      token,
+		http:       &http.Client{Timeout: 5 * time.Minute},
+		streamHTTP: &http.Client{},
+	}
+}
+
+// ResponseError is returned when the server responds with an error status
+type ResponseError struct {
+	StatusCode int
+	Body       api.Error
+}
+
+func (e *ResponseError) Error() string {
+	if e.Body.Error == "" {
+		return fmt.Sprintf("server responded with status %d", e.StatusCode)
+	}
+	return fmt.Sprintf("server responded with status %d: %s", e.StatusCode, e.Body.Error)
+}
+
+func (c *Client) Deployment(ctx context.Context) (*api.Deployment, error) {
+	var deployment api.Deployment
+	return &deployment, c.do(ctx, http.MethodGet, "/admin/deployment", nil, "", &deployment)
+}
+
+// Snapshot returns the snapshot of the running configuration, to plan a deploy with
+func (c *Client) Snapshot(ctx context.Context) (*plan.Snapshot, error) {
+	var snapshot plan.Snapshot
+	return &snapshot, c.do(ctx, http.MethodGet, "/admin/deployment/snapshot", nil, "", &snapshot)
+}
+
+// Deploy uploads a configuration bundle, created with bundle.Pack, and returns the new deployment. The
+// diagnostics of an invalid configuration are in the Body of the returned ResponseError.
+func (c *Client) Deploy(ctx context.Context, bundle []byte) (*api.Deployment, error) {
+	var deployment api.Deployment
+	return &deployment, c.do(ctx, http.MethodPost, "/admin/deploy", bytes.NewReader(bundle), "application/gzip", &deployment)
+}
+
+// Destroy stops the running configuration of the server and deregisters its triggers
+func (c *Client) Destroy(ctx context.Context) (*api.Destroyed, error) {
+	var destroyed api.Destroyed
+	return &destroyed, c.do(ctx, http.MethodPost, "/admin/destroy", nil, "", &destroyed)
+}
+
+// Runs returns the most recent runs, optionally of a single workflow
+func (c *Client) Runs(ctx context.Context, workflow string, limit int) ([]api.Run, error) {
+	query := url.Values{}
+	if workflow != "" {
+		query.Set("workflow", workflow)
+	}
+	if limit > 0 {
+		query.Set("limit", strconv.Itoa(limit))
+	}
+	var runs []api.Run
+	return runs, c.do(ctx, http.MethodGet, "/admin/runs?"+query.Encode(), nil, "", &runs)
+}
+
+// Run returns a single run with the results of its steps
+func (c *Client) Run(ctx context.Context, id string) (*api.Run, error) {
+	var run api.Run
+	return &run, c.do(ctx, http.MethodGet, "/admin/runs/"+url.PathEscape(id), nil, "", &run)
+}
+
+// Stream calls handle for every event of the server that matches filter, until ctx is cancelled, handle
+// returns an error or the server ends the stream
+func (c *Client) Stream(ctx context.Context, filter api.EventFilter, handle func(api.Event) error) error {
+	query := url.Values{}
+	if filter.Workflow != "" {
+		query.Set("workflow", filter.Workflow)
+	}
+	if filter.RunID != "" {
+		query.Set("run_id", filter.RunID)
+	}
+	req, err := c.request(ctx, http.MethodGet, "/admin/stream?"+query.Encode(), nil, "")
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	resp, err := c.streamHTTP.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= http.StatusBadRequest {
+		return responseError(resp)
+	}
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxEventSize)
+	var data bytes.Buffer
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "":
+			if data.Len() == 0 {
+				continue
+			}
+			var event api.Event
+			if err = json.Unmarshal(data.Bytes(), &event); err != nil {
+				return fmt.Errorf("invalid event: %w", err)
+			}
+			data.Reset()
+			if err = handle(event); err != nil {
+				return err
+			}
+		case strings.HasPrefix(line, "data:"):
+			if data.Len() > 0 {
+				data.WriteByte('\n')
+			}
+			data.WriteString(strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+		}
+		// comments and other fields, such as the event name that is also in the data, are ignored
+	}
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return scanner.Err()
+}
+
+func (c *Client) request(ctx context.Context, method string, path string, body io.Reader, contentType string) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, c.host+path, body)
+	if err != nil {
+		return nil, err
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	return req, nil
+}
+
+// do sends a request and decodes the JSON response into out
+func (c *Client) do(ctx context.Context, method string, path string, body io.Reader, contentType string, out any) error {
+	req, err := c.request(ctx, method, path, body, contentType)
+	if err != nil {
+		return err
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= http.StatusBadRequest {
+		return responseError(resp)
+	}
+	if out == nil {
+		return nil
+	}
+	if err = json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("invalid response from server: %w", err)
+	}
+	return nil
+}
+
+func responseError(resp *http.Response) error {
+	output := &ResponseError{StatusCode: resp.StatusCode}
+	raw, err := io.ReadAll(io.LimitReader(resp.Body, maxEventSize))
+	if err != nil {
+		return errors.Join(output, err)
+	}
+	if json.Unmarshal(raw, &output.Body) != nil {
+		output.Body.Error = strings.TrimSpace(string(raw))
+	}
+	return output
+}
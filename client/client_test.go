@@ -0,0 +1,94 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/switchboard-org/switchboard/api"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestClient_Stream(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" || r.URL.Query().Get("workflow") != "charge" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, ": connected\n\n")
+		fmt.Fprint(w, "event: run\ndata: {\"type\":\"run\",\"workflow\":\"charge\",\"run_id\":\"run-1\",\"status\":\"running\"}\n\n")
+		fmt.Fprint(w, ": keep-alive\n\n")
+		fmt.Fprint(w, "event: step\ndata: {\"type\":\"step\",\"workflow\":\"charge\",\n")
+		fmt.Fprint(w, "data: \"run_id\":\"run-1\",\"step\":\"charge\"}\n\n")
+	}))
+	defer server.Close()
+
+	var got []api.Event
+	err := NewClient(server.URL+"/", "token").Stream(context.Background(), api.EventFilter{Workflow: "charge"}, func(event api.Event) error {
+		got = append(got, event)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Stream() error = %v", err)
+	}
+	want := []api.Event{
+		{Type: api.EventRun, Workflow: "charge", RunID: "run-1", Status: "running"},
+		{Type: api.EventStep, Workflow: "charge", RunID: "run-1", Step: "charge"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Stream() events = %v, want %v", got, want)
+	}
+}
+
+func TestClient_do(t *testing.T) {
+	tests := []struct {
+		name       string
+		status     int
+		body       string
+		wantStatus int
+		wantError  string
+	}{
+		{
+			name:       "should decode JSON error responses",
+			status:     http.StatusForbidden,
+			body:       `{"error":"missing scope 'read'"}`,
+			wantStatus: http.StatusForbidden,
+			wantError:  "missing scope 'read'",
+		},
+		{
+			name:       "should keep plain text error responses",
+			status:     http.StatusBadGateway,
+			body:       "bad gateway",
+			wantStatus: http.StatusBadGateway,
+			wantError:  "bad gateway",
+		},
+		{
+			name:   "should decode successful responses",
+			status: http.StatusOK,
+			body:   `{"id":"abc","local":false}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				fmt.Fprint(w, tt.body)
+			}))
+			defer server.Close()
+			got, err := NewClient(server.URL, "token").Deployment(context.Background())
+			if tt.wantStatus == 0 {
+				if err != nil || got.ID != "abc" {
+					t.Errorf("Deployment() = %v, %v", got, err)
+				}
+				return
+			}
+			var responseErr *ResponseError
+			if !errors.As(err, &responseErr) || responseErr.StatusCode != tt.wantStatus || responseErr.Body.Error != tt.wantError {
+				t.Errorf("Deployment() error = %v, want status %d with %q", err, tt.wantStatus, tt.wantError)
+			}
+		})
+	}
+}
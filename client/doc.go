@@ -0,0 +1,5 @@
+/*
+Package client talks to the admin endpoints of a switchboard server, such as to deploy configuration bundles
+or follow the events of running workflows.
+*/
+package client
@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"github.com/spf13/cobra"
+	"github.com/switchboard-org/switchboard/api"
+	"github.com/switchboard-org/switchboard/client"
+	"github.com/switchboard-org/switchboard/internal"
+	"io"
+	"net/http"
+	"os"
+)
+
+var (
+	serverHost  string
+	serverToken string
+)
+
+// addServerFlags adds the flags used to connect to a server, with a token that has the given scope
+func addServerFlags(cmd *cobra.Command, scope string) {
+	cmd.Flags().StringVar(&serverHost, "host", "", "address of the server, defaults to the host block, SWITCHBOARD_HOST or http://localhost:8080")
+	cmd.Flags().StringVar(&serverToken, "token", "", fmt.Sprintf("API token with the %s scope, defaults to the key of the host block or SWITCHBOARD_TOKEN", scope))
+}
+
+// newClient creates a client for the server set with the --host and --token flags. Flags that are not set
+// fall back to the host block of the configuration, which is nil if there is none, and then to the
+// SWITCHBOARD_HOST and SWITCHBOARD_TOKEN environment variables.
+func newClient(host *internal.HostBlock) *client.Client {
+	address, token := serverHost, serverToken
+	if host != nil {
+		if address == "" {
+			address = host.Address
+		}
+		if token == "" {
+			token = host.Key
+		}
+	}
+	if address == "" {
+		address = os.Getenv("SWITCHBOARD_HOST")
+	}
+	if address == "" {
+		address = "http://localhost:8080"
+	}
+	if token == "" {
+		token = os.Getenv("SWITCHBOARD_TOKEN")
+	}
+	return client.NewClient(address, token)
+}
+
+// isNotFound returns true if err is a not found response of the server
+func isNotFound(err error) bool {
+	var responseErr *client.ResponseError
+	return errors.As(err, &responseErr) && responseErr.StatusCode == http.StatusNotFound
+}
+
+// printDiagnostics prints the diagnostics of a configuration the server rejected
+func printDiagnostics(out io.Writer, diagnostics []api.Diagnostic) {
+	for _, diagnostic := range diagnostics {
+		location := ""
+		if diagnostic.Range != nil {
+			location = fmt.Sprintf("%s:%d,%d: ", diagnostic.Range.Filename, diagnostic.Range.Start.Line, diagnostic.Range.Start.Column)
+		}
+		fmt.Fprintf(out, "%s%s; %s\n", location, diagnostic.Summary, diagnostic.Detail)
+	}
+}
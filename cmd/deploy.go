@@ -0,0 +1,143 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/spf13/cobra"
+	"github.com/switchboard-org/switchboard/api"
+	"github.com/switchboard-org/switchboard/bundle"
+	"github.com/switchboard-org/switchboard/client"
+	"github.com/switchboard-org/switchboard/plan"
+	"golang.org/x/exp/slices"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+)
+
+var cmdDeploy = &cobra.Command{
+	Use:   "deploy",
+	Short: "Deploy your configuration to a server",
+	Long:  "Validates your configuration, then packages and uploads it to the server set in the host block, which replaces its running workflows, triggers and providers. Prints the workflows and triggers that were added or removed",
+	Args:  cobra.NoArgs,
+	RunE:  deploy,
+	// errors are printed by Execute
+	SilenceUsage:  true,
+	SilenceErrors: true,
+}
+
+var deployPlanFile string
+
+func init() {
+	addServerFlags(cmdDeploy, "deploy")
+	cmdDeploy.Flags().StringVar(&deployPlanFile, "plan", "", "apply a plan saved with 'plan --out'. The deploy fails if the server no longer runs the configuration the plan was made against")
+}
+
+func deploy(cmd *cobra.Command, args []string) error {
+	config, archive, snapshot, err := packConfiguration(cmd)
+	if err != nil {
+		return err
+	}
+	var planFile *plan.File
+	if deployPlanFile != "" {
+		if planFile, err = plan.ReadFile(deployPlanFile); err != nil {
+			return fmt.Errorf("could not read plan: %w", err)
+		}
+		// the planned bundle is deployed as it is, even if the configuration changed since
+		archive, snapshot = planFile.Bundle, planFile.Snapshot
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	c := newClient(config.Switchboard.Host)
+	previous, err := c.Deployment(ctx)
+	if isNotFound(err) {
+		previous = nil
+	} else if err != nil {
+		return fmt.Errorf("could not get the running deployment: %w", err)
+	}
+	if planFile != nil {
+		running, err := deployedSnapshot(ctx, c)
+		if err != nil {
+			return err
+		}
+		if err = checkPlan(planFile, running); err != nil {
+			return err
+		}
+	}
+	deployment, err := c.Deploy(ctx, archive)
+	var responseErr *client.ResponseError
+	if errors.As(err, &responseErr) && len(responseErr.Body.Diagnostics) > 0 {
+		printDiagnostics(cmd.ErrOrStderr(), responseErr.Body.Diagnostics)
+		return errors.New("server rejected the configuration")
+	}
+	if err != nil {
+		return fmt.Errorf("could not deploy: %w", err)
+	}
+	printDeploymentChanges(cmd.OutOrStdout(), previous, deployment)
+	if err = plan.WriteSnapshot(plan.DefaultSnapshotFile, snapshot); err != nil {
+		log.Printf("WARNING: could not save the snapshot of the deployed configuration: %s", err)
+	}
+	return nil
+}
+
+// checkPlan returns an error if a saved plan can no longer be applied exactly, because the server runs a
+// different configuration than the one the plan was made against, or the plan file was modified
+func checkPlan(planFile *plan.File, running *plan.Snapshot) error {
+	runningHash := ""
+	if running != nil {
+		runningHash = running.Hash
+	}
+	if runningHash != planFile.Plan.FromHash {
+		return errors.New("the server configuration changed since the plan was made, create a new plan")
+	}
+	fsys, err := bundle.Read(bytes.NewReader(planFile.Bundle))
+	if err != nil {
+		return fmt.Errorf("invalid bundle in plan: %w", err)
+	}
+	hash, err := bundle.Hash(fsys)
+	if err != nil {
+		return fmt.Errorf("invalid bundle in plan: %w", err)
+	}
+	if hash != planFile.Plan.ToHash || hash != planFile.Snapshot.Hash {
+		return errors.New("the bundle in the plan does not match the planned configuration")
+	}
+	return nil
+}
+
+// printDeploymentChanges prints the workflows and triggers that were added or removed by a deployment. The
+// previous deployment is nil if nothing was deployed.
+func printDeploymentChanges(out io.Writer, previous *api.Deployment, deployment *api.Deployment) {
+	fmt.Fprintf(out, "deployed %s (%s)\n", deployment.ID, deployment.Hash)
+	if previous == nil {
+		previous = &api.Deployment{}
+	}
+	if previous.Hash != "" && previous.Hash == deployment.Hash {
+		fmt.Fprintln(out, "no changes")
+		return
+	}
+	changes := printNameChanges(out, "workflow", previous.Workflows, deployment.Workflows)
+	changes += printNameChanges(out, "trigger", previous.Triggers, deployment.Triggers)
+	if changes == 0 {
+		fmt.Fprintln(out, "no workflows or triggers were added or removed")
+	}
+}
+
+func printNameChanges(out io.Writer, kind string, previous []string, next []string) int {
+	changes := 0
+	for _, name := range next {
+		if !slices.Contains(previous, name) {
+			fmt.Fprintf(out, "  + %s '%s'\n", kind, name)
+			changes++
+		}
+	}
+	for _, name := range previous {
+		if !slices.Contains(next, name) {
+			fmt.Fprintf(out, "  - %s '%s'\n", kind, name)
+			changes++
+		}
+	}
+	return changes
+}
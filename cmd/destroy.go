@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/spf13/cobra"
+	"os"
+	"os/signal"
+	"strings"
+)
+
+var autoApprove bool
+
+var cmdDestroy = &cobra.Command{
+	Use:   "destroy",
+	Short: "Stop all workflows on a server",
+	Long:  "Deregisters all triggers and stops the workflows and providers running on the server set in the host block. Nothing runs on the server until the next deploy",
+	Args:  cobra.NoArgs,
+	RunE:  destroy,
+	// errors are printed by Execute
+	SilenceUsage:  true,
+	SilenceErrors: true,
+}
+
+func init() {
+	addServerFlags(cmdDestroy, "deploy")
+	cmdDestroy.Flags().BoolVar(&autoApprove, "auto-approve", false, "destroy without asking for confirmation")
+}
+
+func destroy(cmd *cobra.Command, args []string) error {
+	config, diag := parser.Parse()
+	if diag.HasErrors() {
+		for _, err := range diag.Errs() {
+			cmd.PrintErrln(err)
+		}
+		return errors.New("invalid configuration")
+	}
+	if !autoApprove {
+		fmt.Fprint(cmd.OutOrStdout(), "This stops every workflow on the server. Type 'yes' to continue: ")
+		answer, _ := bufio.NewReader(cmd.InOrStdin()).ReadString('\n')
+		if strings.TrimSpace(answer) != "yes" {
+			return errors.New("destroy cancelled")
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	destroyed, err := newClient(config.Switchboard.Host).Destroy(ctx)
+	if err != nil {
+		return fmt.Errorf("could not destroy: %w", err)
+	}
+	out := cmd.OutOrStdout()
+	for _, workflow := range destroyed.Workflows {
+		fmt.Fprintf(out, "  - workflow '%s'\n", workflow)
+	}
+	for _, trigger := range destroyed.Triggers {
+		fmt.Fprintf(out, "  - trigger '%s' deregistered\n", trigger)
+	}
+	for _, plugin := range destroyed.Plugins {
+		fmt.Fprintf(out, "  - provider '%s' stopped\n", plugin)
+	}
+	fmt.Fprintf(out, "destroyed, recorded as deployment %s\n", destroyed.ID)
+	return nil
+}
@@ -0,0 +1,133 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/spf13/cobra"
+	"github.com/switchboard-org/switchboard/engine"
+	"github.com/switchboard-org/switchboard/internal"
+	"github.com/switchboard-org/switchboard/state"
+	"github.com/zclconf/go-cty/cty"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
+	"io"
+	"os"
+	"os/signal"
+	"time"
+)
+
+var payloadFile string
+
+var cmdInvoke = &cobra.Command{
+	Use:   "invoke <workflow>",
+	Short: "Run a workflow locally with a trigger payload",
+	Long:  "Parses your configuration and runs a single workflow without the server, printing the input and output of every step. Exits with a non-zero status if the run fails",
+	Args:  cobra.ExactArgs(1),
+	RunE:  invoke,
+	// errors are printed by Execute
+	SilenceUsage:  true,
+	SilenceErrors: true,
+}
+
+func init() {
+	cmdInvoke.Flags().StringVar(&payloadFile, "payload", "", "JSON file with the trigger payload")
+}
+
+func invoke(cmd *cobra.Command, args []string) error {
+	config, diag := parser.Parse()
+	if diag.HasErrors() {
+		for _, err := range diag.Errs() {
+			cmd.PrintErrln(err)
+		}
+		return errors.New("invalid configuration")
+	}
+	workflow := config.Workflow(args[0])
+	if workflow == nil {
+		return fmt.Errorf("workflow '%s' does not exist", args[0])
+	}
+	payload, err := invokePayload(config, workflow)
+	if err != nil {
+		return err
+	}
+
+	store := &printingStateStore{StateStore: state.NewMemoryStateStore(), out: cmd.OutOrStdout()}
+	e := engine.NewDefaultEngine(config, internal.NewDefaultPluginManager(), store)
+	if err = e.Start(); err != nil {
+		return err
+	}
+	defer e.Stop()
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	run, err := e.RunWorkflow(ctx, workflow.Name, payload)
+	if err != nil {
+		return err
+	}
+	if run.Status != state.StatusSucceeded {
+		return fmt.Errorf("run of workflow '%s' %s: %s", workflow.Name, run.Status, run.Error)
+	}
+	fmt.Fprintf(cmd.OutOrStdout(), "run of workflow '%s' succeeded in %s\n", workflow.Name, run.FinishedAt.Sub(run.StartedAt).Round(time.Millisecond))
+	return nil
+}
+
+// invokePayload reads the payload file and validates it against the trigger of the workflow. Without a
+// payload file, workflows of schedule triggers are run as if scheduled now, and other workflows with an
+// empty payload.
+func invokePayload(config *internal.RootSwitchboardConfig, workflow *internal.WorkflowBlock) (cty.Value, error) {
+	var trigger *internal.TriggerBlock
+	if workflow.Trigger != "" {
+		trigger = config.Trigger(workflow.Trigger)
+	}
+	payload := cty.EmptyObjectVal
+	if payloadFile != "" {
+		raw, err := os.ReadFile(payloadFile)
+		if err != nil {
+			return cty.NilVal, fmt.Errorf("could not read payload file: %w", err)
+		}
+		var value ctyjson.SimpleJSONValue
+		if err = value.UnmarshalJSON(raw); err != nil {
+			return cty.NilVal, fmt.Errorf("could not parse payload file: %w", err)
+		}
+		payload = value.Value
+	} else if trigger != nil && trigger.Schedule != nil {
+		payload = cty.ObjectVal(map[string]cty.Value{
+			"scheduled_at": cty.StringVal(time.Now().In(trigger.Schedule.Location).Format(time.RFC3339)),
+		})
+	}
+	if trigger == nil {
+		return payload, nil
+	}
+	if errs := trigger.ValidatePayload(payload); len(errs) > 0 {
+		return cty.NilVal, fmt.Errorf("payload does not match trigger '%s': %w", trigger.Name, errors.Join(errs...))
+	}
+	return payload, nil
+}
+
+// printingStateStore prints every finished step result as it is saved by the engine
+type printingStateStore struct {
+	state.StateStore
+	out io.Writer
+}
+
+func (s *printingStateStore) SaveStepResult(result state.StepResult) error {
+	if result.FinishedAt != nil {
+		printStepResult(s.out, result)
+	}
+	return s.StateStore.SaveStepResult(result)
+}
+
+func printStepResult(out io.Writer, result state.StepResult) {
+	fmt.Fprintf(out, "step '%s' %s", result.Step, result.Status)
+	if len(result.Attempts) > 1 {
+		fmt.Fprintf(out, " after %d attempts", len(result.Attempts))
+	}
+	fmt.Fprintln(out)
+	if len(result.Input) > 0 {
+		fmt.Fprintf(out, "  input:  %s\n", result.Input)
+	}
+	if len(result.Output) > 0 {
+		fmt.Fprintf(out, "  output: %s\n", result.Output)
+	}
+	if result.Error != "" {
+		fmt.Fprintf(out, "  error:  %s\n", result.Error)
+	}
+}
@@ -0,0 +1,112 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/spf13/cobra"
+	"github.com/switchboard-org/switchboard/api"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+)
+
+var (
+	followLogs   bool
+	logsWorkflow string
+	logsRunID    string
+)
+
+var cmdLogs = &cobra.Command{
+	Use:   "logs",
+	Short: "Show the runs of a server",
+	Long:  "Shows the most recent runs of a server, or the steps of a single run. With --follow, the run, step, plugin and deployment events of the server are printed as they happen",
+	Args:  cobra.NoArgs,
+	RunE:  logs,
+	// errors are printed by Execute
+	SilenceUsage:  true,
+	SilenceErrors: true,
+}
+
+func init() {
+	cmdLogs.Flags().BoolVarP(&followLogs, "follow", "f", false, "print events as they happen, until interrupted")
+	cmdLogs.Flags().StringVar(&logsWorkflow, "workflow", "", "only show runs of this workflow")
+	cmdLogs.Flags().StringVar(&logsRunID, "run", "", "only show this run")
+	addServerFlags(cmdLogs, "read")
+}
+
+func logs(cmd *cobra.Command, args []string) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	c := newClient(nil)
+	out := cmd.OutOrStdout()
+	if followLogs {
+		err := c.Stream(ctx, api.EventFilter{Workflow: logsWorkflow, RunID: logsRunID}, func(event api.Event) error {
+			printEvent(out, event)
+			return nil
+		})
+		if errors.Is(err, context.Canceled) {
+			return nil
+		}
+		return err
+	}
+	if logsRunID != "" {
+		run, err := c.Run(ctx, logsRunID)
+		if err != nil {
+			return err
+		}
+		printRun(out, *run)
+		for _, step := range run.Steps {
+			fmt.Fprintf(out, "  step '%s' %s", step.Step, step.Status)
+			if step.Attempts > 1 {
+				fmt.Fprintf(out, " after %d attempts", step.Attempts)
+			}
+			if step.Error != "" {
+				fmt.Fprintf(out, ": %s", step.Error)
+			}
+			fmt.Fprintln(out)
+		}
+		return nil
+	}
+	runs, err := c.Runs(ctx, logsWorkflow, 20)
+	if err != nil {
+		return err
+	}
+	// the oldest run is printed first, like the events of a followed stream
+	for i := len(runs) - 1; i >= 0; i-- {
+		printRun(out, runs[i])
+	}
+	return nil
+}
+
+func printRun(out io.Writer, run api.Run) {
+	fmt.Fprintf(out, "%s run %s of workflow '%s' %s", run.StartedAt.Local().Format(time.RFC3339), run.ID, run.Workflow, run.Status)
+	if run.Error != "" {
+		fmt.Fprintf(out, ": %s", run.Error)
+	}
+	fmt.Fprintln(out)
+}
+
+func printEvent(out io.Writer, event api.Event) {
+	var subject []string
+	switch event.Type {
+	case api.EventRun:
+		subject = append(subject, fmt.Sprintf("run %s of workflow '%s'", event.RunID, event.Workflow))
+	case api.EventStep:
+		subject = append(subject, fmt.Sprintf("step '%s' of run %s", event.Step, event.RunID))
+	case api.EventPlugin:
+		subject = append(subject, fmt.Sprintf("plugin '%s'", event.Plugin))
+	default:
+		subject = append(subject, string(event.Type))
+	}
+	if event.Status != "" {
+		subject = append(subject, event.Status)
+	}
+	line := strings.Join(subject, " ")
+	if event.Message != "" {
+		line += ": " + event.Message
+	}
+	fmt.Fprintf(out, "%s %s\n", event.Time.Local().Format(time.RFC3339), line)
+}
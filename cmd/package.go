@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/spf13/cobra"
+	"github.com/switchboard-org/switchboard/bundle"
+	"io/fs"
+	"os"
+)
+
+var packageOut string
+
+var cmdPackage = &cobra.Command{
+	Use:   "package",
+	Short: "Package your configuration into a bundle",
+	Long:  "Validates your configuration, then packages its files into a deterministic bundle that can be deployed. Prints the sha256 content hash of the bundle, which the server reports for the deployment",
+	Args:  cobra.NoArgs,
+	RunE:  packageConfiguration,
+	// errors are printed by Execute
+	SilenceUsage:  true,
+	SilenceErrors: true,
+}
+
+func init() {
+	cmdPackage.Flags().StringVarP(&packageOut, "out", "o", "bundle.tgz", "file the bundle is written to")
+}
+
+func packageConfiguration(cmd *cobra.Command, args []string) error {
+	_, diag := parser.Parse()
+	if diag.HasErrors() {
+		for _, err := range diag.Errs() {
+			cmd.PrintErrln(err)
+		}
+		return errors.New("invalid configuration")
+	}
+	archive, err := bundle.Pack(workingDir)
+	if err != nil {
+		return fmt.Errorf("could not package configuration: %w", err)
+	}
+	fsys, err := bundle.Read(bytes.NewReader(archive))
+	if err != nil {
+		return fmt.Errorf("could not package configuration: %w", err)
+	}
+	hash, err := bundle.Hash(fsys)
+	if err != nil {
+		return fmt.Errorf("could not hash bundle: %w", err)
+	}
+	files := 0
+	err = fs.WalkDir(fsys, ".", func(name string, entry fs.DirEntry, err error) error {
+		if err == nil && !entry.IsDir() {
+			files++
+		}
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("could not read bundle: %w", err)
+	}
+	if err = os.WriteFile(packageOut, archive, 0644); err != nil {
+		return fmt.Errorf("could not write bundle: %w", err)
+	}
+	fmt.Fprintf(cmd.OutOrStdout(), "packaged %d files into %s\nsha256:%s\n", files, packageOut, hash)
+	return nil
+}
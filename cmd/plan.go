@@ -0,0 +1,135 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/spf13/cobra"
+	"github.com/switchboard-org/switchboard/bundle"
+	"github.com/switchboard-org/switchboard/client"
+	"github.com/switchboard-org/switchboard/internal"
+	"github.com/switchboard-org/switchboard/plan"
+	"io"
+	"os"
+	"os/signal"
+)
+
+var (
+	planJSON      bool
+	planOut       string
+	planStateFile string
+)
+
+var cmdPlan = &cobra.Command{
+	Use:   "plan",
+	Short: "Show what a deploy would change",
+	Long:  "Compares your configuration with the configuration running on the server set in the host block, or with a local state file, and prints the workflows, triggers, providers and schemas a deploy would add, change or remove. The plan can be saved with --out, and applied exactly with 'deploy --plan'",
+	Args:  cobra.NoArgs,
+	RunE:  planDeploy,
+	// errors are printed by Execute
+	SilenceUsage:  true,
+	SilenceErrors: true,
+}
+
+func init() {
+	addServerFlags(cmdPlan, "read")
+	cmdPlan.Flags().BoolVar(&planJSON, "json", false, "print the plan as json")
+	cmdPlan.Flags().StringVar(&planOut, "out", "", "save the plan to a file, which can be applied with 'deploy --plan'")
+	cmdPlan.Flags().StringVar(&planStateFile, "state", "", fmt.Sprintf("compare with the snapshot in a local state file instead of the server, such as %s which is written by deploy", plan.DefaultSnapshotFile))
+}
+
+func planDeploy(cmd *cobra.Command, args []string) error {
+	config, archive, snapshot, err := packConfiguration(cmd)
+	if err != nil {
+		return err
+	}
+	var deployed *plan.Snapshot
+	if planStateFile != "" {
+		if deployed, err = plan.ReadSnapshot(planStateFile); err != nil {
+			return fmt.Errorf("could not read state file: %w", err)
+		}
+	} else {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+		if deployed, err = deployedSnapshot(ctx, newClient(config.Switchboard.Host)); err != nil {
+			return err
+		}
+	}
+	p := plan.Diff(deployed, snapshot)
+	if planOut != "" {
+		if err = plan.WriteFile(planOut, &plan.File{Plan: p, Snapshot: snapshot, Bundle: archive}); err != nil {
+			return fmt.Errorf("could not save plan: %w", err)
+		}
+	}
+	if planJSON {
+		encoder := json.NewEncoder(cmd.OutOrStdout())
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(p)
+	}
+	printPlan(cmd.OutOrStdout(), p)
+	if planOut != "" {
+		fmt.Fprintf(cmd.OutOrStdout(), "\nsaved plan to %s, apply it with 'switchboard deploy --plan %s'\n", planOut, planOut)
+	}
+	return nil
+}
+
+// packConfiguration parses the configuration in the working directory, and returns its bundle and snapshot
+func packConfiguration(cmd *cobra.Command) (*internal.RootSwitchboardConfig, []byte, *plan.Snapshot, error) {
+	config, diag := parser.Parse()
+	if diag.HasErrors() {
+		for _, err := range diag.Errs() {
+			cmd.PrintErrln(err)
+		}
+		return nil, nil, nil, errors.New("invalid configuration")
+	}
+	archive, err := bundle.Pack(workingDir)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("could not package configuration: %w", err)
+	}
+	fsys, err := bundle.Read(bytes.NewReader(archive))
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("could not package configuration: %w", err)
+	}
+	snapshot, err := plan.NewSnapshot(config, fsys)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("could not create snapshot of configuration: %w", err)
+	}
+	return config, archive, snapshot, nil
+}
+
+// deployedSnapshot returns the snapshot of the configuration running on the server, which is nil if nothing
+// is deployed
+func deployedSnapshot(ctx context.Context, c *client.Client) (*plan.Snapshot, error) {
+	if _, err := c.Deployment(ctx); isNotFound(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("could not get the running deployment: %w", err)
+	}
+	snapshot, err := c.Snapshot(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("could not get the snapshot of the running deployment: %w", err)
+	}
+	return snapshot, nil
+}
+
+// printPlan prints the changes of a plan, one per line, followed by a count of each kind of change
+func printPlan(out io.Writer, p *plan.Plan) {
+	if !p.HasChanges() {
+		fmt.Fprintln(out, "no changes, the configuration is already deployed")
+		return
+	}
+	symbols := map[plan.Action]string{plan.ActionCreate: "+", plan.ActionUpdate: "~", plan.ActionDelete: "-"}
+	for _, change := range p.Changes {
+		fmt.Fprintf(out, "  %s %s '%s'", symbols[change.Action], change.Kind, change.Name)
+		if change.Detail != "" {
+			fmt.Fprintf(out, " (%s)", change.Detail)
+		}
+		fmt.Fprintln(out)
+	}
+	if len(p.Changes) == 0 {
+		fmt.Fprintln(out, "only formatting or files outside of blocks changed")
+	}
+	fmt.Fprintf(out, "\nplan: %d to add, %d to change, %d to remove\n", p.Count(plan.ActionCreate), p.Count(plan.ActionUpdate), p.Count(plan.ActionDelete))
+}
@@ -38,6 +38,14 @@ func Execute(version string) {
 	rootCmd.AddCommand(cmdValidate)
 	rootCmd.AddCommand(cmdServe)
 	rootCmd.AddCommand(cmdInit)
+	rootCmd.AddCommand(cmdInvoke)
+	rootCmd.AddCommand(cmdTest)
+	rootCmd.AddCommand(cmdServer)
+	rootCmd.AddCommand(cmdLogs)
+	rootCmd.AddCommand(cmdPackage)
+	rootCmd.AddCommand(cmdPlan)
+	rootCmd.AddCommand(cmdDeploy)
+	rootCmd.AddCommand(cmdDestroy)
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Fprintln(os.Stderr, err)
 		os.Exit(1)
@@ -2,20 +2,68 @@ package cmd
 
 import (
 	"github.com/spf13/cobra"
+	"github.com/switchboard-org/switchboard/parsecfg"
 	"github.com/switchboard-org/switchboard/server"
+	"github.com/switchboard-org/switchboard/state"
+	"io/fs"
 	"log"
+	"os"
+	"time"
+)
+
+var (
+	listenAddress string
+	adminUser     string
+	watch         bool
+	drainTimeout  time.Duration
+	deregister    bool
 )
 
 var cmdServe = &cobra.Command{
 	Use:   "run",
 	Short: "Start a server that runs your workflows",
-	Long:  "Parses your configuration and runs a server that registers triggers and process workflows",
+	Long:  "Runs a server that registers triggers and process workflows of the last deployed configuration, or of your local configuration if nothing was deployed yet",
 	Run:   serve,
 }
 
+func init() {
+	cmdServe.Flags().StringVar(&listenAddress, "address", ":8080", "address the server listens on")
+	cmdServe.Flags().StringVar(&adminUser, "admin-user", "admin", "user of the admin basic auth credentials, which can also be set with SWITCHBOARD_ADMIN_USER. Basic auth is only enabled if SWITCHBOARD_ADMIN_PASSWORD is set")
+	cmdServe.Flags().StringVar(&tokenFile, "token-file", server.DefaultTokenFile, "file the API tokens are kept in")
+	cmdServe.Flags().DurationVar(&drainTimeout, "drain-timeout", server.DefaultDrainTimeout, "how long to wait for runs in progress when shutting down on SIGINT or SIGTERM. Runs that do not finish in time are resumed on the next start")
+	cmdServe.Flags().BoolVar(&deregister, "deregister-triggers", false, "deregister all triggers when shutting down")
+	cmdServe.Flags().BoolVar(&watch, "watch", false, "run the configuration in the working directory, and apply every valid change to its files without restarting. Runs that were already started finish with the previous configuration")
+}
+
 func serve(cmd *cobra.Command, args []string) {
+	store, err := state.NewBoltStateStore(state.DefaultDirectory)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	defer store.Close()
+	if user, ok := os.LookupEnv("SWITCHBOARD_ADMIN_USER"); ok && !cmd.Flags().Changed("admin-user") {
+		adminUser = user
+	}
+	var watchInterval time.Duration
+	if watch {
+		watchInterval = server.DefaultWatchInterval
+	}
 	//this is a long-running call. Only exits on failure or when shutdown request received
-	err := server.StartServer(parser)
+	err = server.StartServer(server.Config{
+		Parser:             parser,
+		WorkingDir:         workingDir,
+		WatchInterval:      watchInterval,
+		DrainTimeout:       drainTimeout,
+		DeregisterTriggers: deregister,
+		NewBundleParser: func(fsys fs.FS) parsecfg.Parser {
+			return parsecfg.NewDefaultParser(workingDir, varDefinitionFile, rootCmd.Version, parsecfg.WithFileSystem(fsys))
+		},
+		Store:         store,
+		TokenStore:    server.NewFileTokenStore(tokenFile),
+		AdminUser:     adminUser,
+		AdminPassword: os.Getenv("SWITCHBOARD_ADMIN_PASSWORD"),
+	}, listenAddress)
 	if err != nil {
 		log.Println(err)
 	}
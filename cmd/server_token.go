@@ -0,0 +1,112 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"github.com/spf13/cobra"
+	"github.com/switchboard-org/switchboard/server"
+	"github.com/switchboard-org/switchboard/state"
+	"strings"
+	"text/tabwriter"
+)
+
+var (
+	tokenFile   string
+	tokenName   string
+	tokenScopes []string
+)
+
+var cmdServer = &cobra.Command{
+	Use:   "server",
+	Short: "Manage the switchboard server",
+}
+
+var cmdServerToken = &cobra.Command{
+	Use:   "token",
+	Short: "Manage the API tokens of the server",
+	Long:  "Manages the API tokens that authenticate requests to the admin endpoints of the server. Run it in the working directory of the server; tokens take effect without restarting it",
+}
+
+var cmdServerTokenCreate = &cobra.Command{
+	Use:   "create",
+	Short: "Create an API token",
+	Args:  cobra.NoArgs,
+	RunE:  createToken,
+	// errors are printed by Execute
+	SilenceUsage:  true,
+	SilenceErrors: true,
+}
+
+var cmdServerTokenRevoke = &cobra.Command{
+	Use:           "revoke <id>",
+	Short:         "Revoke an API token",
+	Args:          cobra.ExactArgs(1),
+	RunE:          revokeToken,
+	SilenceUsage:  true,
+	SilenceErrors: true,
+}
+
+var cmdServerTokenList = &cobra.Command{
+	Use:           "list",
+	Short:         "List the API tokens",
+	Args:          cobra.NoArgs,
+	RunE:          listTokens,
+	SilenceUsage:  true,
+	SilenceErrors: true,
+}
+
+func init() {
+	var scopes []string
+	for _, scope := range server.Scopes {
+		scopes = append(scopes, string(scope))
+	}
+	cmdServerToken.PersistentFlags().StringVar(&tokenFile, "token-file", server.DefaultTokenFile, "file the API tokens are kept in")
+	cmdServerTokenCreate.Flags().StringVar(&tokenName, "name", "", "name of the token, recorded as the deployer of its deployments")
+	cmdServerTokenCreate.Flags().StringSliceVar(&tokenScopes, "scope", nil, fmt.Sprintf("scopes granted to the token (%s)", strings.Join(scopes, ", ")))
+	cmdServerTokenCreate.MarkFlagRequired("name")
+	cmdServerTokenCreate.MarkFlagRequired("scope")
+	cmdServerToken.AddCommand(cmdServerTokenCreate, cmdServerTokenRevoke, cmdServerTokenList)
+	cmdServer.AddCommand(cmdServerToken)
+}
+
+func createToken(cmd *cobra.Command, args []string) error {
+	var scopes []server.Scope
+	for _, scope := range tokenScopes {
+		scopes = append(scopes, server.Scope(scope))
+	}
+	secret, token, err := server.NewFileTokenStore(tokenFile).Create(tokenName, scopes)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(cmd.OutOrStdout(), "created token %s, which is only shown once:\n%s\n", token.ID, secret)
+	return nil
+}
+
+func revokeToken(cmd *cobra.Command, args []string) error {
+	err := server.NewFileTokenStore(tokenFile).Revoke(args[0])
+	if errors.Is(err, state.ErrNotFound) {
+		return fmt.Errorf("token '%s' does not exist", args[0])
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(cmd.OutOrStdout(), "revoked token %s\n", args[0])
+	return nil
+}
+
+func listTokens(cmd *cobra.Command, args []string) error {
+	tokens, err := server.NewFileTokenStore(tokenFile).List()
+	if err != nil {
+		return err
+	}
+	writer := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, "ID\tNAME\tSCOPES\tCREATED")
+	for _, token := range tokens {
+		var scopes []string
+		for _, scope := range token.Scopes {
+			scopes = append(scopes, string(scope))
+		}
+		fmt.Fprintf(writer, "%s\t%s\t%s\t%s\n", token.ID, token.Name, strings.Join(scopes, ","), token.CreatedAt.Format("2006-01-02 15:04"))
+	}
+	return writer.Flush()
+}
@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/spf13/cobra"
+	"github.com/switchboard-org/switchboard/parsecfg"
+	"github.com/switchboard-org/switchboard/testrunner"
+	"os"
+	"os/signal"
+)
+
+var junitFile string
+
+var cmdTest = &cobra.Command{
+	Use:   "test",
+	Short: "Run your workflow tests",
+	Long:  "Runs the tests in all '" + parsecfg.TestFileSuffix + "' files, which run workflows against mocked provider actions. Exits with a non-zero status if any test fails",
+	Args:  cobra.NoArgs,
+	RunE:  test,
+	// errors are printed by Execute
+	SilenceUsage:  true,
+	SilenceErrors: true,
+}
+
+func init() {
+	cmdTest.Flags().StringVar(&junitFile, "junit", "", "write the test results as JUnit XML to this file")
+}
+
+func test(cmd *cobra.Command, args []string) error {
+	// every action is mocked, so the tests run without the provider plugins installed
+	testParser := parsecfg.NewDefaultParser(workingDir, varDefinitionFile, rootCmd.Version, parsecfg.WithMockedProviders())
+	config, diag := testParser.Parse()
+	if diag.HasErrors() {
+		for _, err := range diag.Errs() {
+			cmd.PrintErrln(err)
+		}
+		return errors.New("invalid configuration")
+	}
+	files := parsecfg.FindTestFiles(workingDir)
+	if len(files) == 0 {
+		return fmt.Errorf("no '%s' files found", parsecfg.TestFileSuffix)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	runner := testrunner.NewDefaultRunner(config)
+	var results []testrunner.TestResult
+	invalid := false
+	for _, file := range files {
+		fileResults, diag := runner.Run(ctx, file)
+		for _, err := range diag.Errs() {
+			cmd.PrintErrln(err)
+			invalid = true
+		}
+		for _, result := range fileResults {
+			printTestResult(cmd, result)
+		}
+		results = append(results, fileResults...)
+	}
+
+	if junitFile != "" {
+		out, err := os.Create(junitFile)
+		if err != nil {
+			return fmt.Errorf("could not create JUnit file: %w", err)
+		}
+		defer out.Close()
+		if err = testrunner.WriteJUnit(out, results); err != nil {
+			return fmt.Errorf("could not write JUnit file: %w", err)
+		}
+	}
+
+	failed := 0
+	for _, result := range results {
+		if !result.Passed() {
+			failed++
+		}
+	}
+	fmt.Fprintf(cmd.OutOrStdout(), "%d passed, %d failed\n", len(results)-failed, failed)
+	if invalid {
+		return errors.New("invalid test files")
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d tests failed", failed)
+	}
+	return nil
+}
+
+func printTestResult(cmd *cobra.Command, result testrunner.TestResult) {
+	if result.Passed() {
+		fmt.Fprintf(cmd.OutOrStdout(), "PASS %s (%s)\n", result.Name, result.File)
+		return
+	}
+	fmt.Fprintf(cmd.OutOrStdout(), "FAIL %s (%s)\n", result.Name, result.File)
+	for _, failure := range result.Failures {
+		fmt.Fprintf(cmd.OutOrStdout(), "  %s\n", failure)
+	}
+}
@@ -0,0 +1,5 @@
+/*
+Package engine runs workflows from a parsed configuration, calling the provider plugin actions of every
+step and recording runs, step results and attempts in the state store.
+*/
+package engine
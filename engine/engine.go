@@ -0,0 +1,572 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hcldec"
+	"github.com/switchboard-org/plugin-sdk/sbsdk"
+	"github.com/switchboard-org/switchboard/internal"
+	"github.com/switchboard-org/switchboard/state"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/convert"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
+	"log"
+	"time"
+)
+
+type Engine interface {
+	// Start loads and initializes the provider plugins used by the workflows
+	Start() error
+	// RunWorkflow runs a workflow with the given trigger payload and records it in the state store. An
+	// error is only returned if the run could not be started, failures of the run itself are reported
+	// in the status of the returned run.
+	RunWorkflow(ctx context.Context, workflow string, payload cty.Value) (*state.Run, error)
+	// ResumeRuns continues every run that was interrupted before it finished, such as by a restart of the
+	// server. Steps that completed before the interruption are not run again.
+	ResumeRuns(ctx context.Context) ([]*state.Run, error)
+	// Stop kills all provider plugins
+	Stop()
+}
+
+type DefaultEngine struct {
+	config        *internal.RootSwitchboardConfig
+	pluginManager internal.PluginManager
+	store         state.StateStore
+	// providerConfigs are the marshalled provider init payloads passed to every action call, keyed by
+	// the provider name used in steps
+	providerConfigs map[string][]byte
+}
+
+func NewDefaultEngine(config *internal.RootSwitchboardConfig, pluginManager internal.PluginManager, store state.StateStore) Engine {
+	return &DefaultEngine{
+		config:          config,
+		pluginManager:   pluginManager,
+		store:           store,
+		providerConfigs: make(map[string][]byte),
+	}
+}
+
+func (e *DefaultEngine) Start() error {
+	for _, requiredProvider := range e.config.Switchboard.RequiredProviders {
+		if err := e.pluginManager.LoadPlugin(requiredProvider); err != nil {
+			e.Stop()
+			return fmt.Errorf("could not load provider '%s': %w", requiredProvider.Name, err)
+		}
+	}
+	for _, providerBlock := range e.config.Providers {
+		if err := e.initProvider(providerBlock.BlockName, &providerBlock.InitPayload); err != nil {
+			e.Stop()
+			return err
+		}
+	}
+	// required providers without a provider block are initialized with an empty configuration
+	for _, requiredProvider := range e.config.Switchboard.RequiredProviders {
+		if _, ok := e.providerConfigs[requiredProvider.Name]; ok {
+			continue
+		}
+		if err := e.initProvider(requiredProvider.Name, nil); err != nil {
+			e.Stop()
+			return err
+		}
+	}
+	return nil
+}
+
+// initProvider calls Init on the plugin of the provider, and keeps the marshalled payload for action calls.
+// A nil payload is decoded from an empty body, which fails if the provider has required settings.
+func (e *DefaultEngine) initProvider(name string, payload *cty.Value) error {
+	pluginName, _ := e.config.ProviderPluginName(name)
+	provider, err := e.pluginManager.ProviderInstance(pluginName)
+	if err != nil {
+		return fmt.Errorf("could not get provider '%s': %w", name, err)
+	}
+	initSchema, err := provider.InitSchema()
+	if err != nil {
+		return fmt.Errorf("could not get init schema of provider '%s': %w", name, err)
+	}
+	if payload == nil {
+		emptyPayload, diag := hcldec.Decode(hcl.EmptyBody(), initSchema.Decode(), nil)
+		if diag.HasErrors() {
+			return fmt.Errorf("provider '%s' requires a provider block: %w", name, diag)
+		}
+		payload = &emptyPayload
+	}
+	initPayload, err := sbsdk.MarshalVal(&initSchema, *payload)
+	if err != nil {
+		return fmt.Errorf("could not marshal configuration of provider '%s': %w", name, err)
+	}
+	if err = provider.Init(initPayload); err != nil {
+		return fmt.Errorf("could not initialize provider '%s': %w", name, err)
+	}
+	e.providerConfigs[name] = initPayload
+	return nil
+}
+
+func (e *DefaultEngine) Stop() {
+	e.pluginManager.KillAllPlugins()
+}
+
+func (e *DefaultEngine) RunWorkflow(ctx context.Context, name string, payload cty.Value) (*state.Run, error) {
+	workflow := e.config.Workflow(name)
+	if workflow == nil {
+		return nil, fmt.Errorf("workflow '%s' does not exist", name)
+	}
+	payloadJSON, err := marshalValue(payload)
+	if err != nil {
+		return nil, fmt.Errorf("could not marshal trigger payload: %w", err)
+	}
+	run := state.Run{
+		ID:        state.NewID(),
+		Workflow:  workflow.Name,
+		Status:    state.StatusRunning,
+		Payload:   payloadJSON,
+		StartedAt: time.Now(),
+	}
+	if deployment, err := e.store.ActiveDeployment(); err == nil {
+		run.DeploymentID = deployment.ID
+	}
+	if err = e.store.SaveRun(run); err != nil {
+		return nil, fmt.Errorf("could not save run: %w", err)
+	}
+	return e.finishRun(ctx, workflow, run, newRunState(run.ID, payload))
+}
+
+func (e *DefaultEngine) ResumeRuns(ctx context.Context) ([]*state.Run, error) {
+	runs, err := e.store.Runs(state.RunFilter{Status: state.StatusRunning})
+	if err != nil {
+		return nil, fmt.Errorf("could not get unfinished runs: %w", err)
+	}
+	var output []*state.Run
+	var errs []error
+	// runs are returned newest first, resume them in the order they were started
+	for i := len(runs) - 1; i >= 0; i-- {
+		run, err := e.resumeRun(ctx, runs[i])
+		if run != nil {
+			output = append(output, run)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("could not resume run '%s': %w", runs[i].ID, err))
+		}
+	}
+	return output, errors.Join(errs...)
+}
+
+// resumeRun continues an interrupted run, reusing the results of the steps that completed before
+func (e *DefaultEngine) resumeRun(ctx context.Context, run state.Run) (*state.Run, error) {
+	workflow := e.config.Workflow(run.Workflow)
+	if workflow == nil {
+		return e.saveFinishedRun(run, fmt.Errorf("workflow '%s' no longer exists", run.Workflow))
+	}
+	var payload ctyjson.SimpleJSONValue
+	if err := payload.UnmarshalJSON(run.Payload); err != nil {
+		return e.saveFinishedRun(run, fmt.Errorf("could not unmarshal trigger payload: %w", err))
+	}
+	results, err := e.store.StepResults(run.ID)
+	if err != nil {
+		return nil, fmt.Errorf("could not get step results: %w", err)
+	}
+	log.Printf("resuming run '%s' of workflow '%s'", run.ID, run.Workflow)
+	runState := newRunState(run.ID, payload.Value)
+	for _, result := range results {
+		runState.previous[result.Step] = result
+	}
+	return e.finishRun(ctx, workflow, run, runState)
+}
+
+// finishRun processes the steps of a run that is already saved as running, and saves its final status. A run
+// that is interrupted stays running, so that it is resumed by ResumeRuns.
+func (e *DefaultEngine) finishRun(ctx context.Context, workflow *internal.WorkflowBlock, run state.Run, runState *runState) (*state.Run, error) {
+	err := e.runSteps(ctx, workflow, runState)
+	if errors.Is(err, ErrRunInterrupted) {
+		return &run, err
+	}
+	return e.saveFinishedRun(run, err)
+}
+
+func (e *DefaultEngine) saveFinishedRun(run state.Run, runErr error) (*state.Run, error) {
+	finishedAt := time.Now()
+	run.FinishedAt = &finishedAt
+	run.Status = state.StatusSucceeded
+	if runErr != nil {
+		run.Status = state.StatusFailed
+		run.Error = runErr.Error()
+	}
+	if err := e.store.SaveRun(run); err != nil {
+		return &run, fmt.Errorf("could not save run: %w", err)
+	}
+	return &run, nil
+}
+
+// runState is the state of a single workflow run, shared by all of its steps
+type runState struct {
+	id      string
+	payload cty.Value
+	// outputs are the values of the processed steps, keyed by step name
+	outputs map[string]cty.Value
+	skipped map[string]bool
+	// previous are the step results saved before the run was interrupted, keyed by result name. It is
+	// empty unless the run is resumed.
+	previous map[string]state.StepResult
+}
+
+func newRunState(id string, payload cty.Value) *runState {
+	return &runState{
+		id:       id,
+		payload:  payload,
+		outputs:  make(map[string]cty.Value),
+		skipped:  make(map[string]bool),
+		previous: make(map[string]state.StepResult),
+	}
+}
+
+// completedResult returns the result saved under resultName before the run was interrupted, if that
+// step (or for_each iteration) had completed
+func (r *runState) completedResult(resultName string) (state.StepResult, bool) {
+	result, ok := r.previous[resultName]
+	return result, ok && result.Status != state.StatusRunning && result.Status != state.StatusPending
+}
+
+// ErrRunInterrupted is returned for runs whose context was cancelled before they finished. The results of
+// their completed steps are saved, and the steps that were not completed run when the run is resumed.
+var ErrRunInterrupted = errors.New("run interrupted")
+
+// StepError is returned when a failed step stops a workflow run
+type StepError struct {
+	Step string
+	Err  error
+}
+
+func (e *StepError) Error() string {
+	return fmt.Sprintf("step '%s' failed: %s", e.Step, e.Err)
+}
+
+func (e *StepError) Unwrap() error {
+	return e.Err
+}
+
+// runSteps processes the steps of a workflow, followed by the on_failure steps if a step failure
+// stopped the run.
+func (e *DefaultEngine) runSteps(ctx context.Context, workflow *internal.WorkflowBlock, run *runState) error {
+	rootContext := e.config.EvalContext()
+	runErr := e.runStepList(ctx, workflow.Steps, run, func() *hcl.EvalContext {
+		return internal.StepEvalContext(rootContext, run.payload, run.outputs)
+	})
+	var stepErr *StepError
+	if !errors.As(runErr, &stepErr) || len(workflow.OnFailure) == 0 {
+		return runErr
+	}
+	failureErr := e.runStepList(ctx, workflow.OnFailure, run, func() *hcl.EvalContext {
+		return internal.ErrorEvalContext(internal.StepEvalContext(rootContext, run.payload, run.outputs), stepErr.Step, stepErr.Err.Error())
+	})
+	if failureErr != nil {
+		return errors.Join(runErr, fmt.Errorf("on_failure: %w", failureErr))
+	}
+	return runErr
+}
+
+// runStepList processes a list of steps in order, applying the error policy of every failed step. The
+// value of every processed step is added to the outputs of the run, and skipped steps are marked as
+// skipped. evalContext is called before every step to get the context including those outputs.
+func (e *DefaultEngine) runStepList(ctx context.Context, steps []internal.StepBlock, run *runState, evalContext func() *hcl.EvalContext) error {
+	for i := 0; i < len(steps); i++ {
+		if ctx.Err() != nil {
+			return ErrRunInterrupted
+		}
+		step := steps[i]
+		value, status, err := e.runStep(ctx, step, run, evalContext())
+		run.outputs[step.Name] = value
+		if status == state.StatusSkipped {
+			run.skipped[step.Name] = true
+		}
+		if err == nil {
+			continue
+		}
+		// a cancelled run is never continued. The error policy of the step is applied when the run is resumed.
+		if ctx.Err() != nil {
+			return ErrRunInterrupted
+		}
+		switch step.OnError {
+		case internal.OnErrorContinue:
+			log.Printf("WARNING: step '%s' failed, continuing run: %s", step.Name, err)
+		case internal.OnErrorGoto:
+			log.Printf("WARNING: step '%s' failed, continuing run at step '%s': %s", step.Name, step.OnErrorStep, err)
+			for i < len(steps)-1 && steps[i+1].Name != step.OnErrorStep {
+				i++
+				run.skipped[steps[i].Name] = true
+				run.outputs[steps[i].Name] = stepValue(steps[i], cty.NullVal(steps[i].OutputType))
+				if err = e.skipStep(steps[i].Name, run.id); err != nil {
+					return fmt.Errorf("could not save step result: %w", err)
+				}
+			}
+		default:
+			return &StepError{Step: step.Name, Err: err}
+		}
+	}
+	return nil
+}
+
+// stepValue returns the value a step is referenced by through 'steps.<name>'. Steps without for_each are
+// an object with the output of the action, while for_each steps are a tuple of objects with the key and
+// output of every iteration.
+func stepValue(step internal.StepBlock, output cty.Value) cty.Value {
+	if step.ForEach != nil {
+		if output.IsNull() {
+			return cty.EmptyTupleVal
+		}
+		return output
+	}
+	return cty.ObjectVal(map[string]cty.Value{
+		"output": output,
+	})
+}
+
+// runStep runs a single step, and returns its value to be referenced by other steps. Steps whose
+// condition is false, or that depend on a skipped step, are skipped with a null output. Steps that completed
+// before a resumed run was interrupted are not run again.
+func (e *DefaultEngine) runStep(ctx context.Context, step internal.StepBlock, run *runState, evalContext *hcl.EvalContext) (cty.Value, state.RunStatus, error) {
+	if previous, ok := run.completedResult(step.Name); ok {
+		return restoreStep(step, previous)
+	}
+	skip, err := shouldSkipStep(step, evalContext, run.skipped)
+	if err == nil && skip {
+		return stepValue(step, cty.NullVal(step.OutputType)), state.StatusSkipped, e.skipStep(step.Name, run.id)
+	}
+	if err == nil && step.ForEach != nil {
+		return e.runIterations(ctx, step, run, evalContext)
+	}
+	output, status, err := e.runAction(ctx, step, step.Name, run, evalContext, err)
+	return stepValue(step, output), status, err
+}
+
+// StepValues returns the values of the steps of a workflow run, as referenced through 'steps.<name>', from
+// the saved step results of the run. Steps without a result have a null output.
+func StepValues(workflow *internal.WorkflowBlock, results []state.StepResult) map[string]cty.Value {
+	resultsByStep := make(map[string]state.StepResult)
+	for _, result := range results {
+		resultsByStep[result.Step] = result
+	}
+	output := make(map[string]cty.Value)
+	for _, step := range append(append([]internal.StepBlock{}, workflow.Steps...), workflow.OnFailure...) {
+		result, ok := resultsByStep[step.Name]
+		if !ok {
+			output[step.Name] = stepValue(step, cty.NullVal(step.OutputType))
+			continue
+		}
+		output[step.Name], _, _ = restoreStep(step, result)
+	}
+	return output
+}
+
+// restoreStep returns the value, status and error of a step from the result saved before the run was
+// interrupted
+func restoreStep(step internal.StepBlock, result state.StepResult) (cty.Value, state.RunStatus, error) {
+	var err error
+	if result.Status == state.StatusFailed {
+		err = errors.New(result.Error)
+	}
+	if result.Status == state.StatusSkipped || len(result.Output) == 0 {
+		return stepValue(step, cty.NullVal(step.OutputType)), result.Status, err
+	}
+	if step.ForEach == nil {
+		output, unmarshalErr := unmarshalValue(result.Output, step.OutputType)
+		if unmarshalErr != nil {
+			return stepValue(step, cty.NullVal(step.OutputType)), state.StatusFailed, errors.Join(err, fmt.Errorf("could not restore step output: %w", unmarshalErr))
+		}
+		return stepValue(step, output), result.Status, err
+	}
+	value, unmarshalErr := restoreIterations(step, result.Output)
+	if unmarshalErr != nil {
+		return cty.EmptyTupleVal, state.StatusFailed, errors.Join(err, fmt.Errorf("could not restore step output: %w", unmarshalErr))
+	}
+	return value, result.Status, err
+}
+
+// runAction evaluates the step configuration and calls the provider action, retrying failed calls according
+// to the retry policy of the step. The result is saved under resultName, along with every attempt. When the
+// action fails (or preconditionErr is set), the returned output is the fallback value of the step, or null
+// if it has none.
+//
+// A running result is saved before the action is called, so a step that was interrupted mid-call can be
+// recognised when the run is resumed. Such a step is only called again if it is idempotent.
+func (e *DefaultEngine) runAction(ctx context.Context, step internal.StepBlock, resultName string, run *runState, evalContext *hcl.EvalContext, preconditionErr error) (cty.Value, state.RunStatus, error) {
+	result := state.StepResult{
+		RunID:     run.id,
+		Step:      resultName,
+		Status:    state.StatusRunning,
+		StartedAt: time.Now(),
+	}
+	err := preconditionErr
+	if previous, ok := run.previous[resultName]; ok {
+		result.StartedAt = previous.StartedAt
+		result.Attempts = previous.Attempts
+		// the action is called again after a failed attempt, the same way it is retried
+		if err == nil && !step.Idempotent && !lastAttemptFailed(previous.Attempts) {
+			err = errors.New("step was interrupted and is not idempotent, so it is not called again")
+		}
+	}
+	if err == nil {
+		if saveErr := e.store.SaveStepResult(result); saveErr != nil {
+			err = fmt.Errorf("could not save step result: %w", saveErr)
+		}
+	}
+	var output cty.Value
+	if err == nil {
+		output, err = e.evaluateStep(ctx, step, evalContext, &result)
+		// the run was interrupted while waiting to retry, the failed attempts are already saved
+		if err != nil && ctx.Err() != nil && errors.Is(err, ctx.Err()) {
+			return cty.NullVal(step.OutputType), state.StatusRunning, ErrRunInterrupted
+		}
+	}
+	if err != nil {
+		output, err = e.fallbackOutput(step, evalContext, err, &result)
+	}
+	finishedAt := time.Now()
+	result.FinishedAt = &finishedAt
+	result.Status = state.StatusSucceeded
+	if err != nil {
+		result.Status = state.StatusFailed
+		result.Error = err.Error()
+	}
+	if saveErr := e.store.SaveStepResult(result); saveErr != nil {
+		return output, result.Status, errors.Join(err, fmt.Errorf("could not save step result: %w", saveErr))
+	}
+	return output, result.Status, err
+}
+
+// lastAttemptFailed returns true if the last attempt of a step failed, so that calling its action again
+// does not repeat a call that succeeded
+func lastAttemptFailed(attempts []state.Attempt) bool {
+	return len(attempts) > 0 && attempts[len(attempts)-1].Error != ""
+}
+
+// shouldSkipStep returns true if the step depends on a skipped step, or its condition evaluates to false
+func shouldSkipStep(step internal.StepBlock, evalContext *hcl.EvalContext, skipped map[string]bool) (bool, error) {
+	for _, dependency := range step.DependsOn {
+		if skipped[dependency] {
+			return true, nil
+		}
+	}
+	if step.Condition == nil {
+		return false, nil
+	}
+	value, diag := step.Condition.Value(evalContext)
+	if diag.HasErrors() {
+		return false, fmt.Errorf("could not evaluate condition: %w", diag)
+	}
+	value, err := convert.Convert(value, cty.Bool)
+	if err != nil || value.IsNull() || !value.IsKnown() {
+		return false, fmt.Errorf("condition must be true or false")
+	}
+	return value.False(), nil
+}
+
+// skipStep records a step (or for_each iteration) that was not run
+func (e *DefaultEngine) skipStep(resultName string, runID string) error {
+	now := time.Now()
+	return e.store.SaveStepResult(state.StepResult{
+		RunID:      runID,
+		Step:       resultName,
+		Status:     state.StatusSkipped,
+		StartedAt:  now,
+		FinishedAt: &now,
+	})
+}
+
+// fallbackOutput evaluates the fallback value of a failed step. The original error is always returned,
+// joined with any error from evaluating the fallback.
+func (e *DefaultEngine) fallbackOutput(step internal.StepBlock, evalContext *hcl.EvalContext, stepErr error, result *state.StepResult) (cty.Value, error) {
+	if step.Fallback == nil {
+		return cty.NullVal(step.OutputType), stepErr
+	}
+	value, diag := step.Fallback.Value(internal.ErrorEvalContext(evalContext, step.Name, stepErr.Error()))
+	if diag.HasErrors() {
+		return cty.NullVal(step.OutputType), errors.Join(stepErr, fmt.Errorf("could not evaluate fallback: %w", diag))
+	}
+	output, err := convert.Convert(value, step.OutputType)
+	if err != nil {
+		return cty.NullVal(step.OutputType), errors.Join(stepErr, fmt.Errorf("invalid fallback value: %w", err))
+	}
+	result.Output, err = marshalValue(output)
+	return output, errors.Join(stepErr, err)
+}
+
+func (e *DefaultEngine) evaluateStep(ctx context.Context, step internal.StepBlock, evalContext *hcl.EvalContext, result *state.StepResult) (cty.Value, error) {
+	input, diag := hcldec.Decode(step.Config, step.ConfigSchema.Decode(), evalContext)
+	if diag.HasErrors() {
+		return cty.NilVal, diag
+	}
+	inputJSON, err := marshalValue(input)
+	if err != nil {
+		return cty.NilVal, err
+	}
+	result.Input = inputJSON
+	actionInput, err := sbsdk.MarshalVal(&step.ConfigSchema, input)
+	if err != nil {
+		return cty.NilVal, fmt.Errorf("could not marshal action input: %w", err)
+	}
+	pluginName, _ := e.config.ProviderPluginName(step.Provider)
+	provider, err := e.pluginManager.ProviderInstance(pluginName)
+	if err != nil {
+		return cty.NilVal, err
+	}
+	rawOutput, err := e.evaluateAction(ctx, provider, step, actionInput, result)
+	if err != nil {
+		return cty.NilVal, err
+	}
+	output, err := unmarshalValue(rawOutput, step.OutputType)
+	if err != nil {
+		return cty.NilVal, fmt.Errorf("action returned invalid output: %w", err)
+	}
+	result.Output, err = marshalValue(output)
+	return output, err
+}
+
+// evaluateAction calls the provider action until it succeeds, or the retry policy of the step gives up.
+// Every call is recorded as an attempt on the step result.
+func (e *DefaultEngine) evaluateAction(ctx context.Context, provider sbsdk.Provider, step internal.StepBlock, input []byte, result *state.StepResult) ([]byte, error) {
+	// attempts made before a resumed run was interrupted are kept, but do not count towards the retry policy
+	previousAttempts := len(result.Attempts)
+	for attempt := 1; ; attempt++ {
+		startedAt := time.Now()
+		output, err := provider.ActionEvaluate(step.Action, e.providerConfigs[step.Provider], input)
+		record := state.Attempt{
+			Number:     previousAttempts + attempt,
+			StartedAt:  startedAt,
+			FinishedAt: time.Now(),
+		}
+		if err != nil {
+			record.Error = err.Error()
+		}
+		result.Attempts = append(result.Attempts, record)
+		if !step.Retry.ShouldRetry(attempt, err) {
+			return output, err
+		}
+		// checkpoint the failed attempt, so it is visible while waiting for the retry
+		if saveErr := e.store.SaveStepResult(*result); saveErr != nil {
+			return nil, errors.Join(err, fmt.Errorf("could not save step result: %w", saveErr))
+		}
+		select {
+		case <-ctx.Done():
+			return nil, errors.Join(err, ctx.Err())
+		case <-time.After(step.Retry.Backoff(attempt)):
+		}
+	}
+}
+
+// marshalValue encodes a value as plain JSON, which can be decoded again without knowing its type
+func marshalValue(value cty.Value) ([]byte, error) {
+	return ctyjson.SimpleJSONValue{Value: value}.MarshalJSON()
+}
+
+// unmarshalValue decodes a JSON value of type t. Values of any type, such as the outputs of actions that are
+// mocked in workflow tests, are decoded with the type implied by the JSON.
+func unmarshalValue(raw []byte, t cty.Type) (cty.Value, error) {
+	if t.HasDynamicTypes() {
+		var value ctyjson.SimpleJSONValue
+		err := value.UnmarshalJSON(raw)
+		return value.Value, err
+	}
+	return ctyjson.Unmarshal(raw, t)
+}
@@ -0,0 +1,732 @@
+package engine
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/switchboard-org/plugin-sdk/sbsdk"
+	"github.com/switchboard-org/switchboard/internal"
+	"github.com/switchboard-org/switchboard/sbtest"
+	"github.com/switchboard-org/switchboard/state"
+	"github.com/zclconf/go-cty/cty"
+	"reflect"
+	"regexp"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testAction echoes the action input as output, and fails when the message is "fail". It counts the
+// calls in flight to check how many items of a step are evaluated in parallel.
+type testAction struct {
+	mu          sync.Mutex
+	inFlight    int
+	maxInFlight int
+	delay       time.Duration
+}
+
+func (a *testAction) evaluate(input []byte) ([]byte, error) {
+	a.mu.Lock()
+	a.inFlight++
+	if a.inFlight > a.maxInFlight {
+		a.maxInFlight = a.inFlight
+	}
+	a.mu.Unlock()
+	time.Sleep(a.delay)
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.inFlight--
+	if bytes.Contains(input, []byte(`"message":"fail"`)) {
+		return nil, errors.New("action failed")
+	}
+	return input, nil
+}
+
+// testProvider creates a provider with the "echo" action, which fails a number of times with err before
+// succeeding, and the "fail" action, which always fails
+func testProvider(action *testAction, failures int, err error) *sbtest.FakeProvider {
+	schema := sbsdk.ObjectSchema{
+		"message": sbsdk.RequiredAttrSchema("message", sbsdk.String),
+	}
+	outputType := sbsdk.Object(map[string]sbsdk.Type{"message": sbsdk.String})
+	return sbtest.NewFakeProvider().
+		WithAction("echo", sbtest.FakeAction{ConfigSchema: schema, OutputType: outputType, Evaluate: action.evaluate, Failures: failures, Err: err}).
+		WithAction("fail", sbtest.FakeAction{ConfigSchema: schema, OutputType: outputType, Failures: -1})
+}
+
+// testPluginManager serves provider as the "test" required provider
+func testPluginManager(provider sbsdk.Provider) *sbtest.FakePluginManager {
+	return sbtest.NewFakePluginManager(map[string]sbsdk.Provider{"test": provider})
+}
+
+func testStepConfig(t *testing.T, src string) hcl.Body {
+	file, diag := hclparse.NewParser().ParseHCL([]byte(src), "step.hcl")
+	if diag.HasErrors() {
+		t.Fatalf("could not parse step config: %v", diag)
+	}
+	return file.Body
+}
+
+func testStep(t *testing.T, name string, config string, retry internal.RetryBlock) internal.StepBlock {
+	return testActionStep(t, name, "echo", config, retry)
+}
+
+func testActionStep(t *testing.T, name string, action string, config string, retry internal.RetryBlock) internal.StepBlock {
+	provider := testProvider(&testAction{}, 0, nil)
+	schema, _ := provider.ActionConfigurationSchema(action)
+	outputType, _ := provider.ActionOutputType(action)
+	return internal.StepBlock{
+		Name:         name,
+		Provider:     "test",
+		Action:       action,
+		Config:       testStepConfig(t, config),
+		ConfigSchema: schema,
+		OutputType:   outputType.ToCty(),
+		Retry:        retry,
+	}
+}
+
+func TestDefaultEngine_RunWorkflow_retry(t *testing.T) {
+	retry := internal.RetryBlock{
+		MaxAttempts:     3,
+		InitialInterval: time.Millisecond,
+		MaxInterval:     time.Millisecond,
+		Multiplier:      1,
+	}
+	retryRateLimits := retry
+	retryRateLimits.RetryOn = []*regexp.Regexp{regexp.MustCompile("rate limit")}
+	tests := []struct {
+		name         string
+		retry        internal.RetryBlock
+		failures     int
+		err          error
+		wantStatus   state.RunStatus
+		wantAttempts int
+	}{
+		{
+			name:         "succeeds without retries",
+			retry:        retry,
+			failures:     0,
+			err:          errors.New("rate limit exceeded"),
+			wantStatus:   state.StatusSucceeded,
+			wantAttempts: 1,
+		},
+		{
+			name:         "succeeds after retrying failed calls",
+			retry:        retry,
+			failures:     2,
+			err:          errors.New("rate limit exceeded"),
+			wantStatus:   state.StatusSucceeded,
+			wantAttempts: 3,
+		},
+		{
+			name:         "fails when attempts are exhausted",
+			retry:        retry,
+			failures:     3,
+			err:          errors.New("rate limit exceeded"),
+			wantStatus:   state.StatusFailed,
+			wantAttempts: 3,
+		},
+		{
+			name:         "does not retry errors not matching retry_on",
+			retry:        retryRateLimits,
+			failures:     1,
+			err:          errors.New("card declined"),
+			wantStatus:   state.StatusFailed,
+			wantAttempts: 1,
+		},
+		{
+			name:         "retries errors matching retry_on",
+			retry:        retryRateLimits,
+			failures:     1,
+			err:          errors.New("rate limit exceeded"),
+			wantStatus:   state.StatusSucceeded,
+			wantAttempts: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := testProvider(&testAction{}, tt.failures, tt.err)
+			config := &internal.RootSwitchboardConfig{
+				Switchboard: internal.SwitchboardBlock{
+					RequiredProviders: []internal.RequiredProviderBlock{{Name: "test"}},
+				},
+				Workflows: []internal.WorkflowBlock{
+					{
+						Name: "charge",
+						Steps: []internal.StepBlock{
+							testStep(t, "create_charge", `message = "hello"`, tt.retry),
+						},
+					},
+				},
+			}
+			store := state.NewMemoryStateStore()
+			e := NewDefaultEngine(config, testPluginManager(provider), store)
+			if err := e.Start(); err != nil {
+				t.Fatalf("Start() error = %v", err)
+			}
+			defer e.Stop()
+			run, err := e.RunWorkflow(context.Background(), "charge", cty.EmptyObjectVal)
+			if err != nil {
+				t.Fatalf("RunWorkflow() error = %v", err)
+			}
+			if run.Status != tt.wantStatus {
+				t.Errorf("RunWorkflow() status = %v, want %v (%s)", run.Status, tt.wantStatus, run.Error)
+			}
+			results, _ := store.StepResults(run.ID)
+			if len(results) != 1 || len(results[0].Attempts) != tt.wantAttempts {
+				t.Fatalf("StepResults() got = %v, want 1 result with %v attempts", results, tt.wantAttempts)
+			}
+			if provider.Calls("echo") != tt.wantAttempts {
+				t.Errorf("action calls = %v, want %v", provider.Calls("echo"), tt.wantAttempts)
+			}
+		})
+	}
+}
+
+func TestDefaultEngine_RunWorkflow_stepOutputs(t *testing.T) {
+	provider := testProvider(&testAction{}, 0, nil)
+	config := &internal.RootSwitchboardConfig{
+		Switchboard: internal.SwitchboardBlock{
+			RequiredProviders: []internal.RequiredProviderBlock{{Name: "test"}},
+		},
+		Workflows: []internal.WorkflowBlock{
+			{
+				Name: "greet",
+				Steps: []internal.StepBlock{
+					testStep(t, "first", `message = "hello ${trigger.name}"`, internal.DefaultRetryBlock()),
+					testStep(t, "second", `message = "${steps.first.output.message}!"`, internal.DefaultRetryBlock()),
+				},
+			},
+		},
+	}
+	store := state.NewMemoryStateStore()
+	e := NewDefaultEngine(config, testPluginManager(provider), store)
+	if err := e.Start(); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	defer e.Stop()
+	run, err := e.RunWorkflow(context.Background(), "greet", cty.ObjectVal(map[string]cty.Value{
+		"name": cty.StringVal("switchboard"),
+	}))
+	if err != nil || run.Status != state.StatusSucceeded {
+		t.Fatalf("RunWorkflow() got = %v, %v", run, err)
+	}
+	results, _ := store.StepResults(run.ID)
+	if len(results) != 2 || string(results[1].Output) != `{"message":"hello switchboard!"}` {
+		t.Errorf("StepResults() got = %v", results)
+	}
+}
+
+func testExpression(t *testing.T, src string) hcl.Expression {
+	expr, diag := hclsyntax.ParseExpression([]byte(src), "fallback.hcl", hcl.InitialPos)
+	if diag.HasErrors() {
+		t.Fatalf("could not parse expression: %v", diag)
+	}
+	return expr
+}
+
+func TestDefaultEngine_RunWorkflow_onError(t *testing.T) {
+	failing := func(onError internal.OnErrorAction, target string, fallback string) internal.StepBlock {
+		step := testActionStep(t, "charge", "fail", `message = "charge"`, internal.DefaultRetryBlock())
+		step.OnError = onError
+		step.OnErrorStep = target
+		if fallback != "" {
+			step.Fallback = testExpression(t, fallback)
+		}
+		return step
+	}
+	tests := []struct {
+		name       string
+		steps      []internal.StepBlock
+		onFailure  []internal.StepBlock
+		wantStatus state.RunStatus
+		// wantOutputs maps every step that should have run to its expected output
+		wantOutputs map[string]string
+	}{
+		{
+			name: "stops the run when a step fails",
+			steps: []internal.StepBlock{
+				failing(internal.OnErrorFail, "", ""),
+				testStep(t, "receipt", `message = "receipt"`, internal.DefaultRetryBlock()),
+			},
+			wantStatus:  state.StatusFailed,
+			wantOutputs: map[string]string{"charge": ""},
+		},
+		{
+			name: "continues with the fallback value as step output",
+			steps: []internal.StepBlock{
+				failing(internal.OnErrorContinue, "", `{message = "fallback for ${error.step}"}`),
+				testStep(t, "receipt", `message = steps.charge.output.message`, internal.DefaultRetryBlock()),
+			},
+			wantStatus: state.StatusSucceeded,
+			wantOutputs: map[string]string{
+				"charge":  `{"message":"fallback for charge"}`,
+				"receipt": `{"message":"fallback for charge"}`,
+			},
+		},
+		{
+			name: "goes to the target step, skipping steps in between",
+			steps: []internal.StepBlock{
+				failing(internal.OnErrorGoto, "refund", ""),
+				testStep(t, "receipt", `message = "receipt"`, internal.DefaultRetryBlock()),
+				testStep(t, "refund", `message = "refund"`, internal.DefaultRetryBlock()),
+			},
+			wantStatus: state.StatusSucceeded,
+			wantOutputs: map[string]string{
+				"charge":  "",
+				"receipt": "",
+				"refund":  `{"message":"refund"}`,
+			},
+		},
+		{
+			name: "runs on_failure steps with the error in scope",
+			steps: []internal.StepBlock{
+				failing(internal.OnErrorFail, "", ""),
+			},
+			onFailure: []internal.StepBlock{
+				testStep(t, "alert", `message = "${error.step}: ${error.message}"`, internal.DefaultRetryBlock()),
+			},
+			wantStatus: state.StatusFailed,
+			wantOutputs: map[string]string{
+				"charge": "",
+				"alert":  `{"message":"charge: action failed"}`,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &internal.RootSwitchboardConfig{
+				Switchboard: internal.SwitchboardBlock{
+					RequiredProviders: []internal.RequiredProviderBlock{{Name: "test"}},
+				},
+				Workflows: []internal.WorkflowBlock{
+					{Name: "charge", Steps: tt.steps, OnFailure: tt.onFailure},
+				},
+			}
+			store := state.NewMemoryStateStore()
+			e := NewDefaultEngine(config, testPluginManager(testProvider(&testAction{}, 0, nil)), store)
+			if err := e.Start(); err != nil {
+				t.Fatalf("Start() error = %v", err)
+			}
+			defer e.Stop()
+			run, err := e.RunWorkflow(context.Background(), "charge", cty.EmptyObjectVal)
+			if err != nil {
+				t.Fatalf("RunWorkflow() error = %v", err)
+			}
+			if run.Status != tt.wantStatus {
+				t.Errorf("RunWorkflow() status = %v, want %v (%s)", run.Status, tt.wantStatus, run.Error)
+			}
+			results, _ := store.StepResults(run.ID)
+			gotOutputs := make(map[string]string)
+			for _, result := range results {
+				gotOutputs[result.Step] = string(result.Output)
+			}
+			if !reflect.DeepEqual(gotOutputs, tt.wantOutputs) {
+				t.Errorf("step outputs = %v, want %v", gotOutputs, tt.wantOutputs)
+			}
+		})
+	}
+}
+
+func TestDefaultEngine_RunWorkflow_condition(t *testing.T) {
+	conditional := func(name string, config string, condition string) internal.StepBlock {
+		step := testStep(t, name, config, internal.DefaultRetryBlock())
+		step.Condition = testExpression(t, condition)
+		return step
+	}
+	dependent := testStep(t, "receipt", `message = steps.charge.output.message`, internal.DefaultRetryBlock())
+	dependent.DependsOn = []string{"charge"}
+	tests := []struct {
+		name         string
+		payload      cty.Value
+		steps        []internal.StepBlock
+		wantStatus   state.RunStatus
+		wantStatuses map[string]state.RunStatus
+	}{
+		{
+			name:    "runs the step when the condition is true",
+			payload: cty.ObjectVal(map[string]cty.Value{"amount": cty.NumberIntVal(100)}),
+			steps: []internal.StepBlock{
+				conditional("charge", `message = "charge"`, `trigger.amount > 0`),
+				dependent,
+			},
+			wantStatus: state.StatusSucceeded,
+			wantStatuses: map[string]state.RunStatus{
+				"charge":  state.StatusSucceeded,
+				"receipt": state.StatusSucceeded,
+			},
+		},
+		{
+			name:    "skips the step and its dependents when the condition is false",
+			payload: cty.ObjectVal(map[string]cty.Value{"amount": cty.NumberIntVal(0)}),
+			steps: []internal.StepBlock{
+				conditional("charge", `message = "charge"`, `trigger.amount > 0`),
+				dependent,
+				testStep(t, "log", `message = "log"`, internal.DefaultRetryBlock()),
+			},
+			wantStatus: state.StatusSucceeded,
+			wantStatuses: map[string]state.RunStatus{
+				"charge":  state.StatusSkipped,
+				"receipt": state.StatusSkipped,
+				"log":     state.StatusSucceeded,
+			},
+		},
+		{
+			name:    "fails the step when the condition is not a boolean",
+			payload: cty.ObjectVal(map[string]cty.Value{"amount": cty.NumberIntVal(0)}),
+			steps: []internal.StepBlock{
+				conditional("charge", `message = "charge"`, `trigger.amount`),
+			},
+			wantStatus: state.StatusFailed,
+			wantStatuses: map[string]state.RunStatus{
+				"charge": state.StatusFailed,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &internal.RootSwitchboardConfig{
+				Switchboard: internal.SwitchboardBlock{
+					RequiredProviders: []internal.RequiredProviderBlock{{Name: "test"}},
+				},
+				Workflows: []internal.WorkflowBlock{
+					{Name: "charge", Steps: tt.steps},
+				},
+			}
+			store := state.NewMemoryStateStore()
+			e := NewDefaultEngine(config, testPluginManager(testProvider(&testAction{}, 0, nil)), store)
+			if err := e.Start(); err != nil {
+				t.Fatalf("Start() error = %v", err)
+			}
+			defer e.Stop()
+			run, err := e.RunWorkflow(context.Background(), "charge", tt.payload)
+			if err != nil {
+				t.Fatalf("RunWorkflow() error = %v", err)
+			}
+			if run.Status != tt.wantStatus {
+				t.Errorf("RunWorkflow() status = %v, want %v (%s)", run.Status, tt.wantStatus, run.Error)
+			}
+			results, _ := store.StepResults(run.ID)
+			gotStatuses := make(map[string]state.RunStatus)
+			for _, result := range results {
+				gotStatuses[result.Step] = result.Status
+			}
+			if !reflect.DeepEqual(gotStatuses, tt.wantStatuses) {
+				t.Errorf("step statuses = %v, want %v", gotStatuses, tt.wantStatuses)
+			}
+		})
+	}
+}
+
+func TestDefaultEngine_RunWorkflow_forEach(t *testing.T) {
+	forEachStep := func(forEach string, maxParallel int, onError internal.OnErrorAction, fallback string) internal.StepBlock {
+		step := testStep(t, "charge", `message = each.value`, internal.DefaultRetryBlock())
+		step.ForEach = testExpression(t, forEach)
+		step.MaxParallel = maxParallel
+		step.OnError = onError
+		if fallback != "" {
+			step.Fallback = testExpression(t, fallback)
+		}
+		return step
+	}
+	summary := testStep(t, "summary", `message = join(",", steps.charge[*].output.message)`, internal.DefaultRetryBlock())
+	summary.DependsOn = []string{"charge"}
+	tests := []struct {
+		name            string
+		step            internal.StepBlock
+		wantStatus      state.RunStatus
+		wantSummary     string
+		wantCalls       int
+		wantMaxInFlight int
+	}{
+		{
+			name:            "runs every item with bounded parallelism",
+			step:            forEachStep(`trigger.items`, 2, internal.OnErrorFail, ""),
+			wantStatus:      state.StatusSucceeded,
+			wantSummary:     `{"message":"a,b,c,d"}`,
+			wantCalls:       5,
+			wantMaxInFlight: 2,
+		},
+		{
+			name:            "iterates over map values",
+			step:            forEachStep(`{first = "a", second = "b"}`, 1, internal.OnErrorFail, ""),
+			wantStatus:      state.StatusSucceeded,
+			wantSummary:     `{"message":"a,b"}`,
+			wantCalls:       3,
+			wantMaxInFlight: 1,
+		},
+		{
+			name:            "uses the fallback for failed items when continuing",
+			step:            forEachStep(`["a", "fail", "c"]`, 1, internal.OnErrorContinue, `{message = "skipped ${each.key}"}`),
+			wantStatus:      state.StatusSucceeded,
+			wantSummary:     `{"message":"a,skipped 1,c"}`,
+			wantCalls:       4,
+			wantMaxInFlight: 1,
+		},
+		{
+			name:            "stops starting items after a failure when failing the run",
+			step:            forEachStep(`["a", "fail", "c"]`, 1, internal.OnErrorFail, ""),
+			wantStatus:      state.StatusFailed,
+			wantCalls:       2,
+			wantMaxInFlight: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			action := &testAction{delay: 5 * time.Millisecond}
+			provider := testProvider(action, 0, nil)
+			config := &internal.RootSwitchboardConfig{
+				Switchboard: internal.SwitchboardBlock{
+					RequiredProviders: []internal.RequiredProviderBlock{{Name: "test"}},
+				},
+				Workflows: []internal.WorkflowBlock{
+					{Name: "charge", Steps: []internal.StepBlock{tt.step, summary}},
+				},
+			}
+			store := state.NewMemoryStateStore()
+			e := NewDefaultEngine(config, testPluginManager(provider), store)
+			if err := e.Start(); err != nil {
+				t.Fatalf("Start() error = %v", err)
+			}
+			defer e.Stop()
+			run, err := e.RunWorkflow(context.Background(), "charge", cty.ObjectVal(map[string]cty.Value{
+				"items": cty.ListVal([]cty.Value{cty.StringVal("a"), cty.StringVal("b"), cty.StringVal("c"), cty.StringVal("d")}),
+			}))
+			if err != nil {
+				t.Fatalf("RunWorkflow() error = %v", err)
+			}
+			if run.Status != tt.wantStatus {
+				t.Errorf("RunWorkflow() status = %v, want %v (%s)", run.Status, tt.wantStatus, run.Error)
+			}
+			results, _ := store.StepResults(run.ID)
+			for _, result := range results {
+				if result.Step == "summary" && string(result.Output) != tt.wantSummary {
+					t.Errorf("summary output = %s, want %s", result.Output, tt.wantSummary)
+				}
+			}
+			if provider.Calls("echo") != tt.wantCalls {
+				t.Errorf("action calls = %v, want %v", provider.Calls("echo"), tt.wantCalls)
+			}
+			if action.maxInFlight != tt.wantMaxInFlight {
+				t.Errorf("max parallel calls = %v, want %v", action.maxInFlight, tt.wantMaxInFlight)
+			}
+		})
+	}
+}
+
+func TestDefaultEngine_ResumeRuns(t *testing.T) {
+	startedAt := time.Now().Add(-time.Minute)
+	finishedAt := startedAt.Add(time.Second)
+	completed := func(step string, output string) state.StepResult {
+		return state.StepResult{Step: step, Status: state.StatusSucceeded, Output: []byte(output), StartedAt: startedAt, FinishedAt: &finishedAt}
+	}
+	interrupted := func(step string) state.StepResult {
+		return state.StepResult{Step: step, Status: state.StatusRunning, StartedAt: startedAt}
+	}
+	steps := func(idempotent bool) []internal.StepBlock {
+		second := testStep(t, "second", `message = "${steps.first.output.message}!"`, internal.DefaultRetryBlock())
+		second.DependsOn = []string{"first"}
+		second.Idempotent = idempotent
+		third := testStep(t, "third", `message = "${steps.second.output.message}?"`, internal.DefaultRetryBlock())
+		third.DependsOn = []string{"second"}
+		return []internal.StepBlock{
+			testStep(t, "first", `message = "hello ${trigger.name}"`, internal.DefaultRetryBlock()),
+			second,
+			third,
+		}
+	}
+	forEachStep := testStep(t, "charge", `message = each.value`, internal.DefaultRetryBlock())
+	forEachStep.ForEach = testExpression(t, `trigger.items`)
+	tests := []struct {
+		name       string
+		workflow   string
+		steps      []internal.StepBlock
+		results    []state.StepResult
+		wantStatus state.RunStatus
+		wantOutput map[string]string
+		wantCalls  int
+	}{
+		{
+			name:       "runs only the steps that did not complete",
+			workflow:   "greet",
+			steps:      steps(false),
+			results:    []state.StepResult{completed("first", `{"message":"hello before restart"}`)},
+			wantStatus: state.StatusSucceeded,
+			wantOutput: map[string]string{"third": `{"message":"hello before restart!?"}`},
+			wantCalls:  2,
+		},
+		{
+			name:       "calls an interrupted idempotent step again",
+			workflow:   "greet",
+			steps:      steps(true),
+			results:    []state.StepResult{completed("first", `{"message":"hello"}`), interrupted("second")},
+			wantStatus: state.StatusSucceeded,
+			wantOutput: map[string]string{"third": `{"message":"hello!?"}`},
+			wantCalls:  2,
+		},
+		{
+			name:       "fails an interrupted step that is not idempotent",
+			workflow:   "greet",
+			steps:      steps(false),
+			results:    []state.StepResult{completed("first", `{"message":"hello"}`), interrupted("second")},
+			wantStatus: state.StatusFailed,
+			wantCalls:  0,
+		},
+		{
+			name:     "calls a step that is not idempotent again after its last attempt failed",
+			workflow: "greet",
+			steps:    steps(false),
+			results: []state.StepResult{
+				completed("first", `{"message":"hello"}`),
+				{Step: "second", Status: state.StatusRunning, StartedAt: startedAt, Attempts: []state.Attempt{{Number: 1, Error: "rate limit exceeded"}}},
+			},
+			wantStatus: state.StatusSucceeded,
+			wantOutput: map[string]string{"third": `{"message":"hello!?"}`},
+			wantCalls:  2,
+		},
+		{
+			name:       "runs only the for_each iterations that did not complete",
+			workflow:   "greet",
+			steps:      []internal.StepBlock{forEachStep},
+			results:    []state.StepResult{completed("charge[0]", `{"message":"a"}`)},
+			wantStatus: state.StatusSucceeded,
+			wantOutput: map[string]string{"charge": `[{"key":0,"output":{"message":"a"}},{"key":1,"output":{"message":"b"}}]`},
+			wantCalls:  1,
+		},
+		{
+			name:       "fails runs of removed workflows",
+			workflow:   "removed",
+			steps:      steps(false),
+			wantStatus: state.StatusFailed,
+			wantCalls:  0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := testProvider(&testAction{}, 0, nil)
+			config := &internal.RootSwitchboardConfig{
+				Switchboard: internal.SwitchboardBlock{
+					RequiredProviders: []internal.RequiredProviderBlock{{Name: "test"}},
+				},
+				Workflows: []internal.WorkflowBlock{{Name: "greet", Steps: tt.steps}},
+			}
+			store := state.NewMemoryStateStore()
+			run := state.Run{
+				ID:        state.NewID(),
+				Workflow:  tt.workflow,
+				Status:    state.StatusRunning,
+				Payload:   []byte(`{"name":"switchboard","items":["a","b"]}`),
+				StartedAt: startedAt,
+			}
+			if err := store.SaveRun(run); err != nil {
+				t.Fatalf("SaveRun() error = %v", err)
+			}
+			for _, result := range tt.results {
+				result.RunID = run.ID
+				if err := store.SaveStepResult(result); err != nil {
+					t.Fatalf("SaveStepResult() error = %v", err)
+				}
+			}
+			e := NewDefaultEngine(config, testPluginManager(provider), store)
+			if err := e.Start(); err != nil {
+				t.Fatalf("Start() error = %v", err)
+			}
+			defer e.Stop()
+			runs, err := e.ResumeRuns(context.Background())
+			if err != nil || len(runs) != 1 {
+				t.Fatalf("ResumeRuns() got = %v, %v", runs, err)
+			}
+			if runs[0].ID != run.ID || runs[0].Status != tt.wantStatus {
+				t.Errorf("ResumeRuns() status = %v, want %v (%s)", runs[0].Status, tt.wantStatus, runs[0].Error)
+			}
+			results, _ := store.StepResults(run.ID)
+			for _, result := range results {
+				if want, ok := tt.wantOutput[result.Step]; ok && string(result.Output) != want {
+					t.Errorf("%s output = %s, want %s", result.Step, result.Output, want)
+				}
+			}
+			if provider.Calls("echo") != tt.wantCalls {
+				t.Errorf("action calls = %v, want %v", provider.Calls("echo"), tt.wantCalls)
+			}
+			if unfinished, _ := store.Runs(state.RunFilter{Status: state.StatusRunning}); len(unfinished) != 0 {
+				t.Errorf("Runs() unfinished = %v, want none", unfinished)
+			}
+		})
+	}
+}
+
+func TestDefaultEngine_RunWorkflow_interrupted(t *testing.T) {
+	// the retry waits until the run is interrupted
+	retry := internal.RetryBlock{MaxAttempts: 3, InitialInterval: time.Hour, MaxInterval: time.Hour, Multiplier: 1}
+	tests := []struct {
+		name         string
+		failures     int
+		wantResults  int
+		wantAttempts int
+	}{
+		{
+			name:         "does not start steps of an interrupted run",
+			failures:     0,
+			wantResults:  0,
+			wantAttempts: 0,
+		},
+		{
+			name:         "keeps the failed attempts of a step waiting to retry",
+			failures:     1,
+			wantResults:  1,
+			wantAttempts: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := testProvider(&testAction{}, tt.failures, errors.New("rate limit exceeded"))
+			config := &internal.RootSwitchboardConfig{
+				Switchboard: internal.SwitchboardBlock{
+					RequiredProviders: []internal.RequiredProviderBlock{{Name: "test"}},
+				},
+				Workflows: []internal.WorkflowBlock{{Name: "charge", Steps: []internal.StepBlock{testStep(t, "create_charge", `message = "hello"`, retry)}}},
+			}
+			store := state.NewMemoryStateStore()
+			e := NewDefaultEngine(config, testPluginManager(provider), store)
+			if err := e.Start(); err != nil {
+				t.Fatalf("Start() error = %v", err)
+			}
+			defer e.Stop()
+			ctx, cancel := context.WithCancel(context.Background())
+			if tt.failures == 0 {
+				cancel()
+			} else {
+				go func() {
+					for {
+						if provider.Calls("echo") > 0 {
+							cancel()
+							return
+						}
+						time.Sleep(time.Millisecond)
+					}
+				}()
+			}
+			run, err := e.RunWorkflow(ctx, "charge", cty.EmptyObjectVal)
+			if !errors.Is(err, ErrRunInterrupted) {
+				t.Fatalf("RunWorkflow() error = %v, want %v", err, ErrRunInterrupted)
+			}
+			if saved, _ := store.Run(run.ID); saved == nil || saved.Status != state.StatusRunning {
+				t.Errorf("Run() = %v, want a running run", saved)
+			}
+			results, _ := store.StepResults(run.ID)
+			if len(results) != tt.wantResults || (tt.wantResults > 0 && (results[0].Status != state.StatusRunning || len(results[0].Attempts) != tt.wantAttempts)) {
+				t.Fatalf("StepResults() = %v, want %d running results with %d attempts", results, tt.wantResults, tt.wantAttempts)
+			}
+
+			// the interrupted run is finished when it is resumed
+			runs, err := e.ResumeRuns(context.Background())
+			if err != nil || len(runs) != 1 || runs[0].Status != state.StatusSucceeded {
+				t.Fatalf("ResumeRuns() got = %v, %v, want a succeeded run", runs, err)
+			}
+		})
+	}
+}
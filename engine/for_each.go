@@ -0,0 +1,206 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/hashicorp/hcl/v2"
+	"github.com/switchboard-org/switchboard/internal"
+	"github.com/switchboard-org/switchboard/state"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/convert"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
+	"sync"
+	"time"
+)
+
+// iteration is a single element of the for_each value of a step
+type iteration struct {
+	key   cty.Value
+	value cty.Value
+}
+
+// forEachIterations returns the iterations of a for_each value. Lists and tuples are keyed by index, maps
+// and objects by attribute name, and sets by the element itself.
+func forEachIterations(forEach cty.Value) ([]iteration, error) {
+	if forEach.IsNull() || !forEach.IsWhollyKnown() {
+		return nil, errors.New("for_each value must not be null")
+	}
+	if !forEach.CanIterateElements() {
+		return nil, fmt.Errorf("for_each must be a list, set, map or object, got %s", forEach.Type().FriendlyName())
+	}
+	var iterations []iteration
+	for it := forEach.ElementIterator(); it.Next(); {
+		key, value := it.Element()
+		iterations = append(iterations, iteration{key: key, value: value})
+	}
+	return iterations, nil
+}
+
+// iterationResultName is the name the result of a single iteration is saved under, such as 'charge["sku-1"]'
+func iterationResultName(step string, key cty.Value) string {
+	rawKey, err := marshalValue(key)
+	if err != nil {
+		return fmt.Sprintf("%s[%s]", step, key.GoString())
+	}
+	return fmt.Sprintf("%s[%s]", step, rawKey)
+}
+
+// runIterations calls the action of a for_each step once for every element of its for_each value, with at
+// most MaxParallel calls running at the same time. Failed iterations use the fallback value of the step as
+// output. When the step fails the run, no new iterations are started after the first failure.
+func (e *DefaultEngine) runIterations(ctx context.Context, step internal.StepBlock, run *runState, evalContext *hcl.EvalContext) (cty.Value, state.RunStatus, error) {
+	result := state.StepResult{
+		RunID:     run.id,
+		Step:      step.Name,
+		Status:    state.StatusRunning,
+		StartedAt: time.Now(),
+	}
+	var iterations []iteration
+	var err error
+	forEach, diag := step.ForEach.Value(evalContext)
+	if diag.HasErrors() {
+		err = fmt.Errorf("could not evaluate for_each: %w", diag)
+	} else {
+		iterations, err = forEachIterations(forEach)
+	}
+
+	outputs := make([]cty.Value, len(iterations))
+	errs := make([]error, len(iterations))
+	if err == nil {
+		e.runIterationsParallel(ctx, step, run, evalContext, iterations, outputs, errs)
+		err = errors.Join(errs...)
+	}
+	if errors.Is(err, ErrRunInterrupted) {
+		return cty.NullVal(step.OutputType), state.StatusRunning, ErrRunInterrupted
+	}
+
+	value := cty.EmptyTupleVal
+	if len(outputs) > 0 {
+		value = cty.TupleVal(outputs)
+	}
+	finishedAt := time.Now()
+	result.FinishedAt = &finishedAt
+	result.Status = state.StatusSucceeded
+	if err != nil {
+		result.Status = state.StatusFailed
+		result.Error = err.Error()
+	}
+	output, marshalErr := marshalValue(value)
+	result.Output = output
+	err = errors.Join(err, marshalErr)
+	if saveErr := e.store.SaveStepResult(result); saveErr != nil {
+		return value, result.Status, errors.Join(err, fmt.Errorf("could not save step result: %w", saveErr))
+	}
+	return value, result.Status, err
+}
+
+func (e *DefaultEngine) runIterationsParallel(ctx context.Context, step internal.StepBlock, run *runState, evalContext *hcl.EvalContext, iterations []iteration, outputs []cty.Value, errs []error) {
+	maxParallel := step.MaxParallel
+	if maxParallel <= 0 {
+		maxParallel = len(iterations)
+	}
+	semaphore := make(chan struct{}, maxParallel)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	failed := false
+	failsRun := step.OnError != internal.OnErrorContinue && step.OnError != internal.OnErrorGoto
+	for i, current := range iterations {
+		semaphore <- struct{}{}
+		mu.Lock()
+		stop := failed && failsRun
+		mu.Unlock()
+		resultName := iterationResultName(step.Name, current.key)
+		// iterations that completed before a resumed run was interrupted are not run again
+		if previous, ok := run.completedResult(resultName); ok {
+			<-semaphore
+			output, err := restoreIteration(step, previous)
+			outputs[i] = iterationValue(current.key, output)
+			if err != nil {
+				errs[i] = fmt.Errorf("%s: %w", resultName, err)
+				mu.Lock()
+				failed = true
+				mu.Unlock()
+			}
+			continue
+		}
+		// iterations that were not started when the run was interrupted run when it is resumed
+		if ctx.Err() != nil {
+			<-semaphore
+			errs[i] = ErrRunInterrupted
+			continue
+		}
+		if stop {
+			<-semaphore
+			outputs[i] = iterationValue(current.key, cty.NullVal(step.OutputType))
+			if err := e.skipStep(resultName, run.id); err != nil {
+				errs[i] = fmt.Errorf("could not save step result: %w", err)
+			}
+			continue
+		}
+		wg.Add(1)
+		go func(i int, current iteration) {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+			iterationContext := internal.EachEvalContext(evalContext, current.key, current.value)
+			output, _, err := e.runAction(ctx, step, resultName, run, iterationContext, nil)
+			outputs[i] = iterationValue(current.key, output)
+			if err != nil {
+				errs[i] = fmt.Errorf("%s: %w", resultName, err)
+				mu.Lock()
+				failed = true
+				mu.Unlock()
+			}
+		}(i, current)
+	}
+	wg.Wait()
+}
+
+func iterationValue(key cty.Value, output cty.Value) cty.Value {
+	return cty.ObjectVal(map[string]cty.Value{
+		"key":    key,
+		"output": output,
+	})
+}
+
+// restoreIteration returns the output and error of a single iteration from the result saved before the run
+// was interrupted
+func restoreIteration(step internal.StepBlock, result state.StepResult) (cty.Value, error) {
+	var err error
+	if result.Status == state.StatusFailed {
+		err = errors.New(result.Error)
+	}
+	if result.Status == state.StatusSkipped || len(result.Output) == 0 {
+		return cty.NullVal(step.OutputType), err
+	}
+	output, unmarshalErr := unmarshalValue(result.Output, step.OutputType)
+	if unmarshalErr != nil {
+		return cty.NullVal(step.OutputType), errors.Join(err, fmt.Errorf("could not restore iteration output: %w", unmarshalErr))
+	}
+	return output, err
+}
+
+// restoreIterations decodes the saved value of a for_each step. The keys keep the type they were saved with,
+// while the outputs are converted back to the output type of the step.
+func restoreIterations(step internal.StepBlock, raw []byte) (cty.Value, error) {
+	var saved ctyjson.SimpleJSONValue
+	if err := saved.UnmarshalJSON(raw); err != nil {
+		return cty.NilVal, err
+	}
+	if !saved.Type().IsTupleType() || saved.LengthInt() == 0 {
+		return cty.EmptyTupleVal, nil
+	}
+	var outputs []cty.Value
+	for it := saved.ElementIterator(); it.Next(); {
+		_, element := it.Element()
+		if !element.Type().IsObjectType() || !element.Type().HasAttribute("key") || !element.Type().HasAttribute("output") {
+			return cty.NilVal, errors.New("saved iteration has no key and output")
+		}
+		output, err := convert.Convert(element.GetAttr("output"), step.OutputType)
+		if err != nil {
+			return cty.NilVal, err
+		}
+		outputs = append(outputs, iterationValue(element.GetAttr("key"), output))
+	}
+	return cty.TupleVal(outputs), nil
+}
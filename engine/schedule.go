@@ -0,0 +1,176 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/switchboard-org/switchboard/internal"
+	"github.com/switchboard-org/switchboard/state"
+	"github.com/zclconf/go-cty/cty"
+	"log"
+	"sync"
+	"time"
+)
+
+// maxCatchUpRuns is the largest number of missed scheduled times that are caught up when the server starts.
+// Older missed times are dropped.
+const maxCatchUpRuns = 100
+
+// Scheduler starts the workflows of schedule triggers at their scheduled times
+type Scheduler interface {
+	// Run blocks until ctx is cancelled, starting workflow runs as they become due. Runs missed while the
+	// server was down are skipped or caught up first, according to the missed run policy of each trigger.
+	Run(ctx context.Context) error
+}
+
+type DefaultScheduler struct {
+	config *internal.RootSwitchboardConfig
+	engine Engine
+	store  state.StateStore
+	now    func() time.Time
+	// runContext is the context of the workflow runs started by the scheduler, the context of Run if nil
+	runContext context.Context
+	// runs tracks the workflow runs started by the scheduler
+	runs sync.WaitGroup
+}
+
+// SchedulerOption changes the behaviour of a DefaultScheduler
+type SchedulerOption func(*DefaultScheduler)
+
+// WithRunContext starts workflow runs with ctx instead of the context of Run, so that cancelling Run stops
+// the schedules without interrupting the runs that were already started
+func WithRunContext(ctx context.Context) SchedulerOption {
+	return func(s *DefaultScheduler) {
+		s.runContext = ctx
+	}
+}
+
+func NewDefaultScheduler(config *internal.RootSwitchboardConfig, engine Engine, store state.StateStore, opts ...SchedulerOption) Scheduler {
+	s := &DefaultScheduler{
+		config: config,
+		engine: engine,
+		store:  store,
+		now:    time.Now,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+func (s *DefaultScheduler) Run(ctx context.Context) error {
+	var wg sync.WaitGroup
+	for _, trigger := range s.config.Triggers {
+		if trigger.Schedule == nil || len(s.config.TriggeredWorkflows(trigger.Name)) == 0 {
+			continue
+		}
+		wg.Add(1)
+		go func(trigger internal.TriggerBlock) {
+			defer wg.Done()
+			s.runSchedule(ctx, trigger)
+		}(trigger)
+	}
+	wg.Wait()
+	s.runs.Wait()
+	return nil
+}
+
+// runSchedule starts the workflows of a trigger every time its schedule is due, until ctx is cancelled
+func (s *DefaultScheduler) runSchedule(ctx context.Context, trigger internal.TriggerBlock) {
+	last := s.catchUp(ctx, trigger)
+	for {
+		next := trigger.Schedule.Next(last)
+		if next.IsZero() {
+			return
+		}
+		timer := time.NewTimer(next.Sub(s.now()))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		s.startRuns(ctx, trigger, next)
+		last = next
+	}
+}
+
+// catchUp handles the scheduled times that passed since the last scheduled run of the trigger, and returns
+// the time the schedule continues from. Nothing is missed the first time a trigger is scheduled.
+func (s *DefaultScheduler) catchUp(ctx context.Context, trigger internal.TriggerBlock) time.Time {
+	now := s.now()
+	last, err := s.lastScheduledAt(trigger.Name)
+	if err != nil {
+		if !errors.Is(err, state.ErrNotFound) {
+			log.Printf("WARNING: could not get last scheduled time of trigger '%s': %s", trigger.Name, err)
+		}
+		s.saveScheduledAt(trigger.Name, now)
+		return now
+	}
+	missed, count := trigger.Schedule.Between(last, now, maxCatchUpRuns)
+	if count == 0 {
+		return last
+	}
+	if trigger.Schedule.MissedRuns != internal.MissedRunsCatchUp {
+		log.Printf("WARNING: skipping %d scheduled runs of trigger '%s' that were missed since %s", count, trigger.Name, last.Format(time.RFC3339))
+		s.saveScheduledAt(trigger.Name, now)
+		return now
+	}
+	if dropped := count - len(missed); dropped > 0 {
+		log.Printf("WARNING: dropping %d scheduled runs of trigger '%s' missed between %s and %s, only the last %d are caught up",
+			dropped, trigger.Name, last.Format(time.RFC3339), missed[0].Format(time.RFC3339), maxCatchUpRuns)
+	}
+	log.Printf("catching up %d scheduled runs of trigger '%s' that were missed since %s", len(missed), trigger.Name, last.Format(time.RFC3339))
+	// missed times are run one after the other, so that catching up does not start every run at once
+	for _, scheduledAt := range missed {
+		s.startRuns(ctx, trigger, scheduledAt).Wait()
+		if ctx.Err() != nil {
+			return scheduledAt
+		}
+	}
+	return missed[len(missed)-1]
+}
+
+// startRuns records the scheduled time of the trigger, and starts a run of every workflow it triggers. The
+// returned wait group is done once the runs finish.
+func (s *DefaultScheduler) startRuns(ctx context.Context, trigger internal.TriggerBlock, scheduledAt time.Time) *sync.WaitGroup {
+	s.saveScheduledAt(trigger.Name, scheduledAt)
+	payload := cty.ObjectVal(map[string]cty.Value{
+		"scheduled_at": cty.StringVal(scheduledAt.In(trigger.Schedule.Location).Format(time.RFC3339)),
+	})
+	runContext := ctx
+	if s.runContext != nil {
+		runContext = s.runContext
+	}
+	var runs sync.WaitGroup
+	for _, workflow := range s.config.TriggeredWorkflows(trigger.Name) {
+		s.runs.Add(1)
+		runs.Add(1)
+		go func(workflow string) {
+			defer s.runs.Done()
+			defer runs.Done()
+			if _, err := s.engine.RunWorkflow(runContext, workflow, payload); err != nil {
+				log.Printf("WARNING: could not run workflow '%s' scheduled by trigger '%s': %s", workflow, trigger.Name, err)
+			}
+		}(workflow)
+	}
+	return &runs
+}
+
+func scheduleKey(trigger string) string {
+	return fmt.Sprintf("schedules/%s/last_scheduled_at", trigger)
+}
+
+func (s *DefaultScheduler) lastScheduledAt(trigger string) (time.Time, error) {
+	raw, err := s.store.Get(scheduleKey(trigger))
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Parse(time.RFC3339Nano, string(raw))
+}
+
+func (s *DefaultScheduler) saveScheduledAt(trigger string, scheduledAt time.Time) {
+	if err := s.store.Put(scheduleKey(trigger), []byte(scheduledAt.Format(time.RFC3339Nano))); err != nil {
+		log.Printf("WARNING: could not save last scheduled time of trigger '%s': %s", trigger, err)
+	}
+}
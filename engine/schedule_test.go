@@ -0,0 +1,167 @@
+package engine
+
+import (
+	"context"
+	"github.com/switchboard-org/switchboard/internal"
+	"github.com/switchboard-org/switchboard/state"
+	"github.com/zclconf/go-cty/cty"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testEngine records the workflow runs it is asked to start
+type testEngine struct {
+	mu       sync.Mutex
+	runs     []string
+	contexts []context.Context
+}
+
+func (e *testEngine) Start() error {
+	return nil
+}
+
+func (e *testEngine) RunWorkflow(ctx context.Context, workflow string, payload cty.Value) (*state.Run, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.runs = append(e.runs, workflow+" "+payload.GetAttr("scheduled_at").AsString())
+	e.contexts = append(e.contexts, ctx)
+	return &state.Run{Workflow: workflow, Status: state.StatusSucceeded}, nil
+}
+
+func (e *testEngine) ResumeRuns(_ context.Context) ([]*state.Run, error) {
+	return nil, nil
+}
+
+func (e *testEngine) Stop() {}
+
+func TestDefaultScheduler_catchUp(t *testing.T) {
+	berlin, _ := time.LoadLocation("Europe/Berlin")
+	now := time.Date(2023, 3, 4, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name          string
+		expression    string
+		missedRuns    internal.MissedRunPolicy
+		lastScheduled *time.Time
+		wantRuns      []string
+		// wantRunCount is checked instead of wantRuns if set
+		wantRunCount int
+		wantNext     time.Time
+	}{
+		{
+			name:       "does not run anything the first time a trigger is scheduled",
+			missedRuns: internal.MissedRunsCatchUp,
+			wantNext:   time.Date(2023, 3, 5, 2, 0, 0, 0, berlin),
+		},
+		{
+			name:          "skips missed runs",
+			missedRuns:    internal.MissedRunsSkip,
+			lastScheduled: timePointer(time.Date(2023, 3, 2, 2, 0, 0, 0, berlin)),
+			wantNext:      time.Date(2023, 3, 5, 2, 0, 0, 0, berlin),
+		},
+		{
+			name:          "catches up every missed run in order",
+			missedRuns:    internal.MissedRunsCatchUp,
+			lastScheduled: timePointer(time.Date(2023, 3, 2, 2, 0, 0, 0, berlin)),
+			wantRuns:      []string{"report 2023-03-03T02:00:00+01:00", "report 2023-03-04T02:00:00+01:00"},
+			wantNext:      time.Date(2023, 3, 5, 2, 0, 0, 0, berlin),
+		},
+		{
+			name:          "only catches up the most recent missed runs",
+			expression:    "* * * * *",
+			missedRuns:    internal.MissedRunsCatchUp,
+			lastScheduled: timePointer(now.Add(-24 * time.Hour)),
+			wantRunCount:  maxCatchUpRuns,
+			wantNext:      now.Add(time.Minute),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expression := "0 2 * * *"
+			if tt.expression != "" {
+				expression = tt.expression
+			}
+			schedule, err := internal.NewScheduleBlock(expression, berlin, tt.missedRuns)
+			if err != nil {
+				t.Fatalf("NewScheduleBlock() error = %v", err)
+			}
+			trigger := internal.TriggerBlock{Name: "nightly", Schedule: schedule}
+			config := &internal.RootSwitchboardConfig{
+				Triggers:  []internal.TriggerBlock{trigger},
+				Workflows: []internal.WorkflowBlock{{Name: "report", Trigger: "nightly"}, {Name: "manual"}},
+			}
+			store := state.NewMemoryStateStore()
+			engine := &testEngine{}
+			s := &DefaultScheduler{config: config, engine: engine, store: store, now: func() time.Time { return now }}
+			if tt.lastScheduled != nil {
+				s.saveScheduledAt(trigger.Name, *tt.lastScheduled)
+			}
+			last := s.catchUp(context.Background(), trigger)
+			s.runs.Wait()
+			if tt.wantRunCount > 0 {
+				if !sort.StringsAreSorted(engine.runs) || len(engine.runs) != tt.wantRunCount {
+					t.Errorf("catchUp() started %d runs, want %d in order", len(engine.runs), tt.wantRunCount)
+				}
+			} else if !reflect.DeepEqual(engine.runs, tt.wantRuns) {
+				t.Errorf("catchUp() runs = %v, want %v", engine.runs, tt.wantRuns)
+			}
+			if next := schedule.Next(last); !next.Equal(tt.wantNext) {
+				t.Errorf("catchUp() next scheduled time = %v, want %v", next, tt.wantNext)
+			}
+			if saved, err := s.lastScheduledAt(trigger.Name); err != nil || !saved.Equal(last) {
+				t.Errorf("lastScheduledAt() = %v, %v, want %v", saved, err, last)
+			}
+		})
+	}
+}
+
+func TestDefaultScheduler_startRuns(t *testing.T) {
+	schedule, err := internal.NewScheduleBlock("0 2 * * *", time.UTC, internal.MissedRunsSkip)
+	if err != nil {
+		t.Fatalf("NewScheduleBlock() error = %v", err)
+	}
+	trigger := internal.TriggerBlock{Name: "nightly", Schedule: schedule}
+	config := &internal.RootSwitchboardConfig{
+		Triggers:  []internal.TriggerBlock{trigger},
+		Workflows: []internal.WorkflowBlock{{Name: "report", Trigger: "nightly"}},
+	}
+	runContext, cancelRuns := context.WithCancel(context.Background())
+	defer cancelRuns()
+	tests := []struct {
+		name          string
+		opts          []SchedulerOption
+		wantCancelled bool
+	}{
+		{
+			name:          "cancels runs with the scheduler",
+			wantCancelled: true,
+		},
+		{
+			name:          "keeps runs with a run context when the scheduler is cancelled",
+			opts:          []SchedulerOption{WithRunContext(runContext)},
+			wantCancelled: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine := &testEngine{}
+			s := NewDefaultScheduler(config, engine, state.NewMemoryStateStore(), tt.opts...).(*DefaultScheduler)
+			ctx, cancel := context.WithCancel(context.Background())
+			s.startRuns(ctx, trigger, time.Date(2023, 3, 4, 2, 0, 0, 0, time.UTC))
+			s.runs.Wait()
+			cancel()
+			if len(engine.contexts) != 1 {
+				t.Fatalf("startRuns() runs = %v, want 1 run", engine.runs)
+			}
+			if cancelled := engine.contexts[0].Err() != nil; cancelled != tt.wantCancelled {
+				t.Errorf("startRuns() run cancelled with scheduler = %v, want %v", cancelled, tt.wantCancelled)
+			}
+		})
+	}
+}
+
+func timePointer(t time.Time) *time.Time {
+	return &t
+}
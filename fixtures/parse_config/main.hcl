@@ -0,0 +1,32 @@
+switchboard {
+  version = "~> 1.0"
+
+  required_provider "stripe" {
+    version = "1.0.0"
+    source  = "github.com/switchboard-org/provider-stripe"
+  }
+}
+
+provider "stripe" {
+  api_key = "sk_test"
+}
+
+trigger "nightly" {
+  schedule = "0 2 * * *"
+}
+
+workflow "charge" {
+  trigger = "nightly"
+
+  step "charge" {
+    provider = "stripe"
+    action   = "create_charge"
+    amount   = "10"
+  }
+
+  step "notify" {
+    provider = "stripe"
+    action   = "create_charge"
+    amount   = steps.charge.output.amount
+  }
+}
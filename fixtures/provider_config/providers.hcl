@@ -0,0 +1,16 @@
+provider "stripe" {
+  api_key = "sk_test"
+}
+
+provider "stripe_eu" {
+  required_provider = "stripe"
+  api_key           = "sk_eu"
+}
+
+provider "missing_setting" {
+  required_provider = "stripe"
+}
+
+provider "unknown" {
+  api_key = "sk_test"
+}
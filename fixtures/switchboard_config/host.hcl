@@ -0,0 +1,24 @@
+switchboard {
+  version = "~> 1.0"
+
+  host {
+    address = "https://switchboard.example.com/"
+    key     = "sbt_abc_123"
+  }
+}
+
+switchboard {
+  version = "~> 1.0"
+
+  host {
+    address = "https://switchboard.example.com"
+  }
+}
+
+switchboard {
+  version = "~> 1.0"
+
+  host {
+    address = "switchboard.example.com"
+  }
+}
@@ -0,0 +1,32 @@
+switchboard {
+  version = "~> 1.0"
+
+  retry {
+    max_attempts     = 5
+    initial_interval = "500ms"
+    max_interval     = "1m"
+    multiplier       = 3
+    jitter           = 0.2
+    retry_on         = ["rate limit", "^timeout"]
+  }
+
+  required_provider "test" {
+    version = "1.0.0"
+    source = "github.com/switchboard-org/provider-test"
+  }
+}
+
+workflow "partial" {
+  retry {
+    max_attempts = 2
+  }
+}
+
+workflow "invalid" {
+  retry {
+    max_attempts     = 0
+    initial_interval = "soon"
+    jitter           = 2
+    retry_on         = ["("]
+  }
+}
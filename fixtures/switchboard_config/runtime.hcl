@@ -0,0 +1,35 @@
+switchboard {
+  version = "~> 1.0"
+
+  required_provider "test" {
+    version = "1.0.0"
+    source = "github.com/switchboard-org/provider-test"
+
+    runtime {
+      env_allowlist = ["PATH", "STRIPE_API_KEY"]
+      max_memory_mb = 512
+      max_open_files = 1024
+      min_instances = 2
+      max_instances = 4
+    }
+  }
+
+  required_provider "invalid" {
+    version = "1.0.0"
+    source = "github.com/switchboard-org/provider-invalid"
+
+    runtime {
+      max_memory_mb = 0
+    }
+  }
+
+  required_provider "invalid_pool" {
+    version = "1.0.0"
+    source = "github.com/switchboard-org/provider-invalid-pool"
+
+    runtime {
+      min_instances = 3
+      max_instances = 2
+    }
+  }
+}
@@ -0,0 +1,39 @@
+trigger "nightly" {
+  schedule    = "0 2 * * *"
+  timezone    = "Europe/Berlin"
+  missed_runs = "catch_up"
+}
+
+trigger "hourly" {
+  schedule = "@hourly"
+}
+
+trigger "invalid_expression" {
+  schedule = "0 2 * *"
+}
+
+trigger "invalid_timezone" {
+  schedule = "0 2 * * *"
+  timezone = "Mars/Olympus_Mons"
+}
+
+trigger "invalid_missed_runs" {
+  schedule    = "0 2 * * *"
+  missed_runs = "sometimes"
+}
+
+trigger "schedule_and_provider" {
+  schedule = "0 2 * * *"
+  provider = "test"
+  function = "webhook"
+}
+
+trigger "webhook" {
+  provider = "test"
+  function = "webhook"
+}
+
+trigger "unknown_provider" {
+  provider = "other"
+  function = "webhook"
+}
@@ -0,0 +1,22 @@
+workflow "conditions" {
+  step "known" {
+    provider  = "test"
+    action    = "echo"
+    condition = 1 > 0
+    message   = "known"
+  }
+
+  step "runtime" {
+    provider  = "test"
+    action    = "echo"
+    condition = trigger.amount > 0 && steps.known.output.message != ""
+    message   = "runtime"
+  }
+
+  step "not_boolean" {
+    provider  = "test"
+    action    = "echo"
+    condition = "yes"
+    message   = "not_boolean"
+  }
+}
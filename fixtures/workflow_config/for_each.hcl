@@ -0,0 +1,31 @@
+workflow "for_each" {
+  step "items" {
+    provider     = "test"
+    action       = "echo"
+    for_each     = trigger.body.items
+    max_parallel = 5
+    message      = each.value.sku
+  }
+
+  step "not_iterable" {
+    provider = "test"
+    action   = "echo"
+    for_each = 10
+    message  = each.value
+  }
+
+  step "max_parallel_without_for_each" {
+    provider     = "test"
+    action       = "echo"
+    max_parallel = 5
+    message      = "max_parallel_without_for_each"
+  }
+
+  step "each_in_condition" {
+    provider  = "test"
+    action    = "echo"
+    for_each  = ["a", "b"]
+    condition = each.value == "a"
+    message   = each.value
+  }
+}
@@ -0,0 +1,87 @@
+test "charges the customer" {
+  workflow = "checkout"
+  payload  = { amount = 10 }
+
+  mock "stripe.create_charge" {
+    returns = { id = "ch_1" }
+  }
+
+  mock "stripe.send_receipt" {
+    returns = { id = "re_1" }
+  }
+
+  assert {
+    condition = steps.charge.output.id == "ch_1"
+    message   = "charge id is passed through"
+  }
+
+  assert {
+    condition = run.status == "succeeded"
+  }
+}
+
+test "reports failed assertions" {
+  workflow = "checkout"
+  payload  = { amount = 10 }
+
+  mock "stripe.create_charge" {
+    returns = { id = "ch_1" }
+  }
+
+  mock "stripe.send_receipt" {
+    returns = { id = "re_1" }
+  }
+
+  assert {
+    condition = steps.receipt.output.id == "re_2"
+    message   = "wrong receipt"
+  }
+}
+
+test "fails when the run fails without assertions" {
+  workflow = "checkout"
+  payload  = { amount = 10 }
+
+  mock "stripe.create_charge" {
+    error = "card declined"
+  }
+}
+
+test "asserts on run failures" {
+  workflow = "checkout"
+  payload  = { amount = 10 }
+
+  mock "stripe.create_charge" {
+    error = "card declined"
+  }
+
+  assert {
+    condition = run.status == "failed" && run.error != ""
+    message   = "run fails with the charge error"
+  }
+
+  assert {
+    condition = steps.receipt.output == null
+    message   = "no receipt is sent"
+  }
+}
+
+test "unknown workflow" {
+  workflow = "refund"
+}
+
+test "invalid mock" {
+  workflow = "checkout"
+
+  mock "create_charge" {
+    returns = {}
+  }
+}
+
+test "assert without condition" {
+  workflow = "checkout"
+
+  assert {
+    message = "condition is missing"
+  }
+}
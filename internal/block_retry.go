@@ -0,0 +1,65 @@
+package internal
+
+import (
+	"math"
+	"math/rand"
+	"regexp"
+	"time"
+)
+
+// RetryBlock controls how failed provider action calls are retried. It can be set in the switchboard
+// block, and overridden in workflow and step blocks. Any setting that is not overridden is inherited
+// from the parent block.
+type RetryBlock struct {
+	// MaxAttempts is the total number of calls made, including the first one. 1 disables retries
+	MaxAttempts int
+	// InitialInterval is the wait time before the first retry
+	InitialInterval time.Duration
+	// MaxInterval caps the wait time between retries
+	MaxInterval time.Duration
+	// Multiplier is applied to the wait time after every retry
+	Multiplier float64
+	// Jitter randomizes each wait time by up to this fraction (0 to 1) of the interval
+	Jitter float64
+	// RetryOn is a list of regular expressions matched against the error message. An empty list retries every error
+	RetryOn []*regexp.Regexp
+}
+
+// DefaultRetryBlock is the retry policy used when no retry block is set anywhere, which does not retry failed calls.
+func DefaultRetryBlock() RetryBlock {
+	return RetryBlock{
+		MaxAttempts:     1,
+		InitialInterval: time.Second,
+		MaxInterval:     30 * time.Second,
+		Multiplier:      2,
+		Jitter:          0,
+	}
+}
+
+// ShouldRetry returns true if another call should be made after the given attempt (starting at 1) failed with err
+func (r RetryBlock) ShouldRetry(attempt int, err error) bool {
+	if err == nil || attempt >= r.MaxAttempts {
+		return false
+	}
+	if len(r.RetryOn) == 0 {
+		return true
+	}
+	for _, pattern := range r.RetryOn {
+		if pattern.MatchString(err.Error()) {
+			return true
+		}
+	}
+	return false
+}
+
+// Backoff returns the wait time before the retry following the given attempt (starting at 1)
+func (r RetryBlock) Backoff(attempt int) time.Duration {
+	interval := float64(r.InitialInterval) * math.Pow(r.Multiplier, float64(attempt-1))
+	if r.MaxInterval > 0 && interval > float64(r.MaxInterval) {
+		interval = float64(r.MaxInterval)
+	}
+	if r.Jitter > 0 {
+		interval += interval * r.Jitter * (rand.Float64()*2 - 1)
+	}
+	return time.Duration(interval)
+}
@@ -19,6 +19,8 @@ type RootSwitchboardConfig struct {
 	Switchboard SwitchboardBlock
 	Providers   []ProviderBlock
 	Schemas     []SchemaBlock
+	Triggers    []TriggerBlock
+	Workflows   []WorkflowBlock
 }
 
 // EvalContext is the high level evaluation context object used for evaluating expressions throughout
@@ -44,3 +46,98 @@ func (conf *RootSwitchboardConfig) EvalContext() *hcl.EvalContext {
 	evalContext.Functions = generalContextFunctions()
 	return &evalContext
 }
+
+// Workflow returns the workflow with the given name, or nil if it does not exist
+func (conf *RootSwitchboardConfig) Workflow(name string) *WorkflowBlock {
+	for i := range conf.Workflows {
+		if conf.Workflows[i].Name == name {
+			return &conf.Workflows[i]
+		}
+	}
+	return nil
+}
+
+// Trigger returns the trigger with the given name, or nil if it does not exist
+func (conf *RootSwitchboardConfig) Trigger(name string) *TriggerBlock {
+	for i := range conf.Triggers {
+		if conf.Triggers[i].Name == name {
+			return &conf.Triggers[i]
+		}
+	}
+	return nil
+}
+
+// TriggeredWorkflows returns the names of the workflows started by the given trigger
+func (conf *RootSwitchboardConfig) TriggeredWorkflows(trigger string) []string {
+	var output []string
+	for _, workflow := range conf.Workflows {
+		if workflow.Trigger == trigger {
+			output = append(output, workflow.Name)
+		}
+	}
+	return output
+}
+
+// Provider returns the provider block with the given name, or nil if it does not exist
+func (conf *RootSwitchboardConfig) Provider(name string) *ProviderBlock {
+	for i := range conf.Providers {
+		if conf.Providers[i].BlockName == name {
+			return &conf.Providers[i]
+		}
+	}
+	return nil
+}
+
+// ProviderPluginName returns the name of the required provider plugin that a provider reference in a step
+// maps to. The reference can either be the name of a provider block, or of a required_provider without a
+// provider block.
+func (conf *RootSwitchboardConfig) ProviderPluginName(name string) (string, bool) {
+	if provider := conf.Provider(name); provider != nil {
+		if provider.ProviderName != "" {
+			return provider.ProviderName, true
+		}
+		return provider.BlockName, true
+	}
+	for _, requiredProvider := range conf.Switchboard.RequiredProviders {
+		if requiredProvider.Name == name {
+			return requiredProvider.Name, true
+		}
+	}
+	return "", false
+}
+
+// StepEvalContext extends the root evaluation context with the values that are only known while a workflow
+// runs, namely the trigger payload and the outputs of previous steps (keyed by step name).
+func StepEvalContext(ctx *hcl.EvalContext, trigger cty.Value, steps map[string]cty.Value) *hcl.EvalContext {
+	stepContext := ctx.NewChild()
+	stepContext.Variables = map[string]cty.Value{
+		"trigger": trigger,
+		"steps":   cty.ObjectVal(steps),
+	}
+	return stepContext
+}
+
+// ErrorEvalContext extends a step evaluation context with the error of a failed step, which is available
+// to fallback expressions and on_failure steps.
+func ErrorEvalContext(ctx *hcl.EvalContext, step string, message string) *hcl.EvalContext {
+	errorContext := ctx.NewChild()
+	errorContext.Variables = map[string]cty.Value{
+		"error": cty.ObjectVal(map[string]cty.Value{
+			"message": cty.StringVal(message),
+			"step":    cty.StringVal(step),
+		}),
+	}
+	return errorContext
+}
+
+// EachEvalContext extends a step evaluation context with the current element of a for_each step
+func EachEvalContext(ctx *hcl.EvalContext, key cty.Value, value cty.Value) *hcl.EvalContext {
+	eachContext := ctx.NewChild()
+	eachContext.Variables = map[string]cty.Value{
+		"each": cty.ObjectVal(map[string]cty.Value{
+			"key":   key,
+			"value": value,
+		}),
+	}
+	return eachContext
+}
@@ -4,8 +4,11 @@ package internal
 // including the required providers, log settings, retry settings, and more.
 type SwitchboardBlock struct {
 	Version string
-	//Host              HostBlock
+	// Host is nil if no host block was provided
+	Host              *HostBlock
 	RequiredProviders []RequiredProviderBlock
+	// Retry is nil if no retry block was provided, in which case DefaultRetryBlock is used
+	Retry *RetryBlock
 }
 
 // RequiredProviderBlock tells us where a provider should be pulled from, and which version it
@@ -14,10 +17,31 @@ type RequiredProviderBlock struct {
 	Name    string
 	Source  string
 	Version string
+	// Runtime is nil if no runtime block was provided, in which case the plugin process
+	// runs without any limits
+	Runtime *RuntimeBlock
 }
 
-//// HostBlock tells us where the workflow runner is hosted and the api key to trigger deployments
-//type HostBlock struct {
-//	Address string `hcl:"address"`
-//	Key     string `hcl:"key"`
-//}
+// RuntimeBlock contains process level settings applied when starting a provider plugin.
+type RuntimeBlock struct {
+	// EnvAllowlist is the list of environment variable names passed on to the plugin process.
+	// A nil list means the full environment is inherited, while an empty list passes nothing.
+	EnvAllowlist []string
+	// MaxMemoryMB limits the memory of the plugin process. 0 means unlimited
+	MaxMemoryMB int
+	// MaxOpenFiles limits the number of file descriptors of the plugin process. 0 means unlimited
+	MaxOpenFiles int
+	// MinInstances is the number of plugin processes kept running for the provider
+	MinInstances int
+	// MaxInstances is the number of plugin processes the provider can scale up to under load
+	MaxInstances int
+}
+
+// HostBlock tells us where the workflow runner is hosted and the api key to trigger deployments
+type HostBlock struct {
+	// Address is the base URL of the server, such as 'https://switchboard.example.com'
+	Address string
+	// Key is an API token of the server. It is empty if not set, in which case the token is taken
+	// from the environment
+	Key string
+}
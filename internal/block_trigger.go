@@ -0,0 +1,122 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"github.com/robfig/cron/v3"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/convert"
+	"time"
+)
+
+// TriggerBlock starts the workflows that reference it. Schedule triggers are handled by switchboard itself,
+// while other triggers are provided by a provider plugin.
+type TriggerBlock struct {
+	Name string
+	// Provider and Function are empty for schedule triggers
+	Provider string
+	Function string
+	// Schema is the schema the payload of a provider trigger must match. It is nil if not set
+	Schema *SchemaBlock
+	// Schedule is nil unless this is a schedule trigger
+	Schedule *ScheduleBlock
+}
+
+// ValidatePayload checks that a payload could have been sent by the trigger. The payload of schedule
+// triggers has the scheduled time, while provider trigger payloads must match the trigger schema if it has one.
+func (t *TriggerBlock) ValidatePayload(payload cty.Value) []error {
+	if payload.IsNull() || !payload.IsKnown() {
+		return []error{errors.New("payload must not be null")}
+	}
+	if t.Schedule != nil {
+		if !payload.Type().IsObjectType() || !payload.Type().HasAttribute("scheduled_at") {
+			return []error{errors.New("payload of a schedule trigger must have a 'scheduled_at' time")}
+		}
+		scheduledAt, err := convert.Convert(payload.GetAttr("scheduled_at"), cty.String)
+		if err != nil || scheduledAt.IsNull() || !scheduledAt.IsKnown() {
+			return []error{errors.New("'scheduled_at' must be a time string")}
+		}
+		if _, err = time.Parse(time.RFC3339, scheduledAt.AsString()); err != nil {
+			return []error{fmt.Errorf("invalid 'scheduled_at' time: %w", err)}
+		}
+		return nil
+	}
+	if t.Schema == nil {
+		return nil
+	}
+	if t.Schema.IsList == nil || !*t.Schema.IsList {
+		return ValidateValueAgainstSpec(payload, t.Schema.Format, "")
+	}
+	if !payload.CanIterateElements() || payload.Type().IsObjectType() || payload.Type().IsMapType() {
+		return []error{fmt.Errorf("payload must be a list of '%s' values", t.Schema.Name)}
+	}
+	var errs []error
+	for it := payload.ElementIterator(); it.Next(); {
+		key, element := it.Element()
+		index, _ := key.AsBigFloat().Int64()
+		errs = append(errs, ValidateValueAgainstSpec(element, t.Schema.Format, fmt.Sprintf("[%d]", index))...)
+	}
+	return errs
+}
+
+// MissedRunPolicy decides what happens to the scheduled runs that were missed while the server was down
+type MissedRunPolicy string
+
+const (
+	// MissedRunsSkip drops every missed run, and continues with the next scheduled time
+	MissedRunsSkip MissedRunPolicy = "skip"
+	// MissedRunsCatchUp starts the most recent missed runs when the server starts, one scheduled time after another
+	MissedRunsCatchUp MissedRunPolicy = "catch_up"
+)
+
+// ScheduleBlock runs workflows at the times of a cron expression
+type ScheduleBlock struct {
+	// Expression is the standard 5 field cron expression, or a descriptor such as '@daily'
+	Expression string
+	// Location is the timezone the expression is evaluated in
+	Location   *time.Location
+	MissedRuns MissedRunPolicy
+	schedule   cron.Schedule
+}
+
+// NewScheduleBlock parses a cron expression, which is evaluated in the given location
+func NewScheduleBlock(expression string, location *time.Location, missedRuns MissedRunPolicy) (*ScheduleBlock, error) {
+	schedule, err := cron.ParseStandard(expression)
+	if err != nil {
+		return nil, err
+	}
+	return &ScheduleBlock{
+		Expression: expression,
+		Location:   location,
+		MissedRuns: missedRuns,
+		schedule:   schedule,
+	}, nil
+}
+
+// Next returns the first scheduled time after t, in the location of the schedule
+func (s *ScheduleBlock) Next(t time.Time) time.Time {
+	return s.schedule.Next(t.In(s.Location))
+}
+
+// Between returns the last limit scheduled times after from, up to and including to, and the number of
+// scheduled times in between, including the ones that were not returned. Only the last limit times are kept
+// while the schedule is enumerated, so that a long gap does not hold every scheduled time in memory. The limit
+// must be positive.
+func (s *ScheduleBlock) Between(from time.Time, to time.Time, limit int) ([]time.Time, int) {
+	output := make([]time.Time, 0, limit)
+	count := 0
+	for next := s.Next(from); !next.IsZero() && !next.After(to); next = s.Next(next) {
+		// output is used as a ring once it is full, overwriting the oldest time
+		if len(output) < limit {
+			output = append(output, next)
+		} else {
+			output[count%limit] = next
+		}
+		count++
+	}
+	if count > limit {
+		oldest := count % limit
+		output = append(output[oldest:], output[:oldest]...)
+	}
+	return output, count
+}
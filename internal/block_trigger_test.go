@@ -0,0 +1,125 @@
+package internal
+
+import (
+	"github.com/zclconf/go-cty/cty"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestTriggerBlock_ValidatePayload(t *testing.T) {
+	schedule, _ := NewScheduleBlock("0 2 * * *", time.UTC, MissedRunsSkip)
+	orderSpec := MapSpec{
+		"id": &PrimitiveSpec{required: true, fieldType: cty.String},
+	}
+	isList := true
+	tests := []struct {
+		name      string
+		trigger   TriggerBlock
+		payload   cty.Value
+		wantCount int
+	}{
+		{
+			name:    "accepts a scheduled time",
+			trigger: TriggerBlock{Name: "nightly", Schedule: schedule},
+			payload: cty.ObjectVal(map[string]cty.Value{"scheduled_at": cty.StringVal("2023-03-01T02:00:00Z")}),
+		},
+		{
+			name:      "rejects a schedule payload without a scheduled time",
+			trigger:   TriggerBlock{Name: "nightly", Schedule: schedule},
+			payload:   cty.EmptyObjectVal,
+			wantCount: 1,
+		},
+		{
+			name:      "rejects an invalid scheduled time",
+			trigger:   TriggerBlock{Name: "nightly", Schedule: schedule},
+			payload:   cty.ObjectVal(map[string]cty.Value{"scheduled_at": cty.StringVal("yesterday")}),
+			wantCount: 1,
+		},
+		{
+			name:    "accepts any payload without a schema",
+			trigger: TriggerBlock{Name: "webhook", Provider: "test", Function: "webhook"},
+			payload: cty.ObjectVal(map[string]cty.Value{"anything": cty.True}),
+		},
+		{
+			name:    "accepts a payload matching the schema",
+			trigger: TriggerBlock{Name: "order", Provider: "test", Function: "webhook", Schema: &SchemaBlock{Name: "order", Format: &orderSpec}},
+			payload: cty.ObjectVal(map[string]cty.Value{"id": cty.StringVal("1")}),
+		},
+		{
+			name:      "rejects a payload missing a required value",
+			trigger:   TriggerBlock{Name: "order", Provider: "test", Function: "webhook", Schema: &SchemaBlock{Name: "order", Format: &orderSpec}},
+			payload:   cty.EmptyObjectVal,
+			wantCount: 1,
+		},
+		{
+			name:      "validates every element of a list schema",
+			trigger:   TriggerBlock{Name: "orders", Provider: "test", Function: "webhook", Schema: &SchemaBlock{Name: "order", IsList: &isList, Format: &orderSpec}},
+			payload:   cty.TupleVal([]cty.Value{cty.ObjectVal(map[string]cty.Value{"id": cty.StringVal("1")}), cty.EmptyObjectVal, cty.EmptyObjectVal}),
+			wantCount: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.trigger.ValidatePayload(tt.payload); len(got) != tt.wantCount {
+				t.Errorf("ValidatePayload() = %v, want %d errors", got, tt.wantCount)
+			}
+		})
+	}
+}
+
+func TestScheduleBlock_Between(t *testing.T) {
+	schedule, _ := NewScheduleBlock("0 * * * *", time.UTC, MissedRunsCatchUp)
+	from := time.Date(2023, 3, 1, 0, 0, 0, 0, time.UTC)
+	hours := func(hours ...int) []time.Time {
+		var output []time.Time
+		for _, hour := range hours {
+			output = append(output, from.Add(time.Duration(hour)*time.Hour))
+		}
+		return output
+	}
+	tests := []struct {
+		name      string
+		to        time.Time
+		limit     int
+		want      []time.Time
+		wantCount int
+	}{
+		{
+			name:      "returns nothing before the next scheduled time",
+			to:        from.Add(time.Minute),
+			limit:     3,
+			want:      []time.Time{},
+			wantCount: 0,
+		},
+		{
+			name:      "returns every scheduled time within the limit",
+			to:        from.Add(2 * time.Hour),
+			limit:     3,
+			want:      hours(1, 2),
+			wantCount: 2,
+		},
+		{
+			name:      "returns the last scheduled times in order above the limit",
+			to:        from.Add(7*time.Hour + time.Minute),
+			limit:     3,
+			want:      hours(5, 6, 7),
+			wantCount: 7,
+		},
+		{
+			name:      "counts the scheduled times of a long gap",
+			to:        from.AddDate(1, 0, 0),
+			limit:     1,
+			want:      hours(366 * 24),
+			wantCount: 366 * 24,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, count := schedule.Between(from, tt.to, tt.limit)
+			if !reflect.DeepEqual(got, tt.want) || count != tt.wantCount {
+				t.Errorf("Between() = %v, %d, want %v, %d", got, count, tt.want, tt.wantCount)
+			}
+		})
+	}
+}
@@ -0,0 +1,79 @@
+package internal
+
+import (
+	"github.com/hashicorp/hcl/v2"
+	"github.com/switchboard-org/plugin-sdk/sbsdk"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// WorkflowBlock is a list of steps that are processed every time the workflow runs.
+type WorkflowBlock struct {
+	Name string
+	// Trigger is the name of the trigger that starts the workflow. It is empty if the workflow is only
+	// started manually
+	Trigger string
+	// Retry is nil if the workflow does not override the global retry settings
+	Retry *RetryBlock
+	// Steps are sorted so that every step comes after the steps it depends on
+	Steps []StepBlock
+	// OnFailure steps run after a step failure stops the workflow. They can reference the
+	// failure through error.message and error.step
+	OnFailure []StepBlock
+}
+
+// Step returns the step with the given name, or nil if the workflow has no such step
+func (w *WorkflowBlock) Step(name string) *StepBlock {
+	for i := range w.Steps {
+		if w.Steps[i].Name == name {
+			return &w.Steps[i]
+		}
+	}
+	return nil
+}
+
+// OnErrorAction decides what happens to a workflow run when a step fails after all retries
+type OnErrorAction string
+
+const (
+	// OnErrorFail stops the run and marks it as failed
+	OnErrorFail OnErrorAction = "fail"
+	// OnErrorContinue runs the remaining steps, using the fallback value (or null) as the step output
+	OnErrorContinue OnErrorAction = "continue"
+	// OnErrorGoto continues the run at StepBlock.OnErrorStep, skipping any steps in between
+	OnErrorGoto OnErrorAction = "goto"
+)
+
+// StepBlock calls a single provider action as part of a workflow.
+type StepBlock struct {
+	Name string
+	// Provider is the name of the provider block (or required_provider when there is no provider block)
+	// that the action is called on
+	Provider string
+	Action   string
+	// Config contains the action specific configuration. It can reference the trigger payload and the
+	// output of other steps, so it is only evaluated when the step runs.
+	Config hcl.Body
+	// ConfigSchema is the action configuration schema provided by the plugin, used to decode Config
+	ConfigSchema sbsdk.ObjectSchema
+	// OutputType is the type of the value returned by the action
+	OutputType cty.Type
+	// Condition decides whether the step runs. It is nil if not set, in which case the step always runs
+	Condition hcl.Expression
+	// ForEach calls the action once for every element of its value, with each.key and each.value in scope.
+	// It is nil if not set. The condition of the step is evaluated once, before ForEach
+	ForEach hcl.Expression
+	// MaxParallel limits the number of ForEach iterations running at the same time. 0 means unlimited
+	MaxParallel int
+	// Idempotent steps can safely be called again with the same input. When a run is resumed after a restart,
+	// a step that was interrupted mid-call is only called again if it is idempotent, otherwise it fails
+	Idempotent bool
+	// DependsOn lists the steps whose output is referenced in Config, Condition, ForEach or Fallback
+	DependsOn []string
+	// Retry is the effective retry policy of the step, after merging the global, workflow and step settings
+	Retry   RetryBlock
+	OnError OnErrorAction
+	// OnErrorStep is the step the run continues at when OnError is OnErrorGoto
+	OnErrorStep string
+	// Fallback is used as the output of the step when it fails and the run continues. It is nil if not set
+	Fallback hcl.Expression
+}
@@ -0,0 +1,329 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"github.com/hashicorp/go-plugin"
+	"github.com/switchboard-org/plugin-sdk/sbsdk"
+	"github.com/switchboard-org/switchboard/internal/sbproto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// TriggerProvider is implemented by providers that expose triggers. The net/rpc sbsdk.Provider interface
+// does not include trigger methods yet, so this is currently only available to providers served over gRPC.
+type TriggerProvider interface {
+	TriggerNames() ([]string, error)
+	TriggerConfigurationSchema(name string) (sbsdk.ObjectSchema, error)
+	TriggerOutputType(name string) (sbsdk.Type, error)
+}
+
+// providerPlugin serves and dispenses a sbsdk.Provider over both of the go-plugin protocols. The net/rpc
+// implementation comes from the sdk, while the gRPC implementation uses the service defined in
+// sbproto/provider.proto, which allows providers to be written in languages other than go.
+type providerPlugin struct {
+	sbsdk.ProviderPlugin
+}
+
+func (p *providerPlugin) GRPCServer(_ *plugin.GRPCBroker, s *grpc.Server) error {
+	sbproto.RegisterProviderServer(s, &ProviderGRPCServer{Impl: p.Impl})
+	return nil
+}
+
+func (p *providerPlugin) GRPCClient(_ context.Context, _ *plugin.GRPCBroker, c *grpc.ClientConn) (interface{}, error) {
+	return &ProviderGRPCClient{client: sbproto.NewProviderClient(c)}, nil
+}
+
+// ProviderGRPCClient is the host side implementation of sbsdk.Provider for plugins that negotiated
+// the gRPC protocol.
+type ProviderGRPCClient struct {
+	client sbproto.ProviderClient
+}
+
+func (p *ProviderGRPCClient) Init(config []byte) error {
+	_, err := p.client.Init(context.Background(), &sbproto.Init_Request{Config: config})
+	return err
+}
+
+func (p *ProviderGRPCClient) InitSchema() (sbsdk.ObjectSchema, error) {
+	resp, err := p.client.InitSchema(context.Background(), &sbproto.InitSchema_Request{})
+	if err != nil {
+		return nil, err
+	}
+	return objectSchemaFromProto(resp.Schema)
+}
+
+func (p *ProviderGRPCClient) ActionNames() ([]string, error) {
+	resp, err := p.client.ActionNames(context.Background(), &sbproto.ActionNames_Request{})
+	if err != nil {
+		return []string{}, err
+	}
+	return resp.Names, nil
+}
+
+func (p *ProviderGRPCClient) ActionEvaluate(name string, config []byte, input []byte) ([]byte, error) {
+	resp, err := p.client.ActionEvaluate(context.Background(), &sbproto.ActionEvaluate_Request{
+		Name:   name,
+		Config: config,
+		Input:  input,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Output, nil
+}
+
+func (p *ProviderGRPCClient) ActionConfigurationSchema(name string) (sbsdk.ObjectSchema, error) {
+	resp, err := p.client.ActionConfigurationSchema(context.Background(), &sbproto.ConfigurationSchema_Request{Name: name})
+	if err != nil {
+		return sbsdk.ObjectSchema{}, err
+	}
+	return objectSchemaFromProto(resp.Schema)
+}
+
+func (p *ProviderGRPCClient) ActionOutputType(name string) (sbsdk.Type, error) {
+	resp, err := p.client.ActionOutputType(context.Background(), &sbproto.OutputType_Request{Name: name})
+	if err != nil {
+		return sbsdk.Invalid, err
+	}
+	return typeFromProto(resp.Type), nil
+}
+
+func (p *ProviderGRPCClient) TriggerNames() ([]string, error) {
+	resp, err := p.client.TriggerNames(context.Background(), &sbproto.TriggerNames_Request{})
+	if err != nil {
+		return []string{}, err
+	}
+	return resp.Names, nil
+}
+
+func (p *ProviderGRPCClient) TriggerConfigurationSchema(name string) (sbsdk.ObjectSchema, error) {
+	resp, err := p.client.TriggerConfigurationSchema(context.Background(), &sbproto.ConfigurationSchema_Request{Name: name})
+	if err != nil {
+		return sbsdk.ObjectSchema{}, err
+	}
+	return objectSchemaFromProto(resp.Schema)
+}
+
+func (p *ProviderGRPCClient) TriggerOutputType(name string) (sbsdk.Type, error) {
+	resp, err := p.client.TriggerOutputType(context.Background(), &sbproto.OutputType_Request{Name: name})
+	if err != nil {
+		return sbsdk.Invalid, err
+	}
+	return typeFromProto(resp.Type), nil
+}
+
+// ProviderGRPCServer is the plugin side of the gRPC protocol, wrapping a go sbsdk.Provider implementation.
+// Trigger methods are served only if the implementation also satisfies TriggerProvider.
+type ProviderGRPCServer struct {
+	sbproto.UnimplementedProviderServer
+	Impl sbsdk.Provider
+}
+
+func (p *ProviderGRPCServer) Init(_ context.Context, req *sbproto.Init_Request) (*sbproto.Init_Response, error) {
+	return &sbproto.Init_Response{}, p.Impl.Init(req.Config)
+}
+
+func (p *ProviderGRPCServer) InitSchema(_ context.Context, _ *sbproto.InitSchema_Request) (*sbproto.InitSchema_Response, error) {
+	result, err := p.Impl.InitSchema()
+	if err != nil {
+		return nil, err
+	}
+	schema, err := objectSchemaToProto(result)
+	if err != nil {
+		return nil, err
+	}
+	return &sbproto.InitSchema_Response{Schema: schema}, nil
+}
+
+func (p *ProviderGRPCServer) ActionNames(_ context.Context, _ *sbproto.ActionNames_Request) (*sbproto.ActionNames_Response, error) {
+	result, err := p.Impl.ActionNames()
+	if err != nil {
+		return nil, err
+	}
+	return &sbproto.ActionNames_Response{Names: result}, nil
+}
+
+func (p *ProviderGRPCServer) ActionConfigurationSchema(_ context.Context, req *sbproto.ConfigurationSchema_Request) (*sbproto.ConfigurationSchema_Response, error) {
+	result, err := p.Impl.ActionConfigurationSchema(req.Name)
+	if err != nil {
+		return nil, err
+	}
+	schema, err := objectSchemaToProto(result)
+	if err != nil {
+		return nil, err
+	}
+	return &sbproto.ConfigurationSchema_Response{Schema: schema}, nil
+}
+
+func (p *ProviderGRPCServer) ActionOutputType(_ context.Context, req *sbproto.OutputType_Request) (*sbproto.OutputType_Response, error) {
+	result, err := p.Impl.ActionOutputType(req.Name)
+	if err != nil {
+		return nil, err
+	}
+	return &sbproto.OutputType_Response{Type: typeToProto(result)}, nil
+}
+
+func (p *ProviderGRPCServer) ActionEvaluate(_ context.Context, req *sbproto.ActionEvaluate_Request) (*sbproto.ActionEvaluate_Response, error) {
+	result, err := p.Impl.ActionEvaluate(req.Name, req.Config, req.Input)
+	if err != nil {
+		return nil, err
+	}
+	return &sbproto.ActionEvaluate_Response{Output: result}, nil
+}
+
+func (p *ProviderGRPCServer) TriggerNames(_ context.Context, _ *sbproto.TriggerNames_Request) (*sbproto.TriggerNames_Response, error) {
+	triggerProvider, ok := p.Impl.(TriggerProvider)
+	if !ok {
+		return nil, status.Error(codes.Unimplemented, "provider does not support triggers")
+	}
+	result, err := triggerProvider.TriggerNames()
+	if err != nil {
+		return nil, err
+	}
+	return &sbproto.TriggerNames_Response{Names: result}, nil
+}
+
+func (p *ProviderGRPCServer) TriggerConfigurationSchema(_ context.Context, req *sbproto.ConfigurationSchema_Request) (*sbproto.ConfigurationSchema_Response, error) {
+	triggerProvider, ok := p.Impl.(TriggerProvider)
+	if !ok {
+		return nil, status.Error(codes.Unimplemented, "provider does not support triggers")
+	}
+	result, err := triggerProvider.TriggerConfigurationSchema(req.Name)
+	if err != nil {
+		return nil, err
+	}
+	schema, err := objectSchemaToProto(result)
+	if err != nil {
+		return nil, err
+	}
+	return &sbproto.ConfigurationSchema_Response{Schema: schema}, nil
+}
+
+func (p *ProviderGRPCServer) TriggerOutputType(_ context.Context, req *sbproto.OutputType_Request) (*sbproto.OutputType_Response, error) {
+	triggerProvider, ok := p.Impl.(TriggerProvider)
+	if !ok {
+		return nil, status.Error(codes.Unimplemented, "provider does not support triggers")
+	}
+	result, err := triggerProvider.TriggerOutputType(req.Name)
+	if err != nil {
+		return nil, err
+	}
+	return &sbproto.OutputType_Response{Type: typeToProto(result)}, nil
+}
+
+func typeToProto(t sbsdk.Type) *sbproto.Type {
+	output := &sbproto.Type{Name: t.Name}
+	if t.NestedValues != nil {
+		output.NestedValues = make(map[string]*sbproto.Type)
+		for k, v := range *t.NestedValues {
+			output.NestedValues[k] = typeToProto(v)
+		}
+	}
+	if t.InternalType != nil {
+		output.InternalType = typeToProto(*t.InternalType)
+	}
+	return output
+}
+
+func typeFromProto(t *sbproto.Type) sbsdk.Type {
+	if t == nil {
+		return sbsdk.Invalid
+	}
+	output := sbsdk.Type{Name: t.Name}
+	if t.NestedValues != nil {
+		nested := make(map[string]sbsdk.Type)
+		for k, v := range t.NestedValues {
+			nested[k] = typeFromProto(v)
+		}
+		output.NestedValues = &nested
+	}
+	if t.InternalType != nil {
+		output.InternalType = Ptr(typeFromProto(t.InternalType))
+	}
+	return output
+}
+
+func objectSchemaToProto(schema sbsdk.ObjectSchema) (*sbproto.Schema_Object, error) {
+	output := &sbproto.Schema_Object{Attributes: make(map[string]*sbproto.Schema)}
+	for k, v := range schema {
+		converted, err := schemaToProto(v)
+		if err != nil {
+			return nil, err
+		}
+		output.Attributes[k] = converted
+	}
+	return output, nil
+}
+
+func schemaToProto(schema sbsdk.Schema) (*sbproto.Schema, error) {
+	switch s := schema.(type) {
+	case *sbsdk.ObjectSchema:
+		object, err := objectSchemaToProto(*s)
+		if err != nil {
+			return nil, err
+		}
+		return &sbproto.Schema{Kind: &sbproto.Schema_Object_{Object: object}}, nil
+	case *sbsdk.AttrSchema:
+		return &sbproto.Schema{Kind: &sbproto.Schema_Attribute_{Attribute: &sbproto.Schema_Attribute{
+			Name:     s.Name,
+			Required: s.Required,
+			Type:     typeToProto(s.Type),
+		}}}, nil
+	case *sbsdk.BlockSchema:
+		nested, err := schemaToProto(s.Nested)
+		if err != nil {
+			return nil, err
+		}
+		return &sbproto.Schema{Kind: &sbproto.Schema_Block_{Block: &sbproto.Schema_Block{
+			Name:     s.Name,
+			Required: s.Required,
+			Nested:   nested,
+		}}}, nil
+	}
+	return nil, fmt.Errorf("unsupported schema type %T", schema)
+}
+
+func objectSchemaFromProto(schema *sbproto.Schema_Object) (sbsdk.ObjectSchema, error) {
+	output := sbsdk.ObjectSchema{}
+	if schema == nil {
+		return output, nil
+	}
+	for k, v := range schema.Attributes {
+		converted, err := schemaFromProto(v)
+		if err != nil {
+			return nil, err
+		}
+		output[k] = converted
+	}
+	return output, nil
+}
+
+func schemaFromProto(schema *sbproto.Schema) (sbsdk.Schema, error) {
+	switch kind := schema.GetKind().(type) {
+	case *sbproto.Schema_Object_:
+		object, err := objectSchemaFromProto(kind.Object)
+		if err != nil {
+			return nil, err
+		}
+		return &object, nil
+	case *sbproto.Schema_Attribute_:
+		return &sbsdk.AttrSchema{
+			Name:     kind.Attribute.Name,
+			Required: kind.Attribute.Required,
+			Type:     typeFromProto(kind.Attribute.Type),
+		}, nil
+	case *sbproto.Schema_Block_:
+		nested, err := schemaFromProto(kind.Block.Nested)
+		if err != nil {
+			return nil, err
+		}
+		return &sbsdk.BlockSchema{
+			Name:     kind.Block.Name,
+			Required: kind.Block.Required,
+			Nested:   nested,
+		}, nil
+	}
+	return nil, fmt.Errorf("unsupported schema kind %T", schema.GetKind())
+}
@@ -0,0 +1,128 @@
+package internal
+
+import (
+	"errors"
+	"github.com/hashicorp/go-plugin"
+	"github.com/switchboard-org/plugin-sdk/sbsdk"
+	"reflect"
+	"testing"
+)
+
+type testGRPCProvider struct{}
+
+func (p *testGRPCProvider) Init(_ []byte) error {
+	return nil
+}
+
+func (p *testGRPCProvider) InitSchema() (sbsdk.ObjectSchema, error) {
+	return sbsdk.ObjectSchema{
+		"api_key": &sbsdk.AttrSchema{Name: "api_key", Required: true, Type: sbsdk.String},
+		"retry": &sbsdk.BlockSchema{Name: "retry", Nested: &sbsdk.ObjectSchema{
+			"count": &sbsdk.AttrSchema{Name: "count", Type: sbsdk.Number},
+		}},
+	}, nil
+}
+
+func (p *testGRPCProvider) ActionNames() ([]string, error) {
+	return []string{"create_charge"}, nil
+}
+
+func (p *testGRPCProvider) ActionEvaluate(name string, _ []byte, input []byte) ([]byte, error) {
+	if name != "create_charge" {
+		return nil, errors.New("unknown action")
+	}
+	return input, nil
+}
+
+func (p *testGRPCProvider) ActionConfigurationSchema(_ string) (sbsdk.ObjectSchema, error) {
+	return sbsdk.ObjectSchema{
+		"amount": &sbsdk.AttrSchema{Name: "amount", Required: true, Type: sbsdk.Number},
+	}, nil
+}
+
+func (p *testGRPCProvider) ActionOutputType(_ string) (sbsdk.Type, error) {
+	return sbsdk.Object(map[string]sbsdk.Type{
+		"id":   sbsdk.String,
+		"tags": sbsdk.List(sbsdk.String),
+	}), nil
+}
+
+func testGRPCProviderClient(t *testing.T) sbsdk.Provider {
+	client, _ := plugin.TestPluginGRPCConn(t, map[string]plugin.Plugin{
+		"provider": &providerPlugin{sbsdk.ProviderPlugin{Impl: &testGRPCProvider{}}},
+	})
+	t.Cleanup(func() { client.Close() })
+	raw, err := client.Dispense("provider")
+	if err != nil {
+		t.Fatalf("Dispense() error = %v", err)
+	}
+	return raw.(sbsdk.Provider)
+}
+
+func TestProviderGRPCClient_InitSchema(t *testing.T) {
+	provider := testGRPCProviderClient(t)
+	want, _ := (&testGRPCProvider{}).InitSchema()
+	got, err := provider.InitSchema()
+	if err != nil {
+		t.Fatalf("InitSchema() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("InitSchema() got = %v, want %v", got, want)
+	}
+}
+
+func TestProviderGRPCClient_ActionOutputType(t *testing.T) {
+	provider := testGRPCProviderClient(t)
+	want, _ := (&testGRPCProvider{}).ActionOutputType("create_charge")
+	got, err := provider.ActionOutputType("create_charge")
+	if err != nil {
+		t.Fatalf("ActionOutputType() error = %v", err)
+	}
+	if !got.ToCty().Equals(want.ToCty()) {
+		t.Errorf("ActionOutputType() got = %v, want %v", got.ToCty().FriendlyName(), want.ToCty().FriendlyName())
+	}
+}
+
+func TestProviderGRPCClient_ActionEvaluate(t *testing.T) {
+	provider := testGRPCProviderClient(t)
+	tests := []struct {
+		name    string
+		action  string
+		input   []byte
+		want    []byte
+		wantErr bool
+	}{
+		{
+			name:   "returns output of the action",
+			action: "create_charge",
+			input:  []byte(`{"amount":10}`),
+			want:   []byte(`{"amount":10}`),
+		},
+		{
+			name:    "returns error of the action",
+			action:  "missing",
+			input:   []byte(`{}`),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := provider.ActionEvaluate(tt.action, nil, tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ActionEvaluate() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ActionEvaluate() got = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProviderGRPCClient_TriggerNames(t *testing.T) {
+	provider := testGRPCProviderClient(t)
+	_, err := provider.(TriggerProvider).TriggerNames()
+	if err == nil {
+		t.Errorf("TriggerNames() expected error for provider without trigger support")
+	}
+}
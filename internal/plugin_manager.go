@@ -15,6 +15,9 @@ type PluginManager interface {
 	KillPlugin(string) error
 	KillAllPlugins()
 	LoadedPlugins() []string
+	// Restarts returns the number of plugin processes of every loaded plugin that exited unexpectedly and were
+	// replaced, keyed by plugin name
+	Restarts() map[string]int
 }
 
 type PluginConfig struct {
@@ -22,75 +25,184 @@ type PluginConfig struct {
 	Source  string
 	Version string
 	Client  *plugin.Client
+	// ProtocolVersion is the plugin protocol version negotiated with the provider binary
+	ProtocolVersion int
+	// limits is nil for reattached plugins, as their process is not managed by switchboard
+	limits *pluginLimits
 }
 
+// failure adds the reason of a plugin failure to an error returned by the plugin, such as
+// the plugin process exceeding one of its runtime limits.
+func (plug *PluginConfig) failure(err error) error {
+	if err == nil {
+		return nil
+	}
+	reason := plug.limits.failureReason(plug.exited(), err)
+	if reason == "" {
+		return err
+	}
+	return fmt.Errorf("provider '%s' failed, %s: %w", plug.Name, reason, err)
+}
+
+// exited returns true if the plugin process is no longer running
+func (plug *PluginConfig) exited() bool {
+	return plug.Client != nil && plug.Client.Exited()
+}
+
+// kill stops the plugin process and cleans up any resources used to limit it
+func (plug *PluginConfig) kill() {
+	if plug.Client != nil {
+		plug.Client.Kill()
+	}
+	plug.limits.release()
+}
+
+// DefaultPluginManager keeps a pool of plugin processes for every loaded provider. The size of
+// each pool is set with the min_instances and max_instances settings of the provider runtime block.
 type DefaultPluginManager struct {
-	plugins       []PluginConfig
-	providerCache map[string]sbsdk.Provider
+	pools []*pluginPool
 }
 
 func NewDefaultPluginManager() PluginManager {
-	return &DefaultPluginManager{
-		providerCache: make(map[string]sbsdk.Provider),
-	}
+	return &DefaultPluginManager{}
 }
 
 func (pm *DefaultPluginManager) LoadPlugin(provider RequiredProviderBlock) error {
-	for _, plug := range pm.plugins {
-		if plug.Name == provider.Name {
+	for _, pool := range pm.pools {
+		if pool.provider.Name == provider.Name {
 			return errors.New("plugin is already loaded")
 		}
 	}
-	client := plugin.NewClient(&plugin.ClientConfig{
-		HandshakeConfig: sbsdk.HandshakeConfig,
-		Plugins:         pluginMap,
-		Cmd:             exec.Command(fmt.Sprintf("./.switchboard/packages/%s/%s/switchboard_plugin", provider.Source, provider.Version)),
-	})
-	pm.plugins = append(pm.plugins, PluginConfig{
-		Name:    provider.Name,
-		Source:  provider.Source,
-		Version: provider.Version,
-		Client:  client,
+	minInstances, maxInstances := 1, 1
+	if provider.Runtime != nil && !IsReattachedProvider(provider.Source) {
+		minInstances, maxInstances = provider.Runtime.MinInstances, provider.Runtime.MaxInstances
+	}
+	pool := newPluginPool(provider, minInstances, maxInstances, func() (*PluginConfig, sbsdk.Provider, error) {
+		return startPlugin(provider)
 	})
-
+	if err := pool.start(); err != nil {
+		return err
+	}
+	pm.pools = append(pm.pools, pool)
 	return nil
 }
 
-func (pm *DefaultPluginManager) PluginClient(name string) (*plugin.Client, error) {
-	for _, plug := range pm.plugins {
-		if plug.Name == name {
-			return plug.Client, nil
+// startPlugin launches (or reattaches to) a single plugin process for the provider and dispenses
+// the provider implementation from it.
+func startPlugin(provider RequiredProviderBlock) (*PluginConfig, sbsdk.Provider, error) {
+	clientConfig, err := pluginClientConfig(provider)
+	if err != nil {
+		return nil, nil, err
+	}
+	client := plugin.NewClient(clientConfig)
+	// starting the client performs the handshake, so version mismatches are reported when loading
+	rpcClient, err := client.Client()
+	if err != nil {
+		client.Kill()
+		return nil, nil, newPluginVersionError(provider.Name, err)
+	}
+	var limits *pluginLimits
+	if provider.Runtime != nil && clientConfig.Reattach == nil {
+		limits, err = applyRuntimeLimits(provider.Name, client.ReattachConfig().Pid, *provider.Runtime)
+		if err != nil {
+			client.Kill()
+			limits.release()
+			return nil, nil, err
 		}
 	}
-	return nil, errors.New("plugin is not available")
+	plug := &PluginConfig{
+		Name:            provider.Name,
+		Source:          provider.Source,
+		Version:         provider.Version,
+		Client:          client,
+		ProtocolVersion: client.NegotiatedVersion(),
+		limits:          limits,
+	}
+	raw, err := rpcClient.Dispense("provider")
+	if err != nil {
+		err = plug.failure(err)
+		plug.kill()
+		return nil, nil, err
+	}
+	return plug, raw.(sbsdk.Provider), nil
 }
 
-func (pm *DefaultPluginManager) ProviderInstance(name string) (sbsdk.Provider, error) {
-	if existingProvider, ok := pm.providerCache[name]; ok {
-		return existingProvider, nil
-	}
-	client, err := pm.PluginClient(name)
+// pluginClientConfig builds the go-plugin client config for a provider. Providers listed in
+// ReattachProvidersEnv are connected to directly, instead of launching the downloaded binary.
+func pluginClientConfig(provider RequiredProviderBlock) (*plugin.ClientConfig, error) {
+	reattachConfigs, err := ReattachConfigs()
 	if err != nil {
 		return nil, err
 	}
-	rpcClient, err := client.Client()
+	if reattach, ok := reattachConfigs[provider.Source]; ok {
+		plugins, ok := versionedPluginMap[reattach.ProtocolVersion]
+		if !ok {
+			return nil, &PluginVersionError{
+				Provider:          provider.Name,
+				ProtocolVersion:   reattach.ProtocolVersion,
+				SupportedVersions: SupportedProtocolVersions(),
+			}
+		}
+		return &plugin.ClientConfig{
+			HandshakeConfig:  sbsdk.HandshakeConfig,
+			Plugins:          plugins,
+			Reattach:         reattach,
+			AllowedProtocols: []plugin.Protocol{plugin.ProtocolNetRPC, plugin.ProtocolGRPC},
+		}, nil
+	}
+	cmd := exec.Command(fmt.Sprintf("./.switchboard/packages/%s/%s/switchboard_plugin", provider.Source, provider.Version))
+	skipHostEnv := false
+	if provider.Runtime != nil && provider.Runtime.EnvAllowlist != nil {
+		cmd.Env = pluginEnvironment(provider.Runtime.EnvAllowlist)
+		skipHostEnv = true
+	}
+	return &plugin.ClientConfig{
+		HandshakeConfig:  sbsdk.HandshakeConfig,
+		VersionedPlugins: versionedPluginMap,
+		Cmd:              cmd,
+		SkipHostEnv:      skipHostEnv,
+		// the protocol used is whichever one the provider binary advertises during the handshake
+		AllowedProtocols: []plugin.Protocol{plugin.ProtocolNetRPC, plugin.ProtocolGRPC},
+	}, nil
+}
+
+func (pm *DefaultPluginManager) pool(name string) (*pluginPool, error) {
+	for _, pool := range pm.pools {
+		if pool.provider.Name == name {
+			return pool, nil
+		}
+	}
+	return nil, errors.New("plugin is not available")
+}
+
+// PluginClient returns the client of the first running plugin process for the provider
+func (pm *DefaultPluginManager) PluginClient(name string) (*plugin.Client, error) {
+	pool, err := pm.pool(name)
 	if err != nil {
 		return nil, err
 	}
-	raw, err := rpcClient.Dispense("provider")
+	plug := pool.firstPlugin()
+	if plug == nil {
+		return nil, errors.New("plugin is not running")
+	}
+	return plug.Client, nil
+}
+
+// ProviderInstance returns a provider that dispatches every call to the least busy plugin
+// process in the pool of the provider
+func (pm *DefaultPluginManager) ProviderInstance(name string) (sbsdk.Provider, error) {
+	pool, err := pm.pool(name)
 	if err != nil {
 		return nil, err
 	}
-	provider := raw.(sbsdk.Provider)
-	pm.providerCache[name] = provider
-	return provider, nil
+	return pool, nil
 }
 
 func (pm *DefaultPluginManager) KillPlugin(name string) error {
-	for i, plug := range pm.plugins {
-		if plug.Name == name {
-			plug.Client.Kill()
-			pm.plugins = append(pm.plugins[:i], pm.plugins[i+1:]...)
+	for i, pool := range pm.pools {
+		if pool.provider.Name == name {
+			pool.kill()
+			pm.pools = append(pm.pools[:i], pm.pools[i+1:]...)
 			return nil
 		}
 	}
@@ -98,20 +210,30 @@ func (pm *DefaultPluginManager) KillPlugin(name string) error {
 }
 
 func (pm *DefaultPluginManager) KillAllPlugins() {
-	for _, plug := range pm.plugins {
-		plug.Client.Kill()
+	for _, pool := range pm.pools {
+		pool.kill()
 	}
-	pm.plugins = []PluginConfig{}
+	pm.pools = []*pluginPool{}
 }
 
 func (pm *DefaultPluginManager) LoadedPlugins() []string {
 	var outputList []string
-	for _, plug := range pm.plugins {
-		outputList = append(outputList, fmt.Sprintf("%s (%s@%s)", plug.Name, plug.Source, plug.Version))
+	for _, pool := range pm.pools {
+		outputList = append(outputList, fmt.Sprintf("%s (%s@%s)", pool.provider.Name, pool.provider.Source, pool.provider.Version))
 	}
 	return outputList
 }
 
+func (pm *DefaultPluginManager) Restarts() map[string]int {
+	output := make(map[string]int)
+	for _, pool := range pm.pools {
+		pool.mu.Lock()
+		output[pool.provider.Name] = pool.restarts
+		pool.mu.Unlock()
+	}
+	return output
+}
+
 var pluginMap = map[string]plugin.Plugin{
-	"provider": &sbsdk.ProviderPlugin{},
+	"provider": &providerPlugin{},
 }
@@ -0,0 +1,344 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"github.com/switchboard-org/plugin-sdk/sbsdk"
+	"log"
+	"sync"
+	"time"
+)
+
+// poolIdleTimeout is how long a plugin process above the min_instances of a pool can stay idle before it is stopped
+const poolIdleTimeout = time.Minute
+
+// pluginInstance is a single running plugin process in a pluginPool
+type pluginInstance struct {
+	plugin   *PluginConfig
+	provider sbsdk.Provider
+	// inFlight is the number of calls currently being processed by the plugin process
+	inFlight int
+	lastUsed time.Time
+}
+
+// pluginPool manages one or more plugin processes for a single provider, and implements sbsdk.Provider by
+// dispatching every call to the least busy process. New processes are started when all processes are busy,
+// up to maxInstances, and idle processes are stopped again until only minInstances remain. Processes that
+// exit unexpectedly are removed from the pool and replaced on the next call.
+type pluginPool struct {
+	provider      RequiredProviderBlock
+	minInstances  int
+	maxInstances  int
+	startInstance func() (*PluginConfig, sbsdk.Provider, error)
+
+	mu        sync.Mutex
+	instances []*pluginInstance
+	// starting is the number of processes currently being started, which count towards maxInstances
+	starting int
+	// initConfig is the payload of the last Init call, which is replayed on processes started afterwards.
+	// initCalls counts the Init calls, so that a process started meanwhile can tell it missed one.
+	initConfig []byte
+	initCalls  int
+	stopReaper chan struct{}
+	// restarts is the number of processes that exited unexpectedly, which are replaced on the next call
+	restarts int
+	// closed is set once the pool is killed, after which no processes are started
+	closed bool
+}
+
+func newPluginPool(provider RequiredProviderBlock, minInstances int, maxInstances int, startInstance func() (*PluginConfig, sbsdk.Provider, error)) *pluginPool {
+	return &pluginPool{
+		provider:      provider,
+		minInstances:  minInstances,
+		maxInstances:  maxInstances,
+		startInstance: startInstance,
+	}
+}
+
+// start launches the minimum number of plugin processes, and starts scaling down idle processes
+// if the pool is allowed to grow.
+func (p *pluginPool) start() error {
+	for i := 0; i < p.minInstances; i++ {
+		instance, err := p.newInstance()
+		if err != nil {
+			p.kill()
+			return err
+		}
+		p.mu.Lock()
+		p.instances = append(p.instances, instance)
+		p.mu.Unlock()
+	}
+	if p.maxInstances > p.minInstances {
+		p.stopReaper = make(chan struct{})
+		go p.reapIdleInstances(p.stopReaper, poolIdleTimeout)
+	}
+	return nil
+}
+
+func (p *pluginPool) newInstance() (*pluginInstance, error) {
+	plug, provider, err := p.startInstance()
+	if err != nil {
+		return nil, err
+	}
+	return &pluginInstance{
+		plugin:   plug,
+		provider: provider,
+		lastUsed: time.Now(),
+	}, nil
+}
+
+// acquire returns the least busy plugin process, starting a new one if all are busy and the pool
+// has not reached its maximum size. Every acquired instance must be released.
+func (p *pluginPool) acquire() (*pluginInstance, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return nil, fmt.Errorf("plugin for provider '%s' was killed", p.provider.Name)
+	}
+	p.removeExitedInstances()
+	instance := p.leastBusyInstance()
+	if (instance == nil || instance.inFlight > 0) && len(p.instances)+p.starting < p.maxInstances {
+		newInstance, err := p.startNewInstance()
+		// the pool may have been killed while the process was starting, which would leave it running
+		if err == nil && p.closed {
+			newInstance.plugin.kill()
+			return nil, fmt.Errorf("plugin for provider '%s' was killed", p.provider.Name)
+		}
+		// other callers may have removed the instance picked before while the lock was released
+		p.removeExitedInstances()
+		instance = p.leastBusyInstance()
+		switch {
+		case err == nil:
+			p.instances = append(p.instances, newInstance)
+			instance = newInstance
+		case instance == nil:
+			return nil, err
+		default:
+			log.Printf("WARNING: could not start additional plugin process for provider '%s': %s", p.provider.Name, err)
+		}
+	}
+	if instance == nil {
+		return nil, fmt.Errorf("no plugin process available for provider '%s'", p.provider.Name)
+	}
+	instance.inFlight++
+	return instance, nil
+}
+
+// startNewInstance starts a plugin process and replays the Init call of the pool on it. It must be called
+// while holding the pool lock, which is released while the process starts and is initialized, as both are
+// slow calls that would block every other caller. The process is initialized again if the pool was
+// initialized meanwhile.
+func (p *pluginPool) startNewInstance() (*pluginInstance, error) {
+	p.starting++
+	p.mu.Unlock()
+	instance, err := p.newInstance()
+	p.mu.Lock()
+	p.starting--
+	if err != nil {
+		return nil, err
+	}
+	for initialized := 0; initialized != p.initCalls && !p.closed; {
+		config, calls := p.initConfig, p.initCalls
+		p.mu.Unlock()
+		err = instance.provider.Init(config)
+		p.mu.Lock()
+		if err != nil {
+			err = instance.plugin.failure(err)
+			instance.plugin.kill()
+			return nil, err
+		}
+		initialized = calls
+	}
+	return instance, nil
+}
+
+func (p *pluginPool) release(instance *pluginInstance) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	instance.inFlight--
+	instance.lastUsed = time.Now()
+}
+
+// leastBusyInstance must be called while holding the pool lock
+func (p *pluginPool) leastBusyInstance() *pluginInstance {
+	var leastBusy *pluginInstance
+	for _, instance := range p.instances {
+		if leastBusy == nil || instance.inFlight < leastBusy.inFlight {
+			leastBusy = instance
+		}
+	}
+	return leastBusy
+}
+
+// removeExitedInstances must be called while holding the pool lock
+func (p *pluginPool) removeExitedInstances() {
+	var running []*pluginInstance
+	for _, instance := range p.instances {
+		if instance.plugin.exited() {
+			instance.plugin.kill()
+			p.restarts++
+			continue
+		}
+		running = append(running, instance)
+	}
+	p.instances = running
+}
+
+func (p *pluginPool) reapIdleInstances(stop <-chan struct{}, idleTimeout time.Duration) {
+	ticker := time.NewTicker(idleTimeout / 2)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			p.scaleDown(idleTimeout)
+		}
+	}
+}
+
+// scaleDown stops plugin processes that have been idle for longer than idleTimeout, keeping at least minInstances
+func (p *pluginPool) scaleDown(idleTimeout time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var kept []*pluginInstance
+	for i, instance := range p.instances {
+		remaining := len(kept) + len(p.instances) - i
+		if remaining > p.minInstances && instance.inFlight == 0 && time.Since(instance.lastUsed) > idleTimeout {
+			instance.plugin.kill()
+			continue
+		}
+		kept = append(kept, instance)
+	}
+	p.instances = kept
+}
+
+// firstPlugin returns the plugin of the first running process, or nil if there are none
+func (p *pluginPool) firstPlugin() *PluginConfig {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.instances) == 0 {
+		return nil
+	}
+	return p.instances[0].plugin
+}
+
+func (p *pluginPool) kill() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.closed = true
+	if p.stopReaper != nil {
+		close(p.stopReaper)
+		p.stopReaper = nil
+	}
+	for _, instance := range p.instances {
+		instance.plugin.kill()
+	}
+	p.instances = nil
+}
+
+// call runs fn against the least busy plugin process, adding the failure reason of the plugin to any error
+func (p *pluginPool) call(fn func(provider sbsdk.Provider) error) error {
+	instance, err := p.acquire()
+	if err != nil {
+		return err
+	}
+	defer p.release(instance)
+	return instance.plugin.failure(fn(instance.provider))
+}
+
+// Init initializes every running plugin process, and is replayed on any processes started later on
+func (p *pluginPool) Init(config []byte) error {
+	p.mu.Lock()
+	p.initConfig = config
+	p.initCalls++
+	instances := append([]*pluginInstance{}, p.instances...)
+	p.mu.Unlock()
+	var errs []error
+	for _, instance := range instances {
+		errs = append(errs, instance.plugin.failure(instance.provider.Init(config)))
+	}
+	return errors.Join(errs...)
+}
+
+func (p *pluginPool) InitSchema() (sbsdk.ObjectSchema, error) {
+	var schema sbsdk.ObjectSchema
+	err := p.call(func(provider sbsdk.Provider) (err error) {
+		schema, err = provider.InitSchema()
+		return err
+	})
+	return schema, err
+}
+
+func (p *pluginPool) ActionNames() ([]string, error) {
+	var names []string
+	err := p.call(func(provider sbsdk.Provider) (err error) {
+		names, err = provider.ActionNames()
+		return err
+	})
+	return names, err
+}
+
+func (p *pluginPool) ActionEvaluate(name string, config []byte, input []byte) ([]byte, error) {
+	var output []byte
+	err := p.call(func(provider sbsdk.Provider) (err error) {
+		output, err = provider.ActionEvaluate(name, config, input)
+		return err
+	})
+	return output, err
+}
+
+func (p *pluginPool) ActionConfigurationSchema(name string) (sbsdk.ObjectSchema, error) {
+	var schema sbsdk.ObjectSchema
+	err := p.call(func(provider sbsdk.Provider) (err error) {
+		schema, err = provider.ActionConfigurationSchema(name)
+		return err
+	})
+	return schema, err
+}
+
+func (p *pluginPool) ActionOutputType(name string) (sbsdk.Type, error) {
+	outputType := sbsdk.Invalid
+	err := p.call(func(provider sbsdk.Provider) (err error) {
+		outputType, err = provider.ActionOutputType(name)
+		return err
+	})
+	return outputType, err
+}
+
+func (p *pluginPool) TriggerNames() ([]string, error) {
+	names := []string{}
+	err := p.call(func(provider sbsdk.Provider) (err error) {
+		if triggerProvider, ok := provider.(TriggerProvider); ok {
+			names, err = triggerProvider.TriggerNames()
+		}
+		return err
+	})
+	return names, err
+}
+
+func (p *pluginPool) TriggerConfigurationSchema(name string) (sbsdk.ObjectSchema, error) {
+	var schema sbsdk.ObjectSchema
+	err := p.call(func(provider sbsdk.Provider) (err error) {
+		triggerProvider, ok := provider.(TriggerProvider)
+		if !ok {
+			return fmt.Errorf("provider '%s' does not support triggers", p.provider.Name)
+		}
+		schema, err = triggerProvider.TriggerConfigurationSchema(name)
+		return err
+	})
+	return schema, err
+}
+
+func (p *pluginPool) TriggerOutputType(name string) (sbsdk.Type, error) {
+	outputType := sbsdk.Invalid
+	err := p.call(func(provider sbsdk.Provider) (err error) {
+		triggerProvider, ok := provider.(TriggerProvider)
+		if !ok {
+			return fmt.Errorf("provider '%s' does not support triggers", p.provider.Name)
+		}
+		outputType, err = triggerProvider.TriggerOutputType(name)
+		return err
+	})
+	return outputType, err
+}
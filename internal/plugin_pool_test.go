@@ -0,0 +1,225 @@
+package internal
+
+import (
+	"github.com/switchboard-org/plugin-sdk/sbsdk"
+	"sync"
+	"testing"
+	"time"
+)
+
+// blockingTestProvider blocks every ActionEvaluate call until release is closed
+type blockingTestProvider struct {
+	testGRPCProvider
+	release chan struct{}
+	inits   int
+}
+
+func (p *blockingTestProvider) Init(_ []byte) error {
+	p.inits++
+	return nil
+}
+
+func (p *blockingTestProvider) ActionEvaluate(_ string, _ []byte, input []byte) ([]byte, error) {
+	<-p.release
+	return input, nil
+}
+
+func newTestPluginPool(minInstances int, maxInstances int, release chan struct{}) (*pluginPool, *[]*blockingTestProvider) {
+	var started []*blockingTestProvider
+	var mu sync.Mutex
+	pool := newPluginPool(RequiredProviderBlock{Name: "test"}, minInstances, maxInstances, func() (*PluginConfig, sbsdk.Provider, error) {
+		provider := &blockingTestProvider{release: release}
+		mu.Lock()
+		defer mu.Unlock()
+		started = append(started, provider)
+		return &PluginConfig{Name: "test"}, provider, nil
+	})
+	return pool, &started
+}
+
+func Test_pluginPool_scaleUp(t *testing.T) {
+	tests := []struct {
+		name          string
+		minInstances  int
+		maxInstances  int
+		calls         int
+		wantInstances int
+	}{
+		{
+			name:          "starts a process per concurrent call up to max",
+			minInstances:  1,
+			maxInstances:  3,
+			calls:         5,
+			wantInstances: 3,
+		},
+		{
+			name:          "keeps a fixed pool when min equals max",
+			minInstances:  2,
+			maxInstances:  2,
+			calls:         5,
+			wantInstances: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			release := make(chan struct{})
+			pool, started := newTestPluginPool(tt.minInstances, tt.maxInstances, release)
+			if err := pool.start(); err != nil {
+				t.Fatalf("start() error = %v", err)
+			}
+			defer pool.kill()
+			if err := pool.Init([]byte("{}")); err != nil {
+				t.Fatalf("Init() error = %v", err)
+			}
+			var wg sync.WaitGroup
+			for i := 0; i < tt.calls; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					_, _ = pool.ActionEvaluate("create_charge", nil, nil)
+				}()
+			}
+			// wait for all calls to be dispatched before releasing them
+			deadline := time.Now().Add(time.Second)
+			for time.Now().Before(deadline) {
+				pool.mu.Lock()
+				inFlight := 0
+				for _, instance := range pool.instances {
+					inFlight += instance.inFlight
+				}
+				pool.mu.Unlock()
+				if inFlight == tt.calls {
+					break
+				}
+				time.Sleep(time.Millisecond)
+			}
+			close(release)
+			wg.Wait()
+			if len(*started) != tt.wantInstances {
+				t.Errorf("started instances = %v, want %v", len(*started), tt.wantInstances)
+			}
+			for _, provider := range *started {
+				if provider.inits != 1 {
+					t.Errorf("provider initialized %v times, want 1", provider.inits)
+				}
+			}
+		})
+	}
+}
+
+func Test_pluginPool_scaleDown(t *testing.T) {
+	release := make(chan struct{})
+	close(release)
+	pool, _ := newTestPluginPool(1, 3, release)
+	for i := 0; i < 3; i++ {
+		instance, _ := pool.newInstance()
+		pool.instances = append(pool.instances, instance)
+	}
+	pool.instances[0].lastUsed = time.Now().Add(-time.Hour)
+	pool.instances[1].lastUsed = time.Now().Add(-time.Hour)
+	pool.instances[1].inFlight = 1
+
+	pool.scaleDown(time.Minute)
+	if len(pool.instances) != 2 {
+		t.Errorf("instances after scaleDown = %v, want 2", len(pool.instances))
+	}
+	if pool.instances[0].inFlight != 1 {
+		t.Errorf("scaleDown() stopped a busy instance")
+	}
+}
+
+func Test_pluginPool_killWhileStarting(t *testing.T) {
+	starting, unblock := make(chan struct{}), make(chan struct{})
+	pool := newPluginPool(RequiredProviderBlock{Name: "test"}, 0, 1, func() (*PluginConfig, sbsdk.Provider, error) {
+		close(starting)
+		<-unblock
+		return &PluginConfig{Name: "test"}, &blockingTestProvider{}, nil
+	})
+	if err := pool.start(); err != nil {
+		t.Fatalf("start() error = %v", err)
+	}
+	errs := make(chan error)
+	go func() {
+		_, err := pool.ActionNames()
+		errs <- err
+	}()
+	<-starting
+	pool.kill()
+	close(unblock)
+	if err := <-errs; err == nil {
+		t.Error("ActionNames() error = nil, want error for a killed pool")
+	}
+	if instances := len(pool.instances); instances != 0 {
+		t.Errorf("pool has %d instances after kill, want 0", instances)
+	}
+	if _, err := pool.acquire(); err == nil {
+		t.Error("acquire() error = nil, want error for a killed pool")
+	}
+}
+
+// initBlockingTestProvider blocks every Init call until release is closed
+type initBlockingTestProvider struct {
+	blockingTestProvider
+	initializing chan struct{}
+}
+
+func (p *initBlockingTestProvider) Init(_ []byte) error {
+	close(p.initializing)
+	<-p.release
+	return nil
+}
+
+func Test_pluginPool_initWithoutLock(t *testing.T) {
+	initializing, release := make(chan struct{}), make(chan struct{})
+	var started int
+	pool := newPluginPool(RequiredProviderBlock{Name: "test"}, 1, 2, func() (*PluginConfig, sbsdk.Provider, error) {
+		started++
+		if started == 1 {
+			return &PluginConfig{Name: "test"}, &blockingTestProvider{}, nil
+		}
+		return &PluginConfig{Name: "test"}, &initBlockingTestProvider{blockingTestProvider{release: release}, initializing}, nil
+	})
+	if err := pool.start(); err != nil {
+		t.Fatalf("start() error = %v", err)
+	}
+	defer pool.kill()
+	if err := pool.Init(nil); err != nil {
+		t.Fatalf("Init() error = %v", err)
+	}
+	busy, err := pool.acquire()
+	if err != nil {
+		t.Fatalf("acquire() error = %v", err)
+	}
+	// the first process is busy, so the next call starts a second one, which is initialized before it is used
+	acquired := make(chan error)
+	go func() {
+		instance, err := pool.acquire()
+		if err == nil {
+			pool.release(instance)
+		}
+		acquired <- err
+	}()
+	<-initializing
+	released := make(chan struct{})
+	go func() {
+		pool.release(busy)
+		close(released)
+	}()
+	select {
+	case <-released:
+	case <-time.After(time.Second):
+		t.Error("release() blocked while a new plugin process was initialized")
+	}
+	close(release)
+	if err = <-acquired; err != nil {
+		t.Errorf("acquire() error = %v", err)
+	}
+	<-released
+}
+
+func TestDefaultPluginManager_ProviderInstance(t *testing.T) {
+	provider, err := NewDefaultPluginManager().ProviderInstance("unknown")
+	if err == nil || provider != nil {
+		t.Errorf("ProviderInstance() = %v, %v, want nil provider and error", provider, err)
+	}
+}
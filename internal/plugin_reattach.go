@@ -0,0 +1,79 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/hashicorp/go-plugin"
+	"net"
+	"os"
+)
+
+// ReattachProvidersEnv is the environment variable provider developers can set to make switchboard
+// connect to an already running provider process (i.e. one running under a debugger) instead of
+// launching the downloaded binary. The value is a JSON object keyed by provider source, e.g.
+//
+//	{"github.com/switchboard-org/provider-stripe": {"Protocol": "grpc", "ProtocolVersion": 1, "Pid": 1234, "Addr": {"Network": "unix", "String": "/tmp/plugin123"}}}
+const ReattachProvidersEnv = "SWITCHBOARD_REATTACH_PROVIDERS"
+
+// reattachConfig is the JSON representation of a plugin.ReattachConfig, which cannot be decoded
+// directly because of its net.Addr field.
+type reattachConfig struct {
+	Protocol        string
+	ProtocolVersion int
+	Pid             int
+	Addr            reattachConfigAddr
+}
+
+type reattachConfigAddr struct {
+	Network string
+	String  string
+}
+
+// ReattachConfigs returns the go-plugin reattach configs set in ReattachProvidersEnv, keyed by
+// provider source. An empty map is returned if the environment variable is not set.
+func ReattachConfigs() (map[string]*plugin.ReattachConfig, error) {
+	output := make(map[string]*plugin.ReattachConfig)
+	rawConfig := os.Getenv(ReattachProvidersEnv)
+	if rawConfig == "" {
+		return output, nil
+	}
+	var configs map[string]reattachConfig
+	if err := json.Unmarshal([]byte(rawConfig), &configs); err != nil {
+		return nil, fmt.Errorf("invalid value for %s: %w", ReattachProvidersEnv, err)
+	}
+	for source, config := range configs {
+		var addr net.Addr
+		var err error
+		switch config.Addr.Network {
+		case "unix":
+			addr, err = net.ResolveUnixAddr("unix", config.Addr.String)
+		case "tcp":
+			addr, err = net.ResolveTCPAddr("tcp", config.Addr.String)
+		default:
+			err = fmt.Errorf("unsupported network '%s'", config.Addr.Network)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid address for '%s' in %s: %w", source, ReattachProvidersEnv, err)
+		}
+		output[source] = &plugin.ReattachConfig{
+			Protocol:        plugin.Protocol(config.Protocol),
+			ProtocolVersion: config.ProtocolVersion,
+			Pid:             config.Pid,
+			Addr:            addr,
+			// the provider process is owned by the developer, so it should never be killed by switchboard
+			Test: true,
+		}
+	}
+	return output, nil
+}
+
+// IsReattachedProvider returns true if the provider source is set in ReattachProvidersEnv. Invalid
+// values are treated as not set here, and are reported when the plugin is loaded.
+func IsReattachedProvider(source string) bool {
+	configs, err := ReattachConfigs()
+	if err != nil {
+		return false
+	}
+	_, ok := configs[source]
+	return ok
+}
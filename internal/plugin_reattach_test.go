@@ -0,0 +1,59 @@
+package internal
+
+import (
+	"github.com/hashicorp/go-plugin"
+	"net"
+	"reflect"
+	"testing"
+)
+
+func TestReattachConfigs(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     string
+		want    map[string]*plugin.ReattachConfig
+		wantErr bool
+	}{
+		{
+			name: "returns empty map when not set",
+			env:  "",
+			want: map[string]*plugin.ReattachConfig{},
+		},
+		{
+			name: "decodes reattach config keyed by source",
+			env:  `{"github.com/switchboard-org/provider-test": {"Protocol": "grpc", "ProtocolVersion": 1, "Pid": 42, "Addr": {"Network": "unix", "String": "/tmp/plugin-test"}}}`,
+			want: map[string]*plugin.ReattachConfig{
+				"github.com/switchboard-org/provider-test": {
+					Protocol:        plugin.ProtocolGRPC,
+					ProtocolVersion: 1,
+					Pid:             42,
+					Addr:            &net.UnixAddr{Name: "/tmp/plugin-test", Net: "unix"},
+					Test:            true,
+				},
+			},
+		},
+		{
+			name:    "fails with invalid json",
+			env:     `{"github.com/switchboard-org/provider-test":`,
+			wantErr: true,
+		},
+		{
+			name:    "fails with unsupported network",
+			env:     `{"github.com/switchboard-org/provider-test": {"Addr": {"Network": "udp", "String": "127.0.0.1:1234"}}}`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(ReattachProvidersEnv, tt.env)
+			got, err := ReattachConfigs()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ReattachConfigs() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ReattachConfigs() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
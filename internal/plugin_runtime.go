@@ -0,0 +1,48 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// pluginLimits tracks the runtime limits applied to a running plugin process, so the reason
+// of a plugin failure can be reported back to the user.
+type pluginLimits struct {
+	runtime RuntimeBlock
+	// pid is the id of the limited plugin process
+	pid int
+	// cgroupDir is only set when the memory limit is enforced with a cgroup v2
+	cgroupDir string
+}
+
+// failureReason explains why a plugin call failed, if it was caused by the plugin process exiting
+// or hitting one of its runtime limits. An empty string is returned if the reason is unknown.
+func (l *pluginLimits) failureReason(exited bool, err error) string {
+	if l != nil {
+		if l.runtime.MaxMemoryMB > 0 && l.memoryLimitExceeded() {
+			return fmt.Sprintf("plugin process exceeded max_memory_mb (%d) and was killed", l.runtime.MaxMemoryMB)
+		}
+		// errors returned by the plugin lose their type over rpc, so the open files of the process are counted
+		if l.runtime.MaxOpenFiles > 0 && (errors.Is(err, syscall.EMFILE) || l.openFilesExhausted()) {
+			return fmt.Sprintf("plugin process exceeded max_open_files (%d)", l.runtime.MaxOpenFiles)
+		}
+	}
+	if exited {
+		return "plugin process exited unexpectedly"
+	}
+	return ""
+}
+
+// pluginEnvironment returns the environment variables from the current process that are
+// included in the allowlist.
+func pluginEnvironment(allowlist []string) []string {
+	var env []string
+	for _, name := range allowlist {
+		if val, ok := os.LookupEnv(name); ok {
+			env = append(env, fmt.Sprintf("%s=%s", name, val))
+		}
+	}
+	return env
+}
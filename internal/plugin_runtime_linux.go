@@ -0,0 +1,119 @@
+package internal
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"golang.org/x/sys/unix"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const cgroupRoot = "/sys/fs/cgroup"
+
+// applyRuntimeLimits limits the resources of a started plugin process. The memory limit is enforced
+// with a cgroup v2 when one can be created next to the current process, falling back to a data
+// segment rlimit otherwise.
+//
+// go-plugin starts the process and performs the handshake before the limits can be applied, so the
+// plugin runs without limits until then. Plugins should not do any work before serving.
+func applyRuntimeLimits(name string, pid int, runtime RuntimeBlock) (*pluginLimits, error) {
+	limits := &pluginLimits{runtime: runtime, pid: pid}
+	if runtime.MaxOpenFiles > 0 {
+		rlimit := unix.Rlimit{Cur: uint64(runtime.MaxOpenFiles), Max: uint64(runtime.MaxOpenFiles)}
+		if err := unix.Prlimit(pid, unix.RLIMIT_NOFILE, &rlimit, nil); err != nil {
+			return limits, fmt.Errorf("could not apply max_open_files: %w", err)
+		}
+	}
+	if runtime.MaxMemoryMB > 0 {
+		maxBytes := uint64(runtime.MaxMemoryMB) * 1024 * 1024
+		cgroupDir, err := createPluginCgroup(name, pid, maxBytes)
+		if err == nil {
+			limits.cgroupDir = cgroupDir
+			return limits, nil
+		}
+		rlimit := unix.Rlimit{Cur: maxBytes, Max: maxBytes}
+		if err := unix.Prlimit(pid, unix.RLIMIT_DATA, &rlimit, nil); err != nil {
+			return limits, fmt.Errorf("could not apply max_memory_mb: %w", err)
+		}
+	}
+	return limits, nil
+}
+
+// createPluginCgroup creates a cgroup v2 for the plugin process as a child of the cgroup of the current
+// process. This only works if the cgroup has been delegated to the user running switchboard.
+func createPluginCgroup(name string, pid int, maxBytes uint64) (string, error) {
+	if _, err := os.Stat(filepath.Join(cgroupRoot, "cgroup.controllers")); err != nil {
+		return "", errors.New("cgroup v2 is not available")
+	}
+	parent, err := currentCgroup()
+	if err != nil {
+		return "", err
+	}
+	dir := filepath.Join(cgroupRoot, parent, fmt.Sprintf("switchboard-%s-%d", name, pid))
+	if err := os.Mkdir(dir, 0755); err != nil {
+		return "", err
+	}
+	err = os.WriteFile(filepath.Join(dir, "memory.max"), []byte(strconv.FormatUint(maxBytes, 10)), 0644)
+	if err == nil {
+		err = os.WriteFile(filepath.Join(dir, "cgroup.procs"), []byte(strconv.Itoa(pid)), 0644)
+	}
+	if err != nil {
+		_ = os.Remove(dir)
+		return "", err
+	}
+	return dir, nil
+}
+
+// currentCgroup returns the cgroup v2 path of the current process, relative to the cgroup root
+func currentCgroup() (string, error) {
+	data, err := os.ReadFile("/proc/self/cgroup")
+	if err != nil {
+		return "", err
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		// cgroup v2 has a single entry in the format "0::<path>"
+		if path, ok := strings.CutPrefix(scanner.Text(), "0::"); ok {
+			return path, nil
+		}
+	}
+	return "", errors.New("could not find cgroup v2 of current process")
+}
+
+// memoryLimitExceeded checks the cgroup memory events for processes killed for exceeding memory.max
+func (l *pluginLimits) memoryLimitExceeded() bool {
+	if l.cgroupDir == "" {
+		return false
+	}
+	data, err := os.ReadFile(filepath.Join(l.cgroupDir, "memory.events"))
+	if err != nil {
+		return false
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if count, ok := strings.CutPrefix(line, "oom_kill "); ok {
+			return count != "0"
+		}
+	}
+	return false
+}
+
+// openFilesExhausted returns true if the plugin process has as many open files as max_open_files allows
+func (l *pluginLimits) openFilesExhausted() bool {
+	if l.pid == 0 {
+		return false
+	}
+	entries, err := os.ReadDir(fmt.Sprintf("/proc/%d/fd", l.pid))
+	return err == nil && len(entries) >= l.runtime.MaxOpenFiles
+}
+
+// release removes the cgroup created for the plugin. It must be called after the plugin process exits
+func (l *pluginLimits) release() {
+	if l == nil || l.cgroupDir == "" {
+		return
+	}
+	_ = os.Remove(l.cgroupDir)
+}
@@ -0,0 +1,23 @@
+//go:build !linux
+
+package internal
+
+import "log"
+
+// applyRuntimeLimits is only supported on linux. On other platforms the limits are ignored with a warning
+func applyRuntimeLimits(name string, _ int, runtime RuntimeBlock) (*pluginLimits, error) {
+	if runtime.MaxMemoryMB > 0 || runtime.MaxOpenFiles > 0 {
+		log.Printf("WARNING: runtime limits for provider '%s' are only supported on linux and will be ignored", name)
+	}
+	return &pluginLimits{runtime: runtime}, nil
+}
+
+func (l *pluginLimits) memoryLimitExceeded() bool {
+	return false
+}
+
+func (l *pluginLimits) openFilesExhausted() bool {
+	return false
+}
+
+func (l *pluginLimits) release() {}
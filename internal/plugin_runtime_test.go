@@ -0,0 +1,83 @@
+package internal
+
+import (
+	"errors"
+	"os"
+	"reflect"
+	"syscall"
+	"testing"
+)
+
+func Test_pluginEnvironment(t *testing.T) {
+	t.Setenv("SWITCHBOARD_TEST_ALLOWED", "yes")
+	t.Setenv("SWITCHBOARD_TEST_SECRET", "secret")
+	tests := []struct {
+		name      string
+		allowlist []string
+		want      []string
+	}{
+		{
+			name:      "only includes allowed variables",
+			allowlist: []string{"SWITCHBOARD_TEST_ALLOWED", "SWITCHBOARD_TEST_MISSING"},
+			want:      []string{"SWITCHBOARD_TEST_ALLOWED=yes"},
+		},
+		{
+			name:      "includes nothing with empty allowlist",
+			allowlist: []string{},
+			want:      nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := pluginEnvironment(tt.allowlist); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("pluginEnvironment() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_pluginLimits_failureReason(t *testing.T) {
+	type args struct {
+		exited bool
+		err    error
+	}
+	limits := &pluginLimits{runtime: RuntimeBlock{MaxOpenFiles: 16}}
+	tests := []struct {
+		name   string
+		limits *pluginLimits
+		args   args
+		want   string
+	}{
+		{
+			name:   "reports exceeded open files",
+			limits: limits,
+			args:   args{exited: false, err: &os.PathError{Op: "open", Path: "/tmp/file", Err: syscall.EMFILE}},
+			want:   "plugin process exceeded max_open_files (16)",
+		},
+		{
+			name:   "does not match open files errors by their message",
+			limits: limits,
+			args:   args{exited: false, err: errors.New("open /tmp/file: too many open files")},
+			want:   "",
+		},
+		{
+			name:   "reports exited plugin without limits",
+			limits: nil,
+			args:   args{exited: true, err: errors.New("connection shut down")},
+			want:   "plugin process exited unexpectedly",
+		},
+		{
+			name:   "returns empty reason for regular errors",
+			limits: limits,
+			args:   args{exited: false, err: errors.New("card declined")},
+			want:   "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.limits.failureReason(tt.args.exited, tt.args.err); got != tt.want {
+				t.Errorf("failureReason() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,108 @@
+package internal
+
+import (
+	"fmt"
+	"github.com/hashicorp/go-plugin"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// pluginSdkReleases maps every plugin handshake protocol version this CLI knows about to the plugin-sdk
+// releases that advertise it. It is used to give users a readable explanation of a version mismatch.
+var pluginSdkReleases = map[int]string{
+	1: "v0.0.x",
+}
+
+// versionedPluginMap contains the plugin sets for every protocol version this CLI can talk to. When
+// a new protocol version is introduced in the plugin-sdk, older versions should stay in here for as long
+// as they are supported.
+var versionedPluginMap = map[int]plugin.PluginSet{
+	1: pluginMap,
+}
+
+// incompatiblePluginVersionRegex extracts the protocol version from the error returned by go-plugin
+// when none of the versions in versionedPluginMap are supported by the plugin.
+var incompatiblePluginVersionRegex = regexp.MustCompile(`Incompatible API version with plugin\. Plugin version: (\d+)`)
+
+// SupportedProtocolVersions returns a sorted list of plugin protocol versions this CLI supports
+func SupportedProtocolVersions() []int {
+	var versions []int
+	for v := range versionedPluginMap {
+		versions = append(versions, v)
+	}
+	sort.Ints(versions)
+	return versions
+}
+
+// PluginVersionError is returned when a provider plugin was built against a plugin-sdk that speaks a
+// protocol version this CLI does not support.
+type PluginVersionError struct {
+	Provider          string
+	ProtocolVersion   int
+	SupportedVersions []int
+}
+
+// ProviderSdkVersion is a human-readable description of the plugin-sdk release used by the provider.
+func (e *PluginVersionError) ProviderSdkVersion() string {
+	return describeSdkRelease(e.ProtocolVersion)
+}
+
+// SupportedSdkVersions is a human-readable description of the plugin-sdk releases this CLI supports.
+func (e *PluginVersionError) SupportedSdkVersions() string {
+	var releases []string
+	for _, v := range e.SupportedVersions {
+		releases = append(releases, describeSdkRelease(v))
+	}
+	return strings.Join(releases, ", ")
+}
+
+// SupportedVersionRange is the range of protocol versions supported by this CLI, i.e. "1-2"
+func (e *PluginVersionError) SupportedVersionRange() string {
+	return formatVersionRange(e.SupportedVersions)
+}
+
+func (e *PluginVersionError) Error() string {
+	return fmt.Sprintf("provider '%s' uses plugin protocol version %d (%s), supported protocol versions are %s (%s)",
+		e.Provider, e.ProtocolVersion, e.ProviderSdkVersion(), e.SupportedVersionRange(), e.SupportedSdkVersions())
+}
+
+// newPluginVersionError checks if err is a go-plugin protocol negotiation failure, and if so, converts
+// it into a PluginVersionError. Any other error is returned untouched.
+func newPluginVersionError(provider string, err error) error {
+	matches := incompatiblePluginVersionRegex.FindStringSubmatch(err.Error())
+	if matches == nil {
+		return err
+	}
+	protocolVersion, convErr := strconv.Atoi(matches[1])
+	if convErr != nil {
+		return err
+	}
+	return &PluginVersionError{
+		Provider:          provider,
+		ProtocolVersion:   protocolVersion,
+		SupportedVersions: SupportedProtocolVersions(),
+	}
+}
+
+func describeSdkRelease(protocolVersion int) string {
+	if release, ok := pluginSdkReleases[protocolVersion]; ok {
+		return "plugin-sdk " + release
+	}
+	supported := SupportedProtocolVersions()
+	if len(supported) > 0 && protocolVersion > supported[len(supported)-1] {
+		return "a plugin-sdk release newer than this CLI"
+	}
+	return "an unsupported plugin-sdk release"
+}
+
+func formatVersionRange(versions []int) string {
+	if len(versions) == 0 {
+		return "none"
+	}
+	if len(versions) == 1 {
+		return strconv.Itoa(versions[0])
+	}
+	return fmt.Sprintf("%d-%d", versions[0], versions[len(versions)-1])
+}
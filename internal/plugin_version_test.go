@@ -0,0 +1,59 @@
+package internal
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func Test_newPluginVersionError(t *testing.T) {
+	type args struct {
+		provider string
+		err      error
+	}
+	tests := []struct {
+		name string
+		args args
+		want error
+	}{
+		{
+			name: "converts go-plugin negotiation error",
+			args: args{
+				provider: "stripe",
+				err:      errors.New("Incompatible API version with plugin. Plugin version: 2, Client versions: [1]"),
+			},
+			want: &PluginVersionError{
+				Provider:          "stripe",
+				ProtocolVersion:   2,
+				SupportedVersions: []int{1},
+			},
+		},
+		{
+			name: "returns other errors untouched",
+			args: args{
+				provider: "stripe",
+				err:      errors.New("exec: no such file or directory"),
+			},
+			want: errors.New("exec: no such file or directory"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := newPluginVersionError(tt.args.provider, tt.args.err); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("newPluginVersionError() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPluginVersionError_Error(t *testing.T) {
+	err := &PluginVersionError{
+		Provider:          "stripe",
+		ProtocolVersion:   2,
+		SupportedVersions: []int{1},
+	}
+	want := "provider 'stripe' uses plugin protocol version 2 (a plugin-sdk release newer than this CLI), supported protocol versions are 1 (plugin-sdk v0.0.x)"
+	if got := err.Error(); got != want {
+		t.Errorf("Error() = %v, want %v", got, want)
+	}
+}
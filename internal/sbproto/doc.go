@@ -0,0 +1,8 @@
+/*
+Package sbproto contains the generated protobuf messages and gRPC service used to communicate with
+provider plugins that negotiate the gRPC protocol. Do not edit the generated files directly, instead
+update provider.proto and regenerate.
+*/
+package sbproto
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative provider.proto
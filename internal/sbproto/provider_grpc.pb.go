@@ -0,0 +1,389 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+
+package sbproto
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+// ProviderClient is the client API for Provider service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type ProviderClient interface {
+	Init(ctx context.Context, in *Init_Request, opts ...grpc.CallOption) (*Init_Response, error)
+	InitSchema(ctx context.Context, in *InitSchema_Request, opts ...grpc.CallOption) (*InitSchema_Response, error)
+	ActionNames(ctx context.Context, in *ActionNames_Request, opts ...grpc.CallOption) (*ActionNames_Response, error)
+	ActionConfigurationSchema(ctx context.Context, in *ConfigurationSchema_Request, opts ...grpc.CallOption) (*ConfigurationSchema_Response, error)
+	ActionOutputType(ctx context.Context, in *OutputType_Request, opts ...grpc.CallOption) (*OutputType_Response, error)
+	ActionEvaluate(ctx context.Context, in *ActionEvaluate_Request, opts ...grpc.CallOption) (*ActionEvaluate_Response, error)
+	TriggerNames(ctx context.Context, in *TriggerNames_Request, opts ...grpc.CallOption) (*TriggerNames_Response, error)
+	TriggerConfigurationSchema(ctx context.Context, in *ConfigurationSchema_Request, opts ...grpc.CallOption) (*ConfigurationSchema_Response, error)
+	TriggerOutputType(ctx context.Context, in *OutputType_Request, opts ...grpc.CallOption) (*OutputType_Response, error)
+}
+
+type providerClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewProviderClient(cc grpc.ClientConnInterface) ProviderClient {
+	return &providerClient{cc}
+}
+
+func (c *providerClient) Init(ctx context.Context, in *Init_Request, opts ...grpc.CallOption) (*Init_Response, error) {
+	out := new(Init_Response)
+	err := c.cc.Invoke(ctx, "/switchboard.provider.v1.Provider/Init", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *providerClient) InitSchema(ctx context.Context, in *InitSchema_Request, opts ...grpc.CallOption) (*InitSchema_Response, error) {
+	out := new(InitSchema_Response)
+	err := c.cc.Invoke(ctx, "/switchboard.provider.v1.Provider/InitSchema", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *providerClient) ActionNames(ctx context.Context, in *ActionNames_Request, opts ...grpc.CallOption) (*ActionNames_Response, error) {
+	out := new(ActionNames_Response)
+	err := c.cc.Invoke(ctx, "/switchboard.provider.v1.Provider/ActionNames", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *providerClient) ActionConfigurationSchema(ctx context.Context, in *ConfigurationSchema_Request, opts ...grpc.CallOption) (*ConfigurationSchema_Response, error) {
+	out := new(ConfigurationSchema_Response)
+	err := c.cc.Invoke(ctx, "/switchboard.provider.v1.Provider/ActionConfigurationSchema", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *providerClient) ActionOutputType(ctx context.Context, in *OutputType_Request, opts ...grpc.CallOption) (*OutputType_Response, error) {
+	out := new(OutputType_Response)
+	err := c.cc.Invoke(ctx, "/switchboard.provider.v1.Provider/ActionOutputType", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *providerClient) ActionEvaluate(ctx context.Context, in *ActionEvaluate_Request, opts ...grpc.CallOption) (*ActionEvaluate_Response, error) {
+	out := new(ActionEvaluate_Response)
+	err := c.cc.Invoke(ctx, "/switchboard.provider.v1.Provider/ActionEvaluate", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *providerClient) TriggerNames(ctx context.Context, in *TriggerNames_Request, opts ...grpc.CallOption) (*TriggerNames_Response, error) {
+	out := new(TriggerNames_Response)
+	err := c.cc.Invoke(ctx, "/switchboard.provider.v1.Provider/TriggerNames", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *providerClient) TriggerConfigurationSchema(ctx context.Context, in *ConfigurationSchema_Request, opts ...grpc.CallOption) (*ConfigurationSchema_Response, error) {
+	out := new(ConfigurationSchema_Response)
+	err := c.cc.Invoke(ctx, "/switchboard.provider.v1.Provider/TriggerConfigurationSchema", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *providerClient) TriggerOutputType(ctx context.Context, in *OutputType_Request, opts ...grpc.CallOption) (*OutputType_Response, error) {
+	out := new(OutputType_Response)
+	err := c.cc.Invoke(ctx, "/switchboard.provider.v1.Provider/TriggerOutputType", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ProviderServer is the server API for Provider service.
+// All implementations must embed UnimplementedProviderServer
+// for forward compatibility
+type ProviderServer interface {
+	Init(context.Context, *Init_Request) (*Init_Response, error)
+	InitSchema(context.Context, *InitSchema_Request) (*InitSchema_Response, error)
+	ActionNames(context.Context, *ActionNames_Request) (*ActionNames_Response, error)
+	ActionConfigurationSchema(context.Context, *ConfigurationSchema_Request) (*ConfigurationSchema_Response, error)
+	ActionOutputType(context.Context, *OutputType_Request) (*OutputType_Response, error)
+	ActionEvaluate(context.Context, *ActionEvaluate_Request) (*ActionEvaluate_Response, error)
+	TriggerNames(context.Context, *TriggerNames_Request) (*TriggerNames_Response, error)
+	TriggerConfigurationSchema(context.Context, *ConfigurationSchema_Request) (*ConfigurationSchema_Response, error)
+	TriggerOutputType(context.Context, *OutputType_Request) (*OutputType_Response, error)
+	mustEmbedUnimplementedProviderServer()
+}
+
+// UnimplementedProviderServer must be embedded to have forward compatible implementations.
+type UnimplementedProviderServer struct {
+}
+
+func (UnimplementedProviderServer) Init(context.Context, *Init_Request) (*Init_Response, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Init not implemented")
+}
+func (UnimplementedProviderServer) InitSchema(context.Context, *InitSchema_Request) (*InitSchema_Response, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method InitSchema not implemented")
+}
+func (UnimplementedProviderServer) ActionNames(context.Context, *ActionNames_Request) (*ActionNames_Response, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ActionNames not implemented")
+}
+func (UnimplementedProviderServer) ActionConfigurationSchema(context.Context, *ConfigurationSchema_Request) (*ConfigurationSchema_Response, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ActionConfigurationSchema not implemented")
+}
+func (UnimplementedProviderServer) ActionOutputType(context.Context, *OutputType_Request) (*OutputType_Response, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ActionOutputType not implemented")
+}
+func (UnimplementedProviderServer) ActionEvaluate(context.Context, *ActionEvaluate_Request) (*ActionEvaluate_Response, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ActionEvaluate not implemented")
+}
+func (UnimplementedProviderServer) TriggerNames(context.Context, *TriggerNames_Request) (*TriggerNames_Response, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method TriggerNames not implemented")
+}
+func (UnimplementedProviderServer) TriggerConfigurationSchema(context.Context, *ConfigurationSchema_Request) (*ConfigurationSchema_Response, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method TriggerConfigurationSchema not implemented")
+}
+func (UnimplementedProviderServer) TriggerOutputType(context.Context, *OutputType_Request) (*OutputType_Response, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method TriggerOutputType not implemented")
+}
+func (UnimplementedProviderServer) mustEmbedUnimplementedProviderServer() {}
+
+// UnsafeProviderServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to ProviderServer will
+// result in compilation errors.
+type UnsafeProviderServer interface {
+	mustEmbedUnimplementedProviderServer()
+}
+
+func RegisterProviderServer(s grpc.ServiceRegistrar, srv ProviderServer) {
+	s.RegisterService(&Provider_ServiceDesc, srv)
+}
+
+func _Provider_Init_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Init_Request)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ProviderServer).Init(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/switchboard.provider.v1.Provider/Init",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ProviderServer).Init(ctx, req.(*Init_Request))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Provider_InitSchema_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(InitSchema_Request)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ProviderServer).InitSchema(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/switchboard.provider.v1.Provider/InitSchema",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ProviderServer).InitSchema(ctx, req.(*InitSchema_Request))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Provider_ActionNames_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ActionNames_Request)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ProviderServer).ActionNames(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/switchboard.provider.v1.Provider/ActionNames",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ProviderServer).ActionNames(ctx, req.(*ActionNames_Request))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Provider_ActionConfigurationSchema_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ConfigurationSchema_Request)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ProviderServer).ActionConfigurationSchema(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/switchboard.provider.v1.Provider/ActionConfigurationSchema",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ProviderServer).ActionConfigurationSchema(ctx, req.(*ConfigurationSchema_Request))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Provider_ActionOutputType_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(OutputType_Request)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ProviderServer).ActionOutputType(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/switchboard.provider.v1.Provider/ActionOutputType",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ProviderServer).ActionOutputType(ctx, req.(*OutputType_Request))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Provider_ActionEvaluate_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ActionEvaluate_Request)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ProviderServer).ActionEvaluate(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/switchboard.provider.v1.Provider/ActionEvaluate",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ProviderServer).ActionEvaluate(ctx, req.(*ActionEvaluate_Request))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Provider_TriggerNames_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TriggerNames_Request)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ProviderServer).TriggerNames(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/switchboard.provider.v1.Provider/TriggerNames",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ProviderServer).TriggerNames(ctx, req.(*TriggerNames_Request))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Provider_TriggerConfigurationSchema_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ConfigurationSchema_Request)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ProviderServer).TriggerConfigurationSchema(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/switchboard.provider.v1.Provider/TriggerConfigurationSchema",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ProviderServer).TriggerConfigurationSchema(ctx, req.(*ConfigurationSchema_Request))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Provider_TriggerOutputType_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(OutputType_Request)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ProviderServer).TriggerOutputType(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/switchboard.provider.v1.Provider/TriggerOutputType",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ProviderServer).TriggerOutputType(ctx, req.(*OutputType_Request))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// Provider_ServiceDesc is the grpc.ServiceDesc for Provider service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Provider_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "switchboard.provider.v1.Provider",
+	HandlerType: (*ProviderServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Init",
+			Handler:    _Provider_Init_Handler,
+		},
+		{
+			MethodName: "InitSchema",
+			Handler:    _Provider_InitSchema_Handler,
+		},
+		{
+			MethodName: "ActionNames",
+			Handler:    _Provider_ActionNames_Handler,
+		},
+		{
+			MethodName: "ActionConfigurationSchema",
+			Handler:    _Provider_ActionConfigurationSchema_Handler,
+		},
+		{
+			MethodName: "ActionOutputType",
+			Handler:    _Provider_ActionOutputType_Handler,
+		},
+		{
+			MethodName: "ActionEvaluate",
+			Handler:    _Provider_ActionEvaluate_Handler,
+		},
+		{
+			MethodName: "TriggerNames",
+			Handler:    _Provider_TriggerNames_Handler,
+		},
+		{
+			MethodName: "TriggerConfigurationSchema",
+			Handler:    _Provider_TriggerConfigurationSchema_Handler,
+		},
+		{
+			MethodName: "TriggerOutputType",
+			Handler:    _Provider_TriggerOutputType_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "provider.proto",
+}
@@ -1,10 +1,13 @@
 package parsecfg
 
 import (
+	"errors"
+	"fmt"
 	"github.com/hashicorp/hcl/v2"
 	"github.com/hashicorp/hcl/v2/gohcl"
 	"github.com/switchboard-org/switchboard/internal"
 	"github.com/switchboard-org/switchboard/providers"
+	"io/fs"
 )
 
 type Parser interface {
@@ -17,15 +20,68 @@ type DefaultParser struct {
 	varFile       string
 	version       string
 	pluginManager internal.PluginManager
+	downloader    providers.Downloader
+	osManager     internal.OsManager
+	// fileSystem holds the configuration files instead of the working directory, if set
+	fileSystem fs.FS
+	// mockProviders parses the configuration without the provider plugins
+	mockProviders bool
 }
 
-func NewDefaultParser(workingDir string, varFile string, version string) Parser {
-	return &DefaultParser{
+// ParserOption replaces one of the dependencies of a DefaultParser, such as with the fakes of the sbtest package
+type ParserOption func(*DefaultParser)
+
+// WithPluginManager sets the plugin manager used to load the provider plugins
+func WithPluginManager(pluginManager internal.PluginManager) ParserOption {
+	return func(p *DefaultParser) {
+		p.pluginManager = pluginManager
+	}
+}
+
+// WithDownloader sets the downloader used to find and download provider packages
+func WithDownloader(downloader providers.Downloader) ParserOption {
+	return func(p *DefaultParser) {
+		p.downloader = downloader
+	}
+}
+
+// WithOsManager sets the os manager used to create the switchboard directories
+func WithOsManager(osManager internal.OsManager) ParserOption {
+	return func(p *DefaultParser) {
+		p.osManager = osManager
+	}
+}
+
+// WithFileSystem reads the configuration files from the root of fsys instead of the working directory, such
+// as a deployed configuration bundle. The variables file is still read from the os.
+func WithFileSystem(fsys fs.FS) ParserOption {
+	return func(p *DefaultParser) {
+		p.fileSystem = fsys
+	}
+}
+
+// WithMockedProviders parses the configuration without downloading or loading the provider plugins, for workflow
+// tests where every action is mocked. Provider settings are not parsed, and step configurations and outputs are
+// not validated against the schemas of the actions.
+func WithMockedProviders() ParserOption {
+	return func(p *DefaultParser) {
+		p.mockProviders = true
+	}
+}
+
+func NewDefaultParser(workingDir string, varFile string, version string, options ...ParserOption) Parser {
+	parser := &DefaultParser{
 		workingDir:    workingDir,
 		varFile:       varFile,
 		version:       version,
 		pluginManager: internal.NewDefaultPluginManager(),
+		downloader:    providers.NewDefaultDownloader(),
+		osManager:     internal.NewDefaultOsManager(),
 	}
+	for _, option := range options {
+		option(parser)
+	}
+	return parser
 }
 
 // Parse runs through the user provided config & calculates any expressions, returning a near completely
@@ -33,7 +89,7 @@ func NewDefaultParser(workingDir string, varFile string, version string) Parser
 // It will short circuit with any errors and return to the caller if necessary.
 func (p *DefaultParser) Parse() (*internal.RootSwitchboardConfig, hcl.Diagnostics) {
 	var switchboardConfig internal.RootSwitchboardConfig
-	rawBody, diag := loadAllHclFilesInDir(p.workingDir)
+	rawBody, diag := p.loadConfigFiles()
 	if diag.HasErrors() {
 		return nil, diag
 	}
@@ -43,7 +99,7 @@ func (p *DefaultParser) Parse() (*internal.RootSwitchboardConfig, hcl.Diagnostic
 	}
 	switchboardConfig.Variables = vars
 
-	switchboardBlock, diag := p.parseSwitchboardBlock(rawBody, switchboardConfig.EvalContext(), false)
+	switchboardBlock, requiredProviderRanges, diag := p.parseSwitchboardBlock(rawBody, switchboardConfig.EvalContext(), false)
 
 	if diag.HasErrors() {
 		return nil, diag
@@ -51,10 +107,11 @@ func (p *DefaultParser) Parse() (*internal.RootSwitchboardConfig, hcl.Diagnostic
 	switchboardConfig.Switchboard = *switchboardBlock
 
 	//load providers which will be used to validate a number of different blocks (provider, trigger, workflow actions, etc.)
-	for _, requiredProvider := range switchboardBlock.RequiredProviders {
-		err := p.pluginManager.LoadPlugin(requiredProvider)
-		if err != nil {
-			diag = diag.Append(simpleDiagnostic("could not load plugin", err.Error(), nil))
+	if !p.mockProviders {
+		defer p.pluginManager.KillAllPlugins()
+		diag = p.loadPlugins(switchboardBlock.RequiredProviders, requiredProviderRanges)
+		if diag.HasErrors() {
+			return nil, diag
 		}
 	}
 	providerBlocks, diag := p.parseProviderBlocks(rawBody, switchboardConfig.EvalContext())
@@ -62,13 +119,24 @@ func (p *DefaultParser) Parse() (*internal.RootSwitchboardConfig, hcl.Diagnostic
 		return nil, diag
 	}
 	switchboardConfig.Providers = providerBlocks
-	defer p.pluginManager.KillAllPlugins()
 
 	schemaBlocks, diag := p.parseSchemaBlocks(rawBody, switchboardConfig.EvalContext())
 	if diag.HasErrors() {
 		return nil, diag
 	}
 	switchboardConfig.Schemas = schemaBlocks
+
+	triggerBlocks, diag := p.parseTriggerBlocks(rawBody, &switchboardConfig)
+	if diag.HasErrors() {
+		return nil, diag
+	}
+	switchboardConfig.Triggers = triggerBlocks
+
+	workflowBlocks, diag := p.parseWorkflowBlocks(rawBody, &switchboardConfig)
+	if diag.HasErrors() {
+		return nil, diag
+	}
+	switchboardConfig.Workflows = workflowBlocks
 	//process config switchboard global step
 	//check if providers are downloaded
 	//remain := variableConfig.Remain
@@ -79,7 +147,7 @@ func (p *DefaultParser) Parse() (*internal.RootSwitchboardConfig, hcl.Diagnostic
 // and anything else that needs to be setup before parsing can be done
 func (p *DefaultParser) Init() hcl.Diagnostics {
 	var switchboardConfig internal.RootSwitchboardConfig
-	rawBody, diag := loadAllHclFilesInDir(p.workingDir)
+	rawBody, diag := p.loadConfigFiles()
 	if diag.HasErrors() {
 		return diag
 	}
@@ -89,10 +157,17 @@ func (p *DefaultParser) Init() hcl.Diagnostics {
 	}
 	switchboardConfig.Variables = vars
 
-	_, diag = p.parseSwitchboardBlock(rawBody, switchboardConfig.EvalContext(), true)
+	_, _, diag = p.parseSwitchboardBlock(rawBody, switchboardConfig.EvalContext(), true)
 	return diag
 }
 
+func (p *DefaultParser) loadConfigFiles() (hcl.Body, hcl.Diagnostics) {
+	if p.fileSystem != nil {
+		return loadAllHclFilesInFS(p.fileSystem)
+	}
+	return loadAllHclFilesInDir(p.workingDir)
+}
+
 func (p *DefaultParser) parseVariableBlocks(body hcl.Body) ([]internal.VariableBlock, hcl.Diagnostics) {
 	var diag hcl.Diagnostics
 	variableOverrides := getVariableDataFromJSONFile(p.varFile)
@@ -104,24 +179,56 @@ func (p *DefaultParser) parseVariableBlocks(body hcl.Body) ([]internal.VariableB
 	return variablesParser.parse(variableOverrides)
 }
 
-func (p *DefaultParser) parseSwitchboardBlock(body hcl.Body, ctx *hcl.EvalContext, init bool) (*internal.SwitchboardBlock, hcl.Diagnostics) {
+func (p *DefaultParser) parseSwitchboardBlock(body hcl.Body, ctx *hcl.EvalContext, init bool) (*internal.SwitchboardBlock, map[string]hcl.Range, hcl.Diagnostics) {
 	switchboardStepParser := switchboardBlockParser{
-		downloader: providers.NewDefaultDownloader(),
-		osManager:  internal.NewDefaultOsManager(),
+		downloader: p.downloader,
+		osManager:  p.osManager,
 	}
 	diag := gohcl.DecodeBody(body, ctx, &switchboardStepParser.config)
 	if diag.HasErrors() {
-		return nil, diag
+		return nil, nil, diag
 	}
 	if init {
-		return switchboardStepParser.init(p.version, ctx)
+		block, diag := switchboardStepParser.init(p.version, ctx)
+		return block, switchboardStepParser.requiredProviderRanges, diag
+	}
+	block, diag := switchboardStepParser.parse(p.version, ctx, !p.mockProviders)
+	return block, switchboardStepParser.requiredProviderRanges, diag
+}
+
+// loadPlugins starts every required provider plugin, which negotiates the plugin protocol version with the
+// provider binary. Failures are reported against the matching required_provider block.
+func (p *DefaultParser) loadPlugins(requiredProviders []internal.RequiredProviderBlock, ranges map[string]hcl.Range) hcl.Diagnostics {
+	var diag hcl.Diagnostics
+	for _, requiredProvider := range requiredProviders {
+		blockRange, hasRange := ranges[requiredProvider.Name]
+		var subject *hcl.Range
+		if hasRange {
+			subject = &blockRange
+		}
+		err := p.pluginManager.LoadPlugin(requiredProvider)
+		var versionErr *internal.PluginVersionError
+		if errors.As(err, &versionErr) {
+			diag = diag.Append(simpleDiagnostic(
+				"Incompatible provider plugin-sdk version",
+				fmt.Sprintf("Provider '%s' (%s@%s) was built with %s, which uses plugin protocol version %d. This version of switchboard supports protocol versions %s (%s). Use a provider version built against a supported plugin-sdk, or upgrade switchboard.",
+					requiredProvider.Name, requiredProvider.Source, requiredProvider.Version, versionErr.ProviderSdkVersion(),
+					versionErr.ProtocolVersion, versionErr.SupportedVersionRange(), versionErr.SupportedSdkVersions()),
+				subject,
+			))
+			continue
+		}
+		if err != nil {
+			diag = diag.Append(simpleDiagnostic("could not load plugin", err.Error(), subject))
+		}
 	}
-	return switchboardStepParser.parse(p.version, ctx, true)
+	return diag
 }
 
 func (p *DefaultParser) parseProviderBlocks(body hcl.Body, ctx *hcl.EvalContext) ([]internal.ProviderBlock, hcl.Diagnostics) {
 	providersStepParser := providerBlocksParser{
 		pluginManager: p.pluginManager,
+		mockProviders: p.mockProviders,
 	}
 	diag := gohcl.DecodeBody(body, ctx, &providersStepParser.config)
 	if diag.HasErrors() {
@@ -138,3 +245,25 @@ func (p *DefaultParser) parseSchemaBlocks(body hcl.Body, ctx *hcl.EvalContext) (
 	}
 	return schemaStepParser.parse()
 }
+
+func (p *DefaultParser) parseTriggerBlocks(body hcl.Body, config *internal.RootSwitchboardConfig) ([]internal.TriggerBlock, hcl.Diagnostics) {
+	triggerStepParser := triggerBlockParser{}
+	diag := gohcl.DecodeBody(body, config.EvalContext(), &triggerStepParser.triggerConfigs)
+	if diag.HasErrors() {
+		return nil, diag
+	}
+	return triggerStepParser.parse(config)
+}
+
+func (p *DefaultParser) parseWorkflowBlocks(body hcl.Body, config *internal.RootSwitchboardConfig) ([]internal.WorkflowBlock, hcl.Diagnostics) {
+	workflowsStepParser := workflowBlocksParser{
+		pluginManager: p.pluginManager,
+		mockProviders: p.mockProviders,
+	}
+	ctx := config.EvalContext()
+	diag := gohcl.DecodeBody(body, ctx, &workflowsStepParser.config)
+	if diag.HasErrors() {
+		return nil, diag
+	}
+	return workflowsStepParser.parse(config, ctx)
+}
@@ -0,0 +1,37 @@
+package parsecfg
+
+import (
+	"fmt"
+	"github.com/hashicorp/hcl/v2"
+	"github.com/switchboard-org/switchboard/internal"
+	"net/url"
+	"strings"
+)
+
+// hostBlockConfig is the configuration of the host block in the switchboard block, which tells the CLI
+// where configurations are deployed to
+type hostBlockConfig struct {
+	Address string  `hcl:"address"`
+	Key     *string `hcl:"key"`
+	//there are no other fields. Remain gives us access the hcl.Range data for the block
+	Remain hcl.Body `hcl:",remain"`
+}
+
+// parseHostBlock validates the host block. A nil block returns a nil HostBlock.
+func parseHostBlock(block *hostBlockConfig) (*internal.HostBlock, hcl.Diagnostics) {
+	if block == nil {
+		return nil, nil
+	}
+	blockRange := block.Remain.MissingItemRange()
+	address, err := url.Parse(block.Address)
+	if err != nil || (address.Scheme != "http" && address.Scheme != "https") || address.Host == "" {
+		return nil, hcl.Diagnostics{simpleDiagnostic("invalid 'address' value", fmt.Sprintf("address must be an http or https URL, such as 'https://switchboard.example.com', got '%s'", block.Address), &blockRange)}
+	}
+	hostBlock := internal.HostBlock{
+		Address: strings.TrimSuffix(block.Address, "/"),
+	}
+	if block.Key != nil {
+		hostBlock.Key = *block.Key
+	}
+	return &hostBlock, nil
+}
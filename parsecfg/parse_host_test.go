@@ -0,0 +1,55 @@
+package parsecfg
+
+import (
+	"github.com/hashicorp/hcl/v2/hclsimple"
+	"github.com/switchboard-org/switchboard/internal"
+	"reflect"
+	"testing"
+)
+
+func Test_parseHostBlock(t *testing.T) {
+	var config struct {
+		Switchboard []switchboardBlockContentsConfig `hcl:"switchboard,block"`
+	}
+	if err := hclsimple.DecodeFile("../fixtures/switchboard_config/host.hcl", nil, &config); err != nil {
+		t.Fatalf("could not decode fixture: %v", err)
+	}
+	tests := []struct {
+		name          string
+		block         *hostBlockConfig
+		want          *internal.HostBlock
+		wantDiagCount int
+	}{
+		{
+			name:  "should return nil without a host block",
+			block: nil,
+			want:  nil,
+		},
+		{
+			name:  "should parse the address and key",
+			block: config.Switchboard[0].Host,
+			want:  &internal.HostBlock{Address: "https://switchboard.example.com", Key: "sbt_abc_123"},
+		},
+		{
+			name:  "should leave the key empty if not set",
+			block: config.Switchboard[1].Host,
+			want:  &internal.HostBlock{Address: "https://switchboard.example.com"},
+		},
+		{
+			name:          "should fail for an address without a scheme",
+			block:         config.Switchboard[2].Host,
+			wantDiagCount: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, diag := parseHostBlock(tt.block)
+			if len(diag) != tt.wantDiagCount {
+				t.Fatalf("parseHostBlock() diagnostics = %v, want %d", diag, tt.wantDiagCount)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseHostBlock() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
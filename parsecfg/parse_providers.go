@@ -4,11 +4,14 @@ import (
 	"github.com/hashicorp/hcl/v2"
 	"github.com/hashicorp/hcl/v2/hcldec"
 	"github.com/switchboard-org/switchboard/internal"
+	"github.com/zclconf/go-cty/cty"
 )
 
 type providerBlocksParser struct {
 	config        providerBlocksConfig
 	pluginManager internal.PluginManager
+	// mockProviders skips the provider settings, which are only validated by the plugins
+	mockProviders bool
 }
 
 type providerBlocksConfig struct {
@@ -32,10 +35,16 @@ func (p *providerBlocksParser) parse(ctx *hcl.EvalContext) ([]internal.ProviderB
 			BlockName: provider.Name,
 		}
 		hclRange := provider.Remain.MissingItemRange()
-		if provider.RequiredProvider == nil {
-			providerBlock.ProviderName = provider.Name
+		providerBlock.ProviderName = provider.Name
+		if provider.RequiredProvider != nil {
+			providerBlock.ProviderName = *provider.RequiredProvider
 		}
-		pluginProvider, err := p.pluginManager.ProviderInstance(provider.Name)
+		if p.mockProviders {
+			providerBlock.InitPayload = cty.EmptyObjectVal
+			output = append(output, providerBlock)
+			continue
+		}
+		pluginProvider, err := p.pluginManager.ProviderInstance(providerBlock.ProviderName)
 		if err != nil {
 			diagnostics = diagnostics.Append(simpleDiagnostic("could not get plugin provider instance", err.Error(), &hclRange))
 			continue
@@ -0,0 +1,76 @@
+package parsecfg
+
+import (
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsimple"
+	"github.com/switchboard-org/plugin-sdk/sbsdk"
+	"github.com/switchboard-org/switchboard/internal"
+	"github.com/switchboard-org/switchboard/sbtest"
+	"github.com/zclconf/go-cty/cty"
+	"testing"
+)
+
+func Test_providerBlocksParser_parse(t *testing.T) {
+	var decodedConfig providerBlocksConfig
+	if err := hclsimple.DecodeFile("../fixtures/provider_config/providers.hcl", nil, &decodedConfig); err != nil {
+		t.Fatalf("could not decode fixture: %v", err)
+	}
+	provider := sbtest.NewFakeProvider().WithInitSchema(sbsdk.ObjectSchema{
+		"api_key": sbsdk.RequiredAttrSchema("api_key", sbsdk.String),
+	})
+	pluginManager := sbtest.NewFakePluginManager(map[string]sbsdk.Provider{"stripe": provider})
+	if err := pluginManager.LoadPlugin(internal.RequiredProviderBlock{Name: "stripe"}); err != nil {
+		t.Fatalf("LoadPlugin() error = %v", err)
+	}
+	tests := []struct {
+		name             string
+		config           providerBlockConfig
+		wantProviderName string
+		wantAPIKey       string
+		wantDiagCount    int
+	}{
+		{
+			name:             "should decode the settings with the init schema of the plugin",
+			config:           decodedConfig.Providers[0],
+			wantProviderName: "stripe",
+			wantAPIKey:       "sk_test",
+		},
+		{
+			name:             "should use the plugin of required_provider",
+			config:           decodedConfig.Providers[1],
+			wantProviderName: "stripe",
+			wantAPIKey:       "sk_eu",
+		},
+		{
+			name:          "should fail when a required setting is missing",
+			config:        decodedConfig.Providers[2],
+			wantDiagCount: 1,
+		},
+		{
+			name:          "should fail when the plugin is not loaded",
+			config:        decodedConfig.Providers[3],
+			wantDiagCount: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &providerBlocksParser{
+				config:        providerBlocksConfig{Providers: []providerBlockConfig{tt.config}},
+				pluginManager: pluginManager,
+			}
+			got, got1 := p.parse(&hcl.EvalContext{})
+			if len(got1.Errs()) != tt.wantDiagCount {
+				t.Fatalf("parse() error count = %v, want %v: %v", len(got1.Errs()), tt.wantDiagCount, got1)
+			}
+			if tt.wantDiagCount > 0 {
+				return
+			}
+			if len(got) != 1 || got[0].ProviderName != tt.wantProviderName || got[0].BlockName != tt.config.Name {
+				t.Fatalf("parse() got = %v, want provider '%s'", got, tt.wantProviderName)
+			}
+			if apiKey := got[0].InitPayload.GetAttr("api_key"); !apiKey.RawEquals(cty.StringVal(tt.wantAPIKey)) {
+				t.Errorf("parse() api_key = %v, want %v", apiKey.GoString(), tt.wantAPIKey)
+			}
+		})
+	}
+}
@@ -0,0 +1,89 @@
+package parsecfg
+
+import (
+	"fmt"
+	"github.com/hashicorp/hcl/v2"
+	"github.com/switchboard-org/switchboard/internal"
+	"regexp"
+	"time"
+)
+
+// retryBlockConfig is the configuration of a retry block, which can be set in the switchboard,
+// workflow and step blocks. Fields that are not set are inherited from the parent block.
+type retryBlockConfig struct {
+	MaxAttempts     *int      `hcl:"max_attempts"`
+	InitialInterval *string   `hcl:"initial_interval"`
+	MaxInterval     *string   `hcl:"max_interval"`
+	Multiplier      *float64  `hcl:"multiplier"`
+	Jitter          *float64  `hcl:"jitter"`
+	RetryOn         *[]string `hcl:"retry_on"`
+	//there are no other fields. Remain gives us access the hcl.Range data for the block
+	Remain hcl.Body `hcl:",remain"`
+}
+
+// parseRetryBlock validates a retry block and merges it into the parent retry settings. A nil block
+// returns the parent settings unchanged.
+func parseRetryBlock(block *retryBlockConfig, parent internal.RetryBlock) (internal.RetryBlock, hcl.Diagnostics) {
+	var diag hcl.Diagnostics
+	if block == nil {
+		return parent, diag
+	}
+	blockRange := block.Remain.MissingItemRange()
+	retryBlock := parent
+	if block.MaxAttempts != nil {
+		if *block.MaxAttempts < 1 {
+			diag = diag.Append(simpleDiagnostic("invalid 'max_attempts' value", "max_attempts must be at least 1", &blockRange))
+		}
+		retryBlock.MaxAttempts = *block.MaxAttempts
+	}
+	if block.InitialInterval != nil {
+		interval, intervalDiag := parseRetryInterval("initial_interval", *block.InitialInterval, &blockRange)
+		diag = diag.Extend(intervalDiag)
+		retryBlock.InitialInterval = interval
+	}
+	if block.MaxInterval != nil {
+		interval, intervalDiag := parseRetryInterval("max_interval", *block.MaxInterval, &blockRange)
+		diag = diag.Extend(intervalDiag)
+		retryBlock.MaxInterval = interval
+	}
+	if block.Multiplier != nil {
+		if *block.Multiplier < 1 {
+			diag = diag.Append(simpleDiagnostic("invalid 'multiplier' value", "multiplier must be at least 1", &blockRange))
+		}
+		retryBlock.Multiplier = *block.Multiplier
+	}
+	if block.Jitter != nil {
+		if *block.Jitter < 0 || *block.Jitter > 1 {
+			diag = diag.Append(simpleDiagnostic("invalid 'jitter' value", "jitter must be between 0 and 1", &blockRange))
+		}
+		retryBlock.Jitter = *block.Jitter
+	}
+	if block.RetryOn != nil {
+		retryBlock.RetryOn = nil
+		for _, pattern := range *block.RetryOn {
+			compiled, err := regexp.Compile(pattern)
+			if err != nil {
+				diag = diag.Append(simpleDiagnostic("invalid 'retry_on' value", fmt.Sprintf("'%s' is not a valid regular expression: %s", pattern, err), &blockRange))
+				continue
+			}
+			retryBlock.RetryOn = append(retryBlock.RetryOn, compiled)
+		}
+	}
+	if retryBlock.MaxInterval < retryBlock.InitialInterval {
+		diag = diag.Append(simpleDiagnostic("invalid 'max_interval' value", "max_interval must be greater than or equal to initial_interval", &blockRange))
+	}
+	return retryBlock, diag
+}
+
+func parseRetryInterval(name string, value string, subject *hcl.Range) (time.Duration, hcl.Diagnostics) {
+	var diag hcl.Diagnostics
+	interval, err := time.ParseDuration(value)
+	if err != nil || interval < 0 {
+		diag = diag.Append(simpleDiagnostic(
+			fmt.Sprintf("invalid '%s' value", name),
+			fmt.Sprintf("%s must be a positive duration such as '500ms' or '10s'", name),
+			subject,
+		))
+	}
+	return interval, diag
+}
@@ -0,0 +1,76 @@
+package parsecfg
+
+import (
+	"github.com/hashicorp/hcl/v2/hclsimple"
+	"github.com/switchboard-org/switchboard/internal"
+	"reflect"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func Test_parseRetryBlock(t *testing.T) {
+	var config struct {
+		Switchboard switchboardBlockContentsConfig `hcl:"switchboard,block"`
+		Workflows   []workflowBlockConfig          `hcl:"workflow,block"`
+	}
+	if err := hclsimple.DecodeFile("../fixtures/switchboard_config/retry.hcl", nil, &config); err != nil {
+		t.Fatalf("could not decode fixture: %v", err)
+	}
+	globalRetry := internal.RetryBlock{
+		MaxAttempts:     5,
+		InitialInterval: 500 * time.Millisecond,
+		MaxInterval:     time.Minute,
+		Multiplier:      3,
+		Jitter:          0.2,
+		RetryOn:         []*regexp.Regexp{regexp.MustCompile("rate limit"), regexp.MustCompile("^timeout")},
+	}
+	partialRetry := globalRetry
+	partialRetry.MaxAttempts = 2
+	tests := []struct {
+		name          string
+		block         *retryBlockConfig
+		parent        internal.RetryBlock
+		want          internal.RetryBlock
+		wantDiagCount int
+	}{
+		{
+			name:          "should return parent settings without a retry block",
+			block:         nil,
+			parent:        globalRetry,
+			want:          globalRetry,
+			wantDiagCount: 0,
+		},
+		{
+			name:          "should parse all settings",
+			block:         config.Switchboard.Retry,
+			parent:        internal.DefaultRetryBlock(),
+			want:          globalRetry,
+			wantDiagCount: 0,
+		},
+		{
+			name:          "should inherit settings that are not overridden",
+			block:         config.Workflows[0].Retry,
+			parent:        globalRetry,
+			want:          partialRetry,
+			wantDiagCount: 0,
+		},
+		{
+			name:          "should fail with invalid settings",
+			block:         config.Workflows[1].Retry,
+			parent:        internal.DefaultRetryBlock(),
+			wantDiagCount: 4,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, got1 := parseRetryBlock(tt.block, tt.parent)
+			if len(got1.Errs()) != tt.wantDiagCount {
+				t.Errorf("parseRetryBlock() error count = %v, want %v", len(got1.Errs()), tt.wantDiagCount)
+			}
+			if tt.wantDiagCount == 0 && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseRetryBlock() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
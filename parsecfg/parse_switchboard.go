@@ -17,6 +17,9 @@ type switchboardBlockParser struct {
 	config     switchboardBlockStepConfig
 	downloader providers.Downloader
 	osManager  internal.OsManager
+	// requiredProviderRanges is populated during parse and maps each required_provider name to its
+	// block range, so later parsing steps can attach diagnostics to the right block.
+	requiredProviderRanges map[string]hcl.Range
 }
 
 // switchboardBlockStepConfig is a simple struct that allows us to parse the switchboard
@@ -30,8 +33,10 @@ type switchboardBlockStepConfig struct {
 // from the switchboard block, ignoring all but version and host for later processing
 type switchboardBlockContentsConfig struct {
 	//Version is an expression, so we can show diagnostics if necessary upon evaluation
-	Version hcl.Expression `hcl:"version"`
-	Remain  hcl.Body       `hcl:",remain"`
+	Version hcl.Expression    `hcl:"version"`
+	Retry   *retryBlockConfig `hcl:"retry,block"`
+	Host    *hostBlockConfig  `hcl:"host,block"`
+	Remain  hcl.Body          `hcl:",remain"`
 }
 
 // requiredProviderBlocksStepConfig is a struct used for parsing required providers from the
@@ -42,9 +47,21 @@ type requiredProviderBlocksStepConfig struct {
 
 // requireProviderBlockConfig
 type requiredProviderContentsConfig struct {
-	Name    string         `hcl:"name,label"`
-	Source  string         `hcl:"source"`
-	Version hcl.Expression `hcl:"version"`
+	Name    string              `hcl:"name,label"`
+	Source  string              `hcl:"source"`
+	Version hcl.Expression      `hcl:"version"`
+	Runtime *runtimeBlockConfig `hcl:"runtime,block"`
+	//there are no other fields. Remain gives us access the hcl.Range data for the block
+	Remain hcl.Body `hcl:",remain"`
+}
+
+// runtimeBlockConfig contains the process settings of a provider plugin
+type runtimeBlockConfig struct {
+	EnvAllowlist *[]string `hcl:"env_allowlist"`
+	MaxMemoryMB  *int      `hcl:"max_memory_mb"`
+	MaxOpenFiles *int      `hcl:"max_open_files"`
+	MinInstances *int      `hcl:"min_instances"`
+	MaxInstances *int      `hcl:"max_instances"`
 	//there are no other fields. Remain gives us access the hcl.Range data for the block
 	Remain hcl.Body `hcl:",remain"`
 }
@@ -64,6 +81,20 @@ func (c *switchboardBlockParser) parse(currentVersion string, ctx *hcl.EvalConte
 		return nil, diag
 	}
 
+	var retryBlock *internal.RetryBlock
+	if c.config.Switchboard.Retry != nil {
+		parsedRetryBlock, retryDiag := parseRetryBlock(c.config.Switchboard.Retry, internal.DefaultRetryBlock())
+		if retryDiag.HasErrors() {
+			return nil, retryDiag
+		}
+		retryBlock = &parsedRetryBlock
+	}
+
+	hostBlock, diag := parseHostBlock(c.config.Switchboard.Host)
+	if diag.HasErrors() {
+		return nil, diag
+	}
+
 	blocks, diag := parseRequiredBlocks(c.config.Switchboard.Remain, ctx)
 	var requiredBlocks []internal.RequiredProviderBlock
 	if diag.HasErrors() {
@@ -82,13 +113,17 @@ func (c *switchboardBlockParser) parse(currentVersion string, ctx *hcl.EvalConte
 		}
 	}
 
+	c.requiredProviderRanges = make(map[string]hcl.Range)
 	for _, block := range blocks {
 		requiredBlocks = append(requiredBlocks, block.block)
+		c.requiredProviderRanges[block.block.Name] = block.blockRange
 	}
 
 	return &internal.SwitchboardBlock{
 			Version:           versionStr,
+			Host:              hostBlock,
 			RequiredProviders: requiredBlocks,
+			Retry:             retryBlock,
 		},
 		nil
 }
@@ -120,7 +155,7 @@ func (c *switchboardBlockParser) init(currentVersion string, ctx *hcl.EvalContex
 			Name:    internal.PackageName(provider.Source),
 			Version: provider.Version,
 		}
-		if !slices.Contains(presentProviders, providerPackage) {
+		if !slices.Contains(presentProviders, providerPackage) && !internal.IsReattachedProvider(provider.Source) {
 			err = c.downloader.DownloadProvider(provider.Source, provider.Version)
 			if err != nil {
 				reason := fmt.Sprintf("Provider: %s@%v, Reason: %s", provider.Source, provider.Version, err)
@@ -217,19 +252,71 @@ func parseRequiredPackageBlockStep(block requiredProviderContentsConfig, ctx *hc
 	if exprDiag.HasErrors() {
 		return internal.RequiredProviderBlock{}, diag.Extend(exprDiag)
 	}
+	runtimeBlock, runtimeDiag := parseRuntimeBlock(block.Runtime)
+	if runtimeDiag.HasErrors() {
+		return internal.RequiredProviderBlock{}, diag.Extend(runtimeDiag)
+	}
 	return internal.RequiredProviderBlock{
 		Name:    block.Name,
 		Source:  block.Source,
 		Version: packageVersion,
+		Runtime: runtimeBlock,
 	}, diag
 }
 
+// parseRuntimeBlock validates the optional runtime block of a required_provider and converts it into a RuntimeBlock
+func parseRuntimeBlock(block *runtimeBlockConfig) (*internal.RuntimeBlock, hcl.Diagnostics) {
+	var diag hcl.Diagnostics
+	if block == nil {
+		return nil, diag
+	}
+	blockRange := block.Remain.MissingItemRange()
+	runtimeBlock := internal.RuntimeBlock{
+		MinInstances: 1,
+		MaxInstances: 1,
+	}
+	if block.EnvAllowlist != nil {
+		runtimeBlock.EnvAllowlist = append([]string{}, *block.EnvAllowlist...)
+	}
+	if block.MaxMemoryMB != nil {
+		if *block.MaxMemoryMB <= 0 {
+			diag = diag.Append(simpleDiagnostic("invalid 'max_memory_mb' value", "max_memory_mb must be greater than 0", &blockRange))
+		}
+		runtimeBlock.MaxMemoryMB = *block.MaxMemoryMB
+	}
+	if block.MaxOpenFiles != nil {
+		if *block.MaxOpenFiles <= 0 {
+			diag = diag.Append(simpleDiagnostic("invalid 'max_open_files' value", "max_open_files must be greater than 0", &blockRange))
+		}
+		runtimeBlock.MaxOpenFiles = *block.MaxOpenFiles
+	}
+	if block.MinInstances != nil {
+		runtimeBlock.MinInstances = *block.MinInstances
+		// without an explicit max, the pool is fixed at min_instances
+		runtimeBlock.MaxInstances = runtimeBlock.MinInstances
+	}
+	if block.MaxInstances != nil {
+		runtimeBlock.MaxInstances = *block.MaxInstances
+	}
+	if runtimeBlock.MinInstances < 1 {
+		diag = diag.Append(simpleDiagnostic("invalid 'min_instances' value", "min_instances must be at least 1", &blockRange))
+	}
+	if runtimeBlock.MaxInstances < runtimeBlock.MinInstances {
+		diag = diag.Append(simpleDiagnostic("invalid 'max_instances' value", "max_instances must be greater than or equal to min_instances", &blockRange))
+	}
+	return &runtimeBlock, diag
+}
+
 // verifyPresenceOfPackages takes in a list of packages and checks whether they are present in the local
 // package cache (usually in /.switchboard/packages/...)
 func verifyPresenceOfPackages(downloadedPackages []providers.Package, packages []requiredProviderData) hcl.Diagnostics {
 	var diag hcl.Diagnostics
 
 	for _, plugin := range packages {
+		// reattached providers are run by the developer, so they don't need to be downloaded
+		if internal.IsReattachedProvider(plugin.block.Source) {
+			continue
+		}
 		pack := providers.Package{
 			Name:    internal.PackageName(plugin.block.Source),
 			Version: plugin.block.Version,
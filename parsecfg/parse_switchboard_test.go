@@ -493,3 +493,53 @@ func testRange() hcl.Range {
 		},
 	}
 }
+
+func Test_parseRequiredPackageBlockStep_runtime(t *testing.T) {
+	decodedConfig := getDecodedSwitchboardStepConfig("../fixtures/switchboard_config/runtime.hcl")
+	requiredProviders, diag := parseRequiredPackageBlocksStep(decodedConfig.Switchboard.Remain, nil)
+	if diag.HasErrors() {
+		t.Fatalf("parseRequiredPackageBlocksStep() diag = %v", diag)
+	}
+	tests := []struct {
+		name          string
+		block         requiredProviderContentsConfig
+		want          *internal.RuntimeBlock
+		wantDiagCount int
+	}{
+		{
+			name:  "should parse runtime block",
+			block: requiredProviders.RequiredProviders[0],
+			want: &internal.RuntimeBlock{
+				EnvAllowlist: []string{"PATH", "STRIPE_API_KEY"},
+				MaxMemoryMB:  512,
+				MaxOpenFiles: 1024,
+				MinInstances: 2,
+				MaxInstances: 4,
+			},
+			wantDiagCount: 0,
+		},
+		{
+			name:          "should fail with invalid limit",
+			block:         requiredProviders.RequiredProviders[1],
+			want:          nil,
+			wantDiagCount: 1,
+		},
+		{
+			name:          "should fail with max_instances lower than min_instances",
+			block:         requiredProviders.RequiredProviders[2],
+			want:          nil,
+			wantDiagCount: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, got1 := parseRequiredPackageBlockStep(tt.block, nil)
+			if !reflect.DeepEqual(got.Runtime, tt.want) {
+				t.Errorf("parseRequiredPackageBlockStep() runtime = %v, want %v", got.Runtime, tt.want)
+			}
+			if len(got1.Errs()) != tt.wantDiagCount {
+				t.Errorf("parseRequiredPackageBlockStep() error count = %v, want %v", len(got1.Errs()), tt.wantDiagCount)
+			}
+		})
+	}
+}
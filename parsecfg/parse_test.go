@@ -0,0 +1,104 @@
+package parsecfg
+
+import (
+	"errors"
+	"github.com/switchboard-org/plugin-sdk/sbsdk"
+	"github.com/switchboard-org/switchboard/providers"
+	"github.com/switchboard-org/switchboard/sbtest"
+	"reflect"
+	"testing"
+)
+
+// testStripeProvider returns a provider for the parse_config fixture, which has a create_charge action if withActions is set
+func testStripeProvider(withActions bool) *sbtest.FakeProvider {
+	provider := sbtest.NewFakeProvider().WithInitSchema(sbsdk.ObjectSchema{
+		"api_key": sbsdk.RequiredAttrSchema("api_key", sbsdk.String),
+	})
+	if withActions {
+		provider.WithEchoAction("create_charge", "amount")
+	}
+	return provider
+}
+
+func TestDefaultParser_Parse(t *testing.T) {
+	stripePackage := providers.Package{Name: "provider-stripe", Version: "1.0.0"}
+	tests := []struct {
+		name          string
+		pluginManager *sbtest.FakePluginManager
+		downloader    *sbtest.FakeDownloader
+		mockProviders bool
+		wantDiagCount int
+	}{
+		{
+			name:          "should parse a configuration with fake providers",
+			pluginManager: sbtest.NewFakePluginManager(map[string]sbsdk.Provider{"stripe": testStripeProvider(true)}),
+			downloader:    sbtest.NewFakeDownloader(stripePackage),
+		},
+		{
+			name:          "should fail when a provider package is not downloaded",
+			pluginManager: sbtest.NewFakePluginManager(map[string]sbsdk.Provider{"stripe": testStripeProvider(true)}),
+			downloader:    sbtest.NewFakeDownloader(),
+			wantDiagCount: 1,
+		},
+		{
+			name:          "should fail when a plugin can not be loaded",
+			pluginManager: sbtest.NewFakePluginManager(nil).WithLoadError("stripe", errors.New("exec format error")),
+			downloader:    sbtest.NewFakeDownloader(stripePackage),
+			wantDiagCount: 1,
+		},
+		{
+			name:          "should fail when a step uses an action the plugin does not have",
+			pluginManager: sbtest.NewFakePluginManager(map[string]sbsdk.Provider{"stripe": testStripeProvider(false)}),
+			downloader:    sbtest.NewFakeDownloader(stripePackage),
+			wantDiagCount: 2,
+		},
+		{
+			name:          "should parse without downloaded or loadable plugins when providers are mocked",
+			pluginManager: sbtest.NewFakePluginManager(nil).WithLoadError("stripe", errors.New("exec format error")),
+			downloader:    sbtest.NewFakeDownloader(),
+			mockProviders: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options := []ParserOption{
+				WithPluginManager(tt.pluginManager),
+				WithDownloader(tt.downloader),
+				WithOsManager(&sbtest.FakeOsManager{}),
+			}
+			if tt.mockProviders {
+				options = append(options, WithMockedProviders())
+			}
+			p := NewDefaultParser("../fixtures/parse_config", "", "1.2.0", options...)
+			got, got1 := p.Parse()
+			if len(got1.Errs()) != tt.wantDiagCount {
+				t.Fatalf("Parse() error count = %v, want %v: %v", len(got1.Errs()), tt.wantDiagCount, got1)
+			}
+			if tt.wantDiagCount > 0 {
+				return
+			}
+			workflow := got.Workflow("charge")
+			if workflow == nil || workflow.Trigger != "nightly" || len(workflow.Steps) != 2 || !reflect.DeepEqual(workflow.Steps[1].DependsOn, []string{"charge"}) {
+				t.Errorf("Parse() workflow = %v", workflow)
+			}
+			if loaded := tt.pluginManager.LoadedPlugins(); len(loaded) != 0 {
+				t.Errorf("LoadedPlugins() = %v, want all plugins killed after parsing", loaded)
+			}
+		})
+	}
+}
+
+func TestDefaultParser_Init(t *testing.T) {
+	downloader := sbtest.NewFakeDownloader()
+	osManager := &sbtest.FakeOsManager{}
+	p := NewDefaultParser("../fixtures/parse_config", "", "1.2.0", WithDownloader(downloader), WithOsManager(osManager))
+	if diag := p.Init(); diag.HasErrors() {
+		t.Fatalf("Init() error = %v", diag)
+	}
+	if got, _ := downloader.DownloadedProviders(); !reflect.DeepEqual(got, []providers.Package{{Name: "provider-stripe", Version: "1.0.0"}}) {
+		t.Errorf("DownloadedProviders() = %v", got)
+	}
+	if got := osManager.Directories(); !reflect.DeepEqual(got, []string{"./.switchboard/packages"}) {
+		t.Errorf("Directories() = %v", got)
+	}
+}
@@ -1,8 +1,13 @@
 package parsecfg
 
 import (
+	"fmt"
 	"github.com/hashicorp/hcl/v2"
+	"github.com/switchboard-org/switchboard/internal"
 	"github.com/zclconf/go-cty/cty"
+	"golang.org/x/exp/slices"
+	"math/big"
+	"time"
 )
 
 // triggerBlockParser is responsible for parsing trigger blocks.
@@ -16,10 +21,111 @@ type triggersStepConfig struct {
 	Remain   hcl.Body        `hcl:",remain"`
 }
 
-// triggerConfig is the configuration for an individual trigger block.
+// triggerConfig is the configuration for an individual trigger block. Schedule triggers set schedule (and
+// optionally timezone and missed_runs), while provider triggers set provider and function, and optionally
+// the schema their payload must match.
 type triggerConfig struct {
-	Name     string    `hcl:"name,label"`
-	Provider string    `hcl:"provider"`
-	Function string    `hcl:"function"`
-	Schema   cty.Value `hcl:"schema"`
+	Name       string  `hcl:"name,label"`
+	Provider   *string `hcl:"provider"`
+	Function   *string `hcl:"function"`
+	Schedule   *string `hcl:"schedule"`
+	Timezone   *string `hcl:"timezone"`
+	MissedRuns *string `hcl:"missed_runs"`
+	// Schema is a reference to a schema block, such as schemas.order
+	Schema *hcl.Attribute `hcl:"schema"`
+	// Remain contains the trigger configuration of provider triggers
+	Remain hcl.Body `hcl:",remain"`
+}
+
+// parse converts the trigger configs into trigger blocks. The providers and schemas of the config must
+// already be parsed, as provider triggers are resolved against them.
+func (p *triggerBlockParser) parse(config *internal.RootSwitchboardConfig) ([]internal.TriggerBlock, hcl.Diagnostics) {
+	var diagnostics hcl.Diagnostics
+	var output []internal.TriggerBlock
+	var names []string
+	for _, trigger := range p.triggerConfigs.Triggers {
+		hclRange := trigger.Remain.MissingItemRange()
+		if slices.Contains(names, trigger.Name) {
+			diagnostics = diagnostics.Append(simpleDiagnostic("duplicate trigger", fmt.Sprintf("trigger '%s' is defined more than once", trigger.Name), &hclRange))
+			continue
+		}
+		names = append(names, trigger.Name)
+		triggerBlock, diag := parseTrigger(trigger, config)
+		diagnostics = diagnostics.Extend(diag)
+		if diag.HasErrors() {
+			continue
+		}
+		output = append(output, triggerBlock)
+	}
+	return output, diagnostics
+}
+
+func parseTrigger(trigger triggerConfig, config *internal.RootSwitchboardConfig) (internal.TriggerBlock, hcl.Diagnostics) {
+	hclRange := trigger.Remain.MissingItemRange()
+	triggerBlock := internal.TriggerBlock{
+		Name: trigger.Name,
+	}
+	isProviderTrigger := trigger.Provider != nil || trigger.Function != nil
+	if trigger.Schedule == nil {
+		if trigger.Timezone != nil || trigger.MissedRuns != nil {
+			return triggerBlock, hcl.Diagnostics{simpleDiagnostic("invalid trigger", fmt.Sprintf("timezone and missed_runs can only be set on schedule triggers, and trigger '%s' has no schedule", trigger.Name), &hclRange)}
+		}
+		if trigger.Provider == nil || trigger.Function == nil {
+			return triggerBlock, hcl.Diagnostics{simpleDiagnostic("invalid trigger", fmt.Sprintf("trigger '%s' must either set a schedule, or a provider and function", trigger.Name), &hclRange)}
+		}
+		if _, ok := config.ProviderPluginName(*trigger.Provider); !ok {
+			return triggerBlock, hcl.Diagnostics{simpleDiagnostic("unknown provider", fmt.Sprintf("trigger '%s' uses provider '%s', which is not a provider or required_provider", trigger.Name, *trigger.Provider), &hclRange)}
+		}
+		triggerBlock.Provider = *trigger.Provider
+		triggerBlock.Function = *trigger.Function
+		if trigger.Schema != nil {
+			schema, diag := parseTriggerSchema(trigger.Schema, config)
+			if diag.HasErrors() {
+				return triggerBlock, diag
+			}
+			triggerBlock.Schema = schema
+		}
+		return triggerBlock, nil
+	}
+	if isProviderTrigger || trigger.Schema != nil {
+		return triggerBlock, hcl.Diagnostics{simpleDiagnostic("invalid trigger", fmt.Sprintf("schedule trigger '%s' can not set a provider, function or schema", trigger.Name), &hclRange)}
+	}
+	location := time.UTC
+	if trigger.Timezone != nil {
+		var err error
+		location, err = time.LoadLocation(*trigger.Timezone)
+		if err != nil {
+			return triggerBlock, hcl.Diagnostics{simpleDiagnostic("invalid 'timezone' value", fmt.Sprintf("trigger '%s' has an unknown timezone: %s", trigger.Name, err), &hclRange)}
+		}
+	}
+	missedRuns := internal.MissedRunsSkip
+	if trigger.MissedRuns != nil {
+		missedRuns = internal.MissedRunPolicy(*trigger.MissedRuns)
+		if missedRuns != internal.MissedRunsSkip && missedRuns != internal.MissedRunsCatchUp {
+			return triggerBlock, hcl.Diagnostics{simpleDiagnostic("invalid 'missed_runs' value", fmt.Sprintf("missed_runs must be '%s' or '%s', got '%s'", internal.MissedRunsSkip, internal.MissedRunsCatchUp, missedRuns), &hclRange)}
+		}
+	}
+	schedule, err := internal.NewScheduleBlock(*trigger.Schedule, location, missedRuns)
+	if err != nil {
+		return triggerBlock, hcl.Diagnostics{simpleDiagnostic("invalid 'schedule' value", fmt.Sprintf("trigger '%s' has an invalid cron expression: %s", trigger.Name, err), &hclRange)}
+	}
+	triggerBlock.Schedule = schedule
+	return triggerBlock, nil
+}
+
+// parseTriggerSchema resolves a schema reference, which evaluates to the index of the schema block
+func parseTriggerSchema(attribute *hcl.Attribute, config *internal.RootSwitchboardConfig) (*internal.SchemaBlock, hcl.Diagnostics) {
+	value, diag := attribute.Expr.Value(config.EvalContext())
+	if diag.HasErrors() {
+		return nil, diag
+	}
+	invalidSchema := hcl.Diagnostics{simpleDiagnostic("invalid 'schema' value", "schema must reference a schema block, such as schemas.order", &attribute.Range)}
+	if value.IsNull() || !value.IsKnown() || !value.Type().Equals(cty.Number) {
+		return nil, invalidSchema
+	}
+	index, accuracy := value.AsBigFloat().Int64()
+	if accuracy != big.Exact || index < 0 || int(index) >= len(config.Schemas) {
+		return nil, invalidSchema
+	}
+	return &config.Schemas[index], nil
 }
@@ -0,0 +1,102 @@
+package parsecfg
+
+import (
+	"github.com/hashicorp/hcl/v2/hclsimple"
+	"github.com/switchboard-org/switchboard/internal"
+	"testing"
+	"time"
+)
+
+func Test_parseTrigger(t *testing.T) {
+	var decodedConfig triggersStepConfig
+	if err := hclsimple.DecodeFile("../fixtures/trigger_config/schedule.hcl", nil, &decodedConfig); err != nil {
+		t.Fatalf("could not decode fixture: %v", err)
+	}
+	config := &internal.RootSwitchboardConfig{
+		Switchboard: internal.SwitchboardBlock{
+			RequiredProviders: []internal.RequiredProviderBlock{{Name: "test"}},
+		},
+	}
+	berlin, _ := time.LoadLocation("Europe/Berlin")
+	tests := []struct {
+		name           string
+		trigger        triggerConfig
+		wantLocation   *time.Location
+		wantMissedRuns internal.MissedRunPolicy
+		wantNext       time.Time
+		wantProvider   string
+		wantDiagCount  int
+	}{
+		{
+			name:           "should parse a schedule in a timezone",
+			trigger:        decodedConfig.Triggers[0],
+			wantLocation:   berlin,
+			wantMissedRuns: internal.MissedRunsCatchUp,
+			wantNext:       time.Date(2023, 3, 2, 2, 0, 0, 0, berlin),
+		},
+		{
+			name:           "should default to utc and skipping missed runs",
+			trigger:        decodedConfig.Triggers[1],
+			wantLocation:   time.UTC,
+			wantMissedRuns: internal.MissedRunsSkip,
+			wantNext:       time.Date(2023, 3, 1, 13, 0, 0, 0, time.UTC),
+		},
+		{
+			name:          "should fail with an invalid cron expression",
+			trigger:       decodedConfig.Triggers[2],
+			wantDiagCount: 1,
+		},
+		{
+			name:          "should fail with an unknown timezone",
+			trigger:       decodedConfig.Triggers[3],
+			wantDiagCount: 1,
+		},
+		{
+			name:          "should fail with an invalid missed run policy",
+			trigger:       decodedConfig.Triggers[4],
+			wantDiagCount: 1,
+		},
+		{
+			name:          "should fail with a schedule and a provider",
+			trigger:       decodedConfig.Triggers[5],
+			wantDiagCount: 1,
+		},
+		{
+			name:         "should parse a provider trigger",
+			trigger:      decodedConfig.Triggers[6],
+			wantProvider: "test",
+		},
+		{
+			name:          "should fail with an unknown provider",
+			trigger:       decodedConfig.Triggers[7],
+			wantDiagCount: 1,
+		},
+	}
+	now := time.Date(2023, 3, 1, 12, 30, 0, 0, time.UTC)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, got1 := parseTrigger(tt.trigger, config)
+			if len(got1.Errs()) != tt.wantDiagCount {
+				t.Fatalf("parseTrigger() error count = %v, want %v: %v", len(got1.Errs()), tt.wantDiagCount, got1)
+			}
+			if tt.wantDiagCount > 0 {
+				return
+			}
+			if got.Provider != tt.wantProvider {
+				t.Errorf("parseTrigger() Provider = %v, want %v", got.Provider, tt.wantProvider)
+			}
+			if tt.wantLocation == nil {
+				if got.Schedule != nil {
+					t.Errorf("parseTrigger() Schedule = %v, want nil", got.Schedule)
+				}
+				return
+			}
+			if got.Schedule.Location.String() != tt.wantLocation.String() || got.Schedule.MissedRuns != tt.wantMissedRuns {
+				t.Errorf("parseTrigger() Schedule = %v, want location %v and missed runs %v", got.Schedule, tt.wantLocation, tt.wantMissedRuns)
+			}
+			if next := got.Schedule.Next(now); !next.Equal(tt.wantNext) {
+				t.Errorf("Next() = %v, want %v", next, tt.wantNext)
+			}
+		})
+	}
+}
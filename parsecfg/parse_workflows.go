@@ -0,0 +1,457 @@
+package parsecfg
+
+import (
+	"fmt"
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hcldec"
+	"github.com/switchboard-org/plugin-sdk/sbsdk"
+	"github.com/switchboard-org/switchboard/internal"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/convert"
+	"golang.org/x/exp/slices"
+	"strings"
+)
+
+// workflowBlocksParser is responsible for parsing workflow blocks and validating their steps against
+// the actions provided by the loaded provider plugins.
+type workflowBlocksParser struct {
+	config        workflowBlocksConfig
+	pluginManager internal.PluginManager
+	// mockProviders accepts any action configuration and output instead of the schemas of the plugins
+	mockProviders bool
+}
+
+type workflowBlocksConfig struct {
+	Workflows []workflowBlockConfig `hcl:"workflow,block"`
+	Remain    hcl.Body              `hcl:",remain"`
+}
+
+type workflowBlockConfig struct {
+	Name      string                `hcl:"name,label"`
+	Trigger   *string               `hcl:"trigger"`
+	Retry     *retryBlockConfig     `hcl:"retry,block"`
+	Steps     []stepBlockConfig     `hcl:"step,block"`
+	OnFailure *onFailureBlockConfig `hcl:"on_failure,block"`
+	Remain    hcl.Body              `hcl:",remain"`
+}
+
+// onFailureBlockConfig contains the steps that run after a workflow run failed
+type onFailureBlockConfig struct {
+	Steps  []stepBlockConfig `hcl:"step,block"`
+	Remain hcl.Body          `hcl:",remain"`
+}
+
+type stepBlockConfig struct {
+	Name        string            `hcl:"name,label"`
+	Provider    string            `hcl:"provider"`
+	Action      string            `hcl:"action"`
+	Retry       *retryBlockConfig `hcl:"retry,block"`
+	OnError     *string           `hcl:"on_error"`
+	Fallback    *hcl.Attribute    `hcl:"fallback"`
+	Condition   *hcl.Attribute    `hcl:"condition"`
+	ForEach     *hcl.Attribute    `hcl:"for_each"`
+	MaxParallel *int              `hcl:"max_parallel"`
+	Idempotent  *bool             `hcl:"idempotent"`
+	// Remain contains the action configuration, which is decoded with the action schema from the provider plugin
+	Remain hcl.Body `hcl:",remain"`
+}
+
+// parse converts the workflow configs into workflow blocks. The config argument must have its switchboard
+// and provider blocks already parsed, as steps are resolved against them.
+func (p *workflowBlocksParser) parse(config *internal.RootSwitchboardConfig, ctx *hcl.EvalContext) ([]internal.WorkflowBlock, hcl.Diagnostics) {
+	var diagnostics hcl.Diagnostics
+	var output []internal.WorkflowBlock
+	globalRetry := internal.DefaultRetryBlock()
+	if config.Switchboard.Retry != nil {
+		globalRetry = *config.Switchboard.Retry
+	}
+	var workflowNames []string
+	for _, workflow := range p.config.Workflows {
+		hclRange := workflow.Remain.MissingItemRange()
+		if slices.Contains(workflowNames, workflow.Name) {
+			diagnostics = diagnostics.Append(simpleDiagnostic("duplicate workflow", fmt.Sprintf("workflow '%s' is defined more than once", workflow.Name), &hclRange))
+			continue
+		}
+		workflowNames = append(workflowNames, workflow.Name)
+		workflowBlock, diag := p.parseWorkflow(workflow, globalRetry, config, ctx)
+		diagnostics = diagnostics.Extend(diag)
+		if diag.HasErrors() {
+			continue
+		}
+		output = append(output, workflowBlock)
+	}
+	return output, diagnostics
+}
+
+func (p *workflowBlocksParser) parseWorkflow(workflow workflowBlockConfig, globalRetry internal.RetryBlock, config *internal.RootSwitchboardConfig, ctx *hcl.EvalContext) (internal.WorkflowBlock, hcl.Diagnostics) {
+	workflowBlock := internal.WorkflowBlock{
+		Name: workflow.Name,
+	}
+	if workflow.Trigger != nil {
+		if config.Trigger(*workflow.Trigger) == nil {
+			hclRange := workflow.Remain.MissingItemRange()
+			return workflowBlock, hcl.Diagnostics{simpleDiagnostic("unknown trigger", fmt.Sprintf("workflow '%s' uses trigger '%s', which does not exist", workflow.Name, *workflow.Trigger), &hclRange)}
+		}
+		workflowBlock.Trigger = *workflow.Trigger
+	}
+	workflowRetry, diag := parseRetryBlock(workflow.Retry, globalRetry)
+	if diag.HasErrors() {
+		return workflowBlock, diag
+	}
+	if workflow.Retry != nil {
+		workflowBlock.Retry = &workflowRetry
+	}
+
+	var onFailureSteps []stepBlockConfig
+	if workflow.OnFailure != nil {
+		onFailureSteps = workflow.OnFailure.Steps
+	}
+	// step outputs are unknown at parse time, but referencing a step that does not exist is still an error
+	stepOutputs := make(map[string]cty.Value)
+	var names []string
+	for _, step := range append(append([]stepBlockConfig{}, workflow.Steps...), onFailureSteps...) {
+		if slices.Contains(names, step.Name) {
+			hclRange := step.Remain.MissingItemRange()
+			diag = diag.Append(simpleDiagnostic("duplicate step", fmt.Sprintf("step '%s' is defined more than once in workflow '%s'", step.Name, workflow.Name), &hclRange))
+		}
+		names = append(names, step.Name)
+		stepOutputs[step.Name] = cty.DynamicVal
+	}
+	if diag.HasErrors() {
+		return workflowBlock, diag
+	}
+	validationContext := internal.StepEvalContext(ctx, cty.DynamicVal, stepOutputs)
+
+	workflowBlock.Steps, diag = p.parseSteps(workflow, workflow.Steps, workflowRetry, config, validationContext)
+	if diag.HasErrors() {
+		return workflowBlock, diag
+	}
+	for _, step := range workflowBlock.Steps {
+		for _, dependency := range step.DependsOn {
+			if workflowBlock.Step(dependency) == nil {
+				hclRange := step.Config.MissingItemRange()
+				diag = diag.Append(simpleDiagnostic("invalid step reference", fmt.Sprintf("step '%s' references '%s', which is an on_failure step", step.Name, dependency), &hclRange))
+			}
+		}
+	}
+	if diag.HasErrors() || workflow.OnFailure == nil {
+		return workflowBlock, diag
+	}
+	workflowBlock.OnFailure, diag = p.parseSteps(workflow, onFailureSteps, workflowRetry, config, unknownErrorEvalContext(validationContext))
+	return workflowBlock, diag
+}
+
+// unknownErrorEvalContext adds an unknown error value to the context, for validating expressions that can
+// reference the error of a failed step
+func unknownErrorEvalContext(ctx *hcl.EvalContext) *hcl.EvalContext {
+	errorContext := ctx.NewChild()
+	errorContext.Variables = map[string]cty.Value{
+		"error": cty.DynamicVal,
+	}
+	return errorContext
+}
+
+// parseSteps parses a list of steps and sorts them by their dependencies. Steps can depend on steps outside
+// the list, which are expected to run before any step in the list.
+func (p *workflowBlocksParser) parseSteps(workflow workflowBlockConfig, stepConfigs []stepBlockConfig, workflowRetry internal.RetryBlock, config *internal.RootSwitchboardConfig, ctx *hcl.EvalContext) ([]internal.StepBlock, hcl.Diagnostics) {
+	var diagnostics hcl.Diagnostics
+	var steps []internal.StepBlock
+	for _, step := range stepConfigs {
+		hclRange := step.Remain.MissingItemRange()
+		stepBlock, diag := p.parseStep(step, workflowRetry, config, ctx)
+		diagnostics = diagnostics.Extend(diag)
+		if diag.HasErrors() {
+			continue
+		}
+		if slices.Contains(stepBlock.DependsOn, step.Name) {
+			diagnostics = diagnostics.Append(simpleDiagnostic("invalid step reference", fmt.Sprintf("step '%s' cannot reference its own output", step.Name), &hclRange))
+		}
+		steps = append(steps, stepBlock)
+	}
+	if diagnostics.HasErrors() {
+		return nil, diagnostics
+	}
+	workflowRange := workflow.Remain.MissingItemRange()
+	sortedSteps, err := sortSteps(steps)
+	if err != nil {
+		return nil, diagnostics.Append(simpleDiagnostic("invalid step references", fmt.Sprintf("workflow '%s': %s", workflow.Name, err), &workflowRange))
+	}
+	for i, step := range sortedSteps {
+		if step.OnError != internal.OnErrorGoto {
+			continue
+		}
+		targetIndex := slices.IndexFunc(sortedSteps, func(target internal.StepBlock) bool {
+			return target.Name == step.OnErrorStep
+		})
+		if targetIndex <= i {
+			hclRange := step.Config.MissingItemRange()
+			diagnostics = diagnostics.Append(simpleDiagnostic(
+				"invalid 'on_error' value",
+				fmt.Sprintf("step '%s' can only go to a step in the same step list that runs after it, and '%s' does not", step.Name, step.OnErrorStep),
+				&hclRange,
+			))
+		}
+	}
+	return sortedSteps, diagnostics
+}
+
+func (p *workflowBlocksParser) parseStep(step stepBlockConfig, workflowRetry internal.RetryBlock, config *internal.RootSwitchboardConfig, ctx *hcl.EvalContext) (internal.StepBlock, hcl.Diagnostics) {
+	var diagnostics hcl.Diagnostics
+	hclRange := step.Remain.MissingItemRange()
+	stepBlock := internal.StepBlock{
+		Name:     step.Name,
+		Provider: step.Provider,
+		Action:   step.Action,
+		Config:   step.Remain,
+	}
+	if step.Idempotent != nil {
+		stepBlock.Idempotent = *step.Idempotent
+	}
+	retryBlock, diag := parseRetryBlock(step.Retry, workflowRetry)
+	if diag.HasErrors() {
+		return stepBlock, diag
+	}
+	stepBlock.Retry = retryBlock
+	stepBlock.OnError, stepBlock.OnErrorStep, diag = parseOnError(step)
+	if diag.HasErrors() {
+		return stepBlock, diag
+	}
+
+	pluginName, ok := config.ProviderPluginName(step.Provider)
+	if !ok {
+		return stepBlock, diagnostics.Append(simpleDiagnostic("unknown provider", fmt.Sprintf("step '%s' references provider '%s', which is not a provider or required_provider block", step.Name, step.Provider), &hclRange))
+	}
+	configSchema, outputType, diag := p.actionSchema(pluginName, step)
+	if diag.HasErrors() {
+		return stepBlock, diagnostics.Extend(diag)
+	}
+	stepBlock.ConfigSchema = configSchema
+	stepBlock.OutputType = outputType
+
+	// the condition is evaluated before for_each, so each is only in scope for the other expressions
+	conditionContext := ctx
+	var traversals []hcl.Traversal
+	if step.ForEach != nil {
+		forEachTraversals, diag := parseForEach(&stepBlock, step, ctx)
+		if diag.HasErrors() {
+			return stepBlock, diagnostics.Extend(diag)
+		}
+		traversals = append(traversals, forEachTraversals...)
+		ctx = unknownEachEvalContext(ctx)
+	} else if step.MaxParallel != nil {
+		return stepBlock, diagnostics.Append(simpleDiagnostic("invalid 'max_parallel' value", fmt.Sprintf("max_parallel can only be set on steps with for_each, which step '%s' does not have", step.Name), &hclRange))
+	}
+
+	spec := configSchema.Decode()
+	_, diag = hcldec.Decode(step.Remain, spec, ctx)
+	if diag.HasErrors() {
+		return stepBlock, diagnostics.Extend(diag)
+	}
+	traversals = append(traversals, hcldec.Variables(step.Remain, spec)...)
+	if step.Condition != nil {
+		stepBlock.Condition = step.Condition.Expr
+		conditionValue, diag := step.Condition.Expr.Value(conditionContext)
+		if diag.HasErrors() {
+			return stepBlock, diagnostics.Extend(diag)
+		}
+		// the type is only known when the condition does not depend on the trigger payload or step outputs
+		if conditionType := conditionValue.Type(); conditionType != cty.DynamicPseudoType && conditionType != cty.Bool {
+			return stepBlock, diagnostics.Append(simpleDiagnostic(
+				"invalid 'condition' value",
+				fmt.Sprintf("condition of step '%s' must be a boolean expression, got %s", step.Name, conditionType.FriendlyName()),
+				&step.Condition.Range,
+			))
+		}
+		traversals = append(traversals, step.Condition.Expr.Variables()...)
+	}
+	if step.Fallback != nil {
+		stepBlock.Fallback = step.Fallback.Expr
+		fallbackValue, diag := step.Fallback.Expr.Value(unknownErrorEvalContext(ctx))
+		if diag.HasErrors() {
+			return stepBlock, diagnostics.Extend(diag)
+		}
+		if _, err := convert.Convert(fallbackValue, stepBlock.OutputType); err != nil {
+			return stepBlock, diagnostics.Append(simpleDiagnostic(
+				"invalid 'fallback' value",
+				fmt.Sprintf("fallback of step '%s' does not match the output type of action '%s': %s", step.Name, step.Action, err),
+				&step.Fallback.Range,
+			))
+		}
+		traversals = append(traversals, step.Fallback.Expr.Variables()...)
+	}
+	stepBlock.DependsOn = stepReferences(traversals)
+	return stepBlock, diagnostics
+}
+
+// actionSchema returns the configuration schema and output type of the action of a step, from its provider plugin
+func (p *workflowBlocksParser) actionSchema(pluginName string, step stepBlockConfig) (sbsdk.ObjectSchema, cty.Type, hcl.Diagnostics) {
+	hclRange := step.Remain.MissingItemRange()
+	if p.mockProviders {
+		return mockActionSchema(step.Remain)
+	}
+	provider, err := p.pluginManager.ProviderInstance(pluginName)
+	if err != nil {
+		return nil, cty.NilType, hcl.Diagnostics{simpleDiagnostic("could not get plugin provider instance", err.Error(), &hclRange)}
+	}
+	actionNames, err := provider.ActionNames()
+	if err != nil {
+		return nil, cty.NilType, hcl.Diagnostics{simpleDiagnostic("could not get actions for provider plugin", err.Error(), &hclRange)}
+	}
+	if !slices.Contains(actionNames, step.Action) {
+		return nil, cty.NilType, hcl.Diagnostics{simpleDiagnostic("unknown action", fmt.Sprintf("provider '%s' has no action '%s'. Available actions: %v", step.Provider, step.Action, actionNames), &hclRange)}
+	}
+	configSchema, err := provider.ActionConfigurationSchema(step.Action)
+	if err != nil {
+		return nil, cty.NilType, hcl.Diagnostics{simpleDiagnostic("could not get schema for action", err.Error(), &hclRange)}
+	}
+	outputType, err := provider.ActionOutputType(step.Action)
+	if err != nil {
+		return nil, cty.NilType, hcl.Diagnostics{simpleDiagnostic("could not get output type for action", err.Error(), &hclRange)}
+	}
+	return configSchema, outputType.ToCty(), nil
+}
+
+// mockActionSchema returns a schema accepting any value for every attribute of an action configuration, and
+// any output, for actions that are mocked instead of validated against their provider plugin
+func mockActionSchema(body hcl.Body) (sbsdk.ObjectSchema, cty.Type, hcl.Diagnostics) {
+	attributes, diag := body.JustAttributes()
+	if diag.HasErrors() {
+		return nil, cty.NilType, diag
+	}
+	schema := sbsdk.ObjectSchema{}
+	for name := range attributes {
+		schema[name] = &anyAttrSchema{Name: name}
+	}
+	return schema, cty.DynamicPseudoType, nil
+}
+
+// anyAttrSchema is an attribute of any type. It is only used in process, as plugins can not decode it.
+type anyAttrSchema struct {
+	Name string
+}
+
+func (s *anyAttrSchema) Decode() hcldec.Spec {
+	return &hcldec.AttrSpec{Name: s.Name, Type: cty.DynamicPseudoType}
+}
+
+// parseForEach validates the for_each and max_parallel settings of a step and sets them on the step block,
+// returning the traversals referenced by for_each
+func parseForEach(stepBlock *internal.StepBlock, step stepBlockConfig, ctx *hcl.EvalContext) ([]hcl.Traversal, hcl.Diagnostics) {
+	forEachValue, diag := step.ForEach.Expr.Value(ctx)
+	if diag.HasErrors() {
+		return nil, diag
+	}
+	forEachType := forEachValue.Type()
+	if forEachType != cty.DynamicPseudoType && !forEachType.IsCollectionType() && !forEachType.IsTupleType() && !forEachType.IsObjectType() {
+		return nil, diag.Append(simpleDiagnostic(
+			"invalid 'for_each' value",
+			fmt.Sprintf("for_each of step '%s' must be a list, set, map or object, got %s", step.Name, forEachType.FriendlyName()),
+			&step.ForEach.Range,
+		))
+	}
+	stepBlock.ForEach = step.ForEach.Expr
+	if step.MaxParallel != nil {
+		if *step.MaxParallel < 1 {
+			hclRange := step.Remain.MissingItemRange()
+			return nil, diag.Append(simpleDiagnostic("invalid 'max_parallel' value", "max_parallel must be at least 1", &hclRange))
+		}
+		stepBlock.MaxParallel = *step.MaxParallel
+	}
+	return step.ForEach.Expr.Variables(), diag
+}
+
+// unknownEachEvalContext adds an unknown each value to the context, for validating expressions of for_each steps
+func unknownEachEvalContext(ctx *hcl.EvalContext) *hcl.EvalContext {
+	eachContext := ctx.NewChild()
+	eachContext.Variables = map[string]cty.Value{
+		"each": cty.DynamicVal,
+	}
+	return eachContext
+}
+
+// parseOnError parses the on_error setting of a step, which is one of "fail", "continue" or "goto:<step>".
+// Steps with a fallback value continue by default, while all other steps fail.
+func parseOnError(step stepBlockConfig) (internal.OnErrorAction, string, hcl.Diagnostics) {
+	var diag hcl.Diagnostics
+	if step.OnError == nil {
+		if step.Fallback != nil {
+			return internal.OnErrorContinue, "", diag
+		}
+		return internal.OnErrorFail, "", diag
+	}
+	switch onError := *step.OnError; onError {
+	case string(internal.OnErrorFail), string(internal.OnErrorContinue):
+		return internal.OnErrorAction(onError), "", diag
+	default:
+		if target, ok := strings.CutPrefix(onError, string(internal.OnErrorGoto)+":"); ok && target != "" {
+			return internal.OnErrorGoto, target, diag
+		}
+	}
+	hclRange := step.Remain.MissingItemRange()
+	return "", "", diag.Append(simpleDiagnostic(
+		"invalid 'on_error' value",
+		fmt.Sprintf("on_error of step '%s' must be \"fail\", \"continue\" or \"goto:<step>\"", step.Name),
+		&hclRange,
+	))
+}
+
+// stepReferences returns the names of all steps referenced through 'steps.<name>' in the traversals
+func stepReferences(traversals []hcl.Traversal) []string {
+	var output []string
+	for _, traversal := range traversals {
+		if traversal.RootName() != "steps" || len(traversal) < 2 {
+			continue
+		}
+		var name string
+		switch step := traversal[1].(type) {
+		case hcl.TraverseAttr:
+			name = step.Name
+		case hcl.TraverseIndex:
+			if step.Key.Type() == cty.String {
+				name = step.Key.AsString()
+			}
+		}
+		if name != "" && !slices.Contains(output, name) {
+			output = append(output, name)
+		}
+	}
+	return output
+}
+
+// sortSteps orders the steps so that every step comes after the steps it depends on, keeping the
+// declared order otherwise. An error is returned if the steps depend on each other in a cycle.
+func sortSteps(steps []internal.StepBlock) ([]internal.StepBlock, error) {
+	var sorted []internal.StepBlock
+	done := make(map[string]bool)
+	for len(sorted) < len(steps) {
+		progressed := false
+		for _, step := range steps {
+			if done[step.Name] {
+				continue
+			}
+			ready := true
+			for _, dependency := range step.DependsOn {
+				// dependencies outside the list have already run
+				if !done[dependency] && slices.ContainsFunc(steps, func(other internal.StepBlock) bool { return other.Name == dependency }) {
+					ready = false
+					break
+				}
+			}
+			if ready {
+				sorted = append(sorted, step)
+				done[step.Name] = true
+				progressed = true
+			}
+		}
+		if !progressed {
+			var remaining []string
+			for _, step := range steps {
+				if !done[step.Name] {
+					remaining = append(remaining, step.Name)
+				}
+			}
+			return nil, fmt.Errorf("steps %v reference each other in a cycle", remaining)
+		}
+	}
+	return sorted, nil
+}
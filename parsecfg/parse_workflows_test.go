@@ -0,0 +1,227 @@
+package parsecfg
+
+import (
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsimple"
+	"github.com/switchboard-org/plugin-sdk/sbsdk"
+	"github.com/switchboard-org/switchboard/internal"
+	"github.com/switchboard-org/switchboard/sbtest"
+	"github.com/zclconf/go-cty/cty"
+	"reflect"
+	"testing"
+)
+
+func Test_sortSteps(t *testing.T) {
+	tests := []struct {
+		name      string
+		steps     []internal.StepBlock
+		wantNames []string
+		wantErr   bool
+	}{
+		{
+			name: "should keep declared order without references",
+			steps: []internal.StepBlock{
+				{Name: "a"},
+				{Name: "b"},
+			},
+			wantNames: []string{"a", "b"},
+		},
+		{
+			name: "should move steps after the steps they reference",
+			steps: []internal.StepBlock{
+				{Name: "notify", DependsOn: []string{"charge"}},
+				{Name: "charge", DependsOn: []string{"customer"}},
+				{Name: "customer"},
+			},
+			wantNames: []string{"customer", "charge", "notify"},
+		},
+		{
+			name: "should fail when steps reference each other",
+			steps: []internal.StepBlock{
+				{Name: "a", DependsOn: []string{"b"}},
+				{Name: "b", DependsOn: []string{"a"}},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := sortSteps(tt.steps)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("sortSteps() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			var gotNames []string
+			for _, step := range got {
+				gotNames = append(gotNames, step.Name)
+			}
+			if !reflect.DeepEqual(gotNames, tt.wantNames) {
+				t.Errorf("sortSteps() got = %v, want %v", gotNames, tt.wantNames)
+			}
+		})
+	}
+}
+
+func Test_parseOnError(t *testing.T) {
+	fallback := &hcl.Attribute{Name: "fallback"}
+	tests := []struct {
+		name          string
+		step          stepBlockConfig
+		want          internal.OnErrorAction
+		wantStep      string
+		wantDiagCount int
+	}{
+		{
+			name: "should fail by default",
+			step: stepBlockConfig{Remain: hcl.EmptyBody()},
+			want: internal.OnErrorFail,
+		},
+		{
+			name: "should continue by default with a fallback",
+			step: stepBlockConfig{Fallback: fallback, Remain: hcl.EmptyBody()},
+			want: internal.OnErrorContinue,
+		},
+		{
+			name: "should use the explicit setting over the fallback default",
+			step: stepBlockConfig{OnError: internal.Ptr("fail"), Fallback: fallback, Remain: hcl.EmptyBody()},
+			want: internal.OnErrorFail,
+		},
+		{
+			name:     "should parse goto target",
+			step:     stepBlockConfig{OnError: internal.Ptr("goto:refund"), Remain: hcl.EmptyBody()},
+			want:     internal.OnErrorGoto,
+			wantStep: "refund",
+		},
+		{
+			name:          "should fail without goto target",
+			step:          stepBlockConfig{OnError: internal.Ptr("goto:"), Remain: hcl.EmptyBody()},
+			wantDiagCount: 1,
+		},
+		{
+			name:          "should fail with unknown value",
+			step:          stepBlockConfig{OnError: internal.Ptr("retry"), Remain: hcl.EmptyBody()},
+			wantDiagCount: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, gotStep, got1 := parseOnError(tt.step)
+			if got != tt.want || gotStep != tt.wantStep {
+				t.Errorf("parseOnError() got = %v, %v, want %v, %v", got, gotStep, tt.want, tt.wantStep)
+			}
+			if len(got1.Errs()) != tt.wantDiagCount {
+				t.Errorf("parseOnError() error count = %v, want %v", len(got1.Errs()), tt.wantDiagCount)
+			}
+		})
+	}
+}
+
+// testPluginManager serves a provider with a single 'echo' action, which is all workflow parsing needs from a plugin
+func testPluginManager(t *testing.T) internal.PluginManager {
+	pluginManager := sbtest.NewFakePluginManager(map[string]sbsdk.Provider{
+		"test": sbtest.NewFakeProvider().WithEchoAction("echo", "message"),
+	})
+	if err := pluginManager.LoadPlugin(internal.RequiredProviderBlock{Name: "test"}); err != nil {
+		t.Fatalf("LoadPlugin() error = %v", err)
+	}
+	return pluginManager
+}
+
+func Test_workflowBlocksParser_parseStep_condition(t *testing.T) {
+	var decodedConfig workflowBlocksConfig
+	if err := hclsimple.DecodeFile("../fixtures/workflow_config/conditions.hcl", nil, &decodedConfig); err != nil {
+		t.Fatalf("could not decode fixture: %v", err)
+	}
+	config := &internal.RootSwitchboardConfig{
+		Switchboard: internal.SwitchboardBlock{
+			RequiredProviders: []internal.RequiredProviderBlock{{Name: "test"}},
+		},
+	}
+	validationContext := internal.StepEvalContext(config.EvalContext(), cty.DynamicVal, map[string]cty.Value{
+		"known": cty.DynamicVal,
+	})
+	tests := []struct {
+		name          string
+		step          stepBlockConfig
+		wantDependsOn []string
+		wantDiagCount int
+	}{
+		{
+			name: "should accept a known boolean condition",
+			step: decodedConfig.Workflows[0].Steps[0],
+		},
+		{
+			name:          "should accept a condition only known at runtime and depend on referenced steps",
+			step:          decodedConfig.Workflows[0].Steps[1],
+			wantDependsOn: []string{"known"},
+		},
+		{
+			name:          "should fail with a condition that is not a boolean",
+			step:          decodedConfig.Workflows[0].Steps[2],
+			wantDiagCount: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &workflowBlocksParser{pluginManager: testPluginManager(t)}
+			got, got1 := p.parseStep(tt.step, internal.DefaultRetryBlock(), config, validationContext)
+			if len(got1.Errs()) != tt.wantDiagCount {
+				t.Errorf("parseStep() error count = %v, want %v", len(got1.Errs()), tt.wantDiagCount)
+			}
+			if tt.wantDiagCount == 0 && !reflect.DeepEqual(got.DependsOn, tt.wantDependsOn) {
+				t.Errorf("parseStep() DependsOn = %v, want %v", got.DependsOn, tt.wantDependsOn)
+			}
+		})
+	}
+}
+
+func Test_workflowBlocksParser_parseStep_forEach(t *testing.T) {
+	var decodedConfig workflowBlocksConfig
+	if err := hclsimple.DecodeFile("../fixtures/workflow_config/for_each.hcl", nil, &decodedConfig); err != nil {
+		t.Fatalf("could not decode fixture: %v", err)
+	}
+	config := &internal.RootSwitchboardConfig{
+		Switchboard: internal.SwitchboardBlock{
+			RequiredProviders: []internal.RequiredProviderBlock{{Name: "test"}},
+		},
+	}
+	validationContext := internal.StepEvalContext(config.EvalContext(), cty.DynamicVal, map[string]cty.Value{})
+	tests := []struct {
+		name            string
+		step            stepBlockConfig
+		wantMaxParallel int
+		wantDiagCount   int
+	}{
+		{
+			name:            "should accept for_each with each in scope",
+			step:            decodedConfig.Workflows[0].Steps[0],
+			wantMaxParallel: 5,
+		},
+		{
+			name:          "should fail with a for_each value that cannot be iterated",
+			step:          decodedConfig.Workflows[0].Steps[1],
+			wantDiagCount: 1,
+		},
+		{
+			name:          "should fail with max_parallel without for_each",
+			step:          decodedConfig.Workflows[0].Steps[2],
+			wantDiagCount: 1,
+		},
+		{
+			name:          "should fail when the condition references each",
+			step:          decodedConfig.Workflows[0].Steps[3],
+			wantDiagCount: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &workflowBlocksParser{pluginManager: testPluginManager(t)}
+			got, got1 := p.parseStep(tt.step, internal.DefaultRetryBlock(), config, validationContext)
+			if len(got1.Errs()) != tt.wantDiagCount {
+				t.Errorf("parseStep() error count = %v, want %v: %v", len(got1.Errs()), tt.wantDiagCount, got1)
+			}
+			if tt.wantDiagCount == 0 && got.MaxParallel != tt.wantMaxParallel {
+				t.Errorf("parseStep() MaxParallel = %v, want %v", got.MaxParallel, tt.wantMaxParallel)
+			}
+		})
+	}
+}
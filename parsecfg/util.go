@@ -1,6 +1,7 @@
 package parsecfg
 
 import (
+	"errors"
 	"github.com/hashicorp/hcl/v2"
 	"github.com/hashicorp/hcl/v2/hclparse"
 	"github.com/zclconf/go-cty/cty"
@@ -9,7 +10,9 @@ import (
 	"io/fs"
 	"log"
 	"os"
+	"path"
 	"path/filepath"
+	"strings"
 )
 
 func findAllFiles(root, ext string) []string {
@@ -29,13 +32,38 @@ func findAllFiles(root, ext string) []string {
 	return allFilePaths
 }
 
+// TestFileSuffix is the file name suffix of workflow test files, which are run by 'switchboard test'
+const TestFileSuffix = ".sbtest.hcl"
+
+// FindTestFiles finds all workflow test files in the directory and any child directories
+func FindTestFiles(root string) []string {
+	var output []string
+	for _, file := range findAllFiles(root, ".hcl") {
+		if strings.HasSuffix(file, TestFileSuffix) {
+			output = append(output, file)
+		}
+	}
+	return output
+}
+
+// FindConfigFiles finds all configuration files in the directory and any child directories, which are
+// the '.hcl' files that are not workflow tests
+func FindConfigFiles(root string) []string {
+	var output []string
+	for _, file := range findAllFiles(root, ".hcl") {
+		if !strings.HasSuffix(file, TestFileSuffix) {
+			output = append(output, file)
+		}
+	}
+	return output
+}
+
 // loadAllHclFilesInDir finds all '.hcl' files in the working
 // directory and any child directories (including deeply nested dirs) and transforms it into a parsed hcl.Body.
 func loadAllHclFilesInDir(path string) (hcl.Body, hcl.Diagnostics) {
 	parser := hclparse.NewParser()
-	allHclFiles := findAllFiles(path, ".hcl")
 	var parsedFiles []*hcl.File
-	for _, file := range allHclFiles {
+	for _, file := range FindConfigFiles(path) {
 		parsedFile, diag := parser.ParseHCLFile(file)
 		if diag.HasErrors() {
 			return nil, diag
@@ -46,6 +74,39 @@ func loadAllHclFilesInDir(path string) (hcl.Body, hcl.Diagnostics) {
 	return hcl.MergeFiles(parsedFiles), nil
 }
 
+// loadAllHclFilesInFS is loadAllHclFilesInDir for the files of a file system, such as an unpacked
+// configuration bundle
+func loadAllHclFilesInFS(fsys fs.FS) (hcl.Body, hcl.Diagnostics) {
+	parser := hclparse.NewParser()
+	var parsedFiles []*hcl.File
+	err := fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || path.Ext(name) != ".hcl" || strings.HasSuffix(name, TestFileSuffix) {
+			return nil
+		}
+		src, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return err
+		}
+		parsedFile, diag := parser.ParseHCL(src, name)
+		if diag.HasErrors() {
+			return diag
+		}
+		parsedFiles = append(parsedFiles, parsedFile)
+		return nil
+	})
+	var diag hcl.Diagnostics
+	if errors.As(err, &diag) {
+		return nil, diag
+	}
+	if err != nil {
+		return nil, hcl.Diagnostics{simpleDiagnostic("could not read configuration files", err.Error(), nil)}
+	}
+	return hcl.MergeFiles(parsedFiles), nil
+}
+
 // getVariableDataFromJSONFile loads a json object file and serializes it into a map of name/value pairs.
 // It does not throw an error if the file doesn't exist, but will instead return an empty map. It throws
 // an error if the file does exist and is not formatted correctly (i.e. not a basic JSON object), or cannot
@@ -4,8 +4,10 @@ import (
 	"fmt"
 	"github.com/hashicorp/hcl/v2/hcldec"
 	"github.com/zclconf/go-cty/cty"
+	"io/fs"
 	"reflect"
 	"testing"
+	"testing/fstest"
 )
 
 func Test_findAllFiles(t *testing.T) {
@@ -36,6 +38,32 @@ func Test_findAllFiles(t *testing.T) {
 	}
 }
 
+func TestFindTestFiles(t *testing.T) {
+	tests := []struct {
+		name string
+		root string
+		want []string
+	}{
+		{
+			name: "finds workflow test files",
+			root: "../fixtures/workflow_tests",
+			want: []string{"../fixtures/workflow_tests/checkout.sbtest.hcl"},
+		},
+		{
+			name: "ignores other hcl files",
+			root: "../fixtures/basic",
+			want: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := FindTestFiles(tt.root); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("FindTestFiles() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
 func Test_getVariableDataFromJSONFile(t *testing.T) {
 	type args struct {
 		varFile string
@@ -120,3 +148,58 @@ func Test_loadAllHclFilesInDir(t *testing.T) {
 		})
 	}
 }
+
+func Test_loadAllHclFilesInFS(t *testing.T) {
+	spec := hcldec.ObjectSpec{
+		"values": &hcldec.BlockListSpec{
+			TypeName: "fake",
+			Nested: hcldec.ObjectSpec{
+				"value": &hcldec.AttrSpec{
+					Name:     "value",
+					Type:     cty.String,
+					Required: true,
+				},
+			},
+		},
+	}
+	tests := []struct {
+		name       string
+		fsys       fs.FS
+		wantBlocks int
+		wantErr    bool
+	}{
+		{
+			name: "load all config files in the file system, except workflow tests",
+			fsys: fstest.MapFS{
+				"main.hcl":            {Data: []byte(`fake { value = "a" }`)},
+				"nested/other.hcl":    {Data: []byte(`fake { value = "b" }`)},
+				"notes.txt":           {Data: []byte(`not hcl`)},
+				"checkout.sbtest.hcl": {Data: []byte(`test "t" {}`)},
+			},
+			wantBlocks: 2,
+		},
+		{
+			name:    "fail for invalid hcl files",
+			fsys:    fstest.MapFS{"main.hcl": {Data: []byte(`fake {`)}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, diag := loadAllHclFilesInFS(tt.fsys)
+			if diag.HasErrors() != tt.wantErr {
+				t.Fatalf("loadAllHclFilesInFS() diagnostics = %v, wantErr %v", diag, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			got, diag := hcldec.Decode(result, spec, nil)
+			if diag.HasErrors() {
+				t.Fatalf("Decode() diagnostics = %v", diag)
+			}
+			if blocks := got.GetAttr("values").LengthInt(); blocks != tt.wantBlocks {
+				t.Errorf("loadAllHclFilesInFS() blocks = %d, want %d", blocks, tt.wantBlocks)
+			}
+		})
+	}
+}
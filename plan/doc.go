@@ -0,0 +1,4 @@
+/*
+Package plan compares configurations, to show what a deploy would change on a server before it is applied.
+*/
+package plan
@@ -0,0 +1,74 @@
+package plan
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// DefaultSnapshotFile is where deploy keeps the snapshot of the last configuration it deployed
+const DefaultSnapshotFile = "./.switchboard/deployed.json"
+
+// File is a saved plan, which deploy applies by uploading its bundle if the server still runs the
+// configuration the plan was made against
+type File struct {
+	Plan *Plan `json:"plan"`
+	// Snapshot is the snapshot of the planned configuration
+	Snapshot *Snapshot `json:"snapshot"`
+	// Bundle is the configuration bundle that is deployed, created with bundle.Pack
+	Bundle []byte `json:"bundle"`
+}
+
+// ReadFile reads a plan file saved with WriteFile
+func ReadFile(path string) (*File, error) {
+	var file File
+	if err := readJSON(path, &file); err != nil {
+		return nil, err
+	}
+	if file.Plan == nil || file.Snapshot == nil || len(file.Bundle) == 0 {
+		return nil, fmt.Errorf("'%s' is not a plan file", path)
+	}
+	return &file, nil
+}
+
+// WriteFile saves a plan file
+func WriteFile(path string, file *File) error {
+	return writeJSON(path, file)
+}
+
+// ReadSnapshot reads a snapshot saved with WriteSnapshot
+func ReadSnapshot(path string) (*Snapshot, error) {
+	var snapshot Snapshot
+	if err := readJSON(path, &snapshot); err != nil {
+		return nil, err
+	}
+	return &snapshot, nil
+}
+
+// WriteSnapshot saves a snapshot, creating its directory if needed
+func WriteSnapshot(path string, snapshot *Snapshot) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return writeJSON(path, snapshot)
+}
+
+func readJSON(path string, out any) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if err = json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("could not decode '%s': %w", path, err)
+	}
+	return nil
+}
+
+func writeJSON(path string, value any) error {
+	data, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
@@ -0,0 +1,130 @@
+package plan
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Kind is the kind of configuration object a change applies to
+type Kind string
+
+const (
+	KindWorkflow Kind = "workflow"
+	KindTrigger  Kind = "trigger"
+	KindProvider Kind = "provider"
+	KindSchema   Kind = "schema"
+)
+
+// Action is what a deploy does to a configuration object
+type Action string
+
+const (
+	ActionCreate Action = "create"
+	ActionUpdate Action = "update"
+	ActionDelete Action = "delete"
+)
+
+// Change is a single change to a configuration object
+type Change struct {
+	Kind   Kind   `json:"kind"`
+	Name   string `json:"name"`
+	Action Action `json:"action"`
+	// Detail describes the effect of the change, such as 'register' for a new trigger
+	Detail string `json:"detail,omitempty"`
+}
+
+// Plan is the list of changes a deploy makes to the configuration of a server
+type Plan struct {
+	// FromHash is the bundle hash of the deployed configuration, empty if nothing is deployed
+	FromHash string `json:"from_hash"`
+	// ToHash is the bundle hash of the configuration being deployed
+	ToHash  string   `json:"to_hash"`
+	Changes []Change `json:"changes"`
+}
+
+// HasChanges returns true if applying the plan changes anything
+func (p *Plan) HasChanges() bool {
+	return p.FromHash != p.ToHash || len(p.Changes) > 0
+}
+
+// Count returns the number of changes of an action
+func (p *Plan) Count(action Action) int {
+	count := 0
+	for _, change := range p.Changes {
+		if change.Action == action {
+			count++
+		}
+	}
+	return count
+}
+
+// Diff returns the plan that changes the configuration of the from snapshot into the to snapshot. A nil
+// from snapshot means nothing is deployed, so everything is created.
+func Diff(from *Snapshot, to *Snapshot) *Plan {
+	if from == nil {
+		from = &Snapshot{}
+	}
+	plan := &Plan{
+		FromHash: from.Hash,
+		ToHash:   to.Hash,
+		Changes:  []Change{},
+	}
+	plan.Changes = append(plan.Changes, diffProviders(from.Providers, to.Providers)...)
+	plan.Changes = append(plan.Changes, diffFingerprints(KindSchema, from.Schemas, to.Schemas, "", "")...)
+	plan.Changes = append(plan.Changes, diffFingerprints(KindTrigger, from.Triggers, to.Triggers, "register", "deregister")...)
+	plan.Changes = append(plan.Changes, diffFingerprints(KindWorkflow, from.Workflows, to.Workflows, "", "")...)
+	return plan
+}
+
+func diffFingerprints(kind Kind, from map[string]string, to map[string]string, createDetail string, deleteDetail string) []Change {
+	var changes []Change
+	for _, name := range sortedNames(from, to) {
+		fromFingerprint, inFrom := from[name]
+		toFingerprint, inTo := to[name]
+		switch {
+		case !inFrom:
+			changes = append(changes, Change{Kind: kind, Name: name, Action: ActionCreate, Detail: createDetail})
+		case !inTo:
+			changes = append(changes, Change{Kind: kind, Name: name, Action: ActionDelete, Detail: deleteDetail})
+		case fromFingerprint != toFingerprint:
+			changes = append(changes, Change{Kind: kind, Name: name, Action: ActionUpdate})
+		}
+	}
+	return changes
+}
+
+func diffProviders(from map[string]ProviderSnapshot, to map[string]ProviderSnapshot) []Change {
+	var changes []Change
+	for _, name := range sortedNames(from, to) {
+		fromProvider, inFrom := from[name]
+		toProvider, inTo := to[name]
+		switch {
+		case !inFrom:
+			changes = append(changes, Change{Kind: KindProvider, Name: name, Action: ActionCreate, Detail: fmt.Sprintf("start %s %s", toProvider.Source, toProvider.Version)})
+		case !inTo:
+			changes = append(changes, Change{Kind: KindProvider, Name: name, Action: ActionDelete, Detail: "stop"})
+		case fromProvider.Source != toProvider.Source:
+			changes = append(changes, Change{Kind: KindProvider, Name: name, Action: ActionUpdate, Detail: fmt.Sprintf("replace %s with %s", fromProvider.Source, toProvider.Source)})
+		case fromProvider.Version != toProvider.Version:
+			changes = append(changes, Change{Kind: KindProvider, Name: name, Action: ActionUpdate, Detail: fmt.Sprintf("upgrade %s to %s", fromProvider.Version, toProvider.Version)})
+		case fromProvider.Fingerprint != toProvider.Fingerprint:
+			changes = append(changes, Change{Kind: KindProvider, Name: name, Action: ActionUpdate, Detail: "reconfigure"})
+		}
+	}
+	return changes
+}
+
+// sortedNames returns the keys of both maps, sorted and without duplicates
+func sortedNames[T any](from map[string]T, to map[string]T) []string {
+	var names []string
+	for name := range from {
+		names = append(names, name)
+	}
+	for name := range to {
+		if _, ok := from[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
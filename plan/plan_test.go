@@ -0,0 +1,77 @@
+package plan
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	from := &Snapshot{
+		Hash:      "from",
+		Workflows: map[string]string{"charge": "a", "refund": "b", "old": "c"},
+		Triggers:  map[string]string{"nightly": "a", "webhook": "b"},
+		Schemas:   map[string]string{"charge": "a"},
+		Providers: map[string]ProviderSnapshot{
+			"stripe": {Source: "github.com/switchboard-org/provider-stripe", Version: "1.0.0", Fingerprint: "a"},
+			"slack":  {Source: "github.com/switchboard-org/provider-slack", Version: "1.0.0", Fingerprint: "a"},
+			"email":  {Source: "github.com/switchboard-org/provider-email", Version: "1.0.0", Fingerprint: "a"},
+		},
+	}
+	to := &Snapshot{
+		Hash:      "to",
+		Workflows: map[string]string{"charge": "a", "refund": "changed", "new": "d"},
+		Triggers:  map[string]string{"nightly": "a", "hourly": "c"},
+		Schemas:   map[string]string{"charge": "changed"},
+		Providers: map[string]ProviderSnapshot{
+			"stripe": {Source: "github.com/switchboard-org/provider-stripe", Version: "1.1.0", Fingerprint: "a"},
+			"slack":  {Source: "github.com/switchboard-org/provider-slack", Version: "1.0.0", Fingerprint: "changed"},
+			"sms":    {Source: "github.com/switchboard-org/provider-sms", Version: "2.0.0"},
+		},
+	}
+	tests := []struct {
+		name string
+		from *Snapshot
+		to   *Snapshot
+		want *Plan
+	}{
+		{
+			name: "should create everything when nothing is deployed",
+			from: nil,
+			to:   &Snapshot{Hash: "to", Workflows: map[string]string{"charge": "a"}, Triggers: map[string]string{"nightly": "a"}},
+			want: &Plan{FromHash: "", ToHash: "to", Changes: []Change{
+				{Kind: KindTrigger, Name: "nightly", Action: ActionCreate, Detail: "register"},
+				{Kind: KindWorkflow, Name: "charge", Action: ActionCreate},
+			}},
+		},
+		{
+			name: "should have no changes for the same snapshot",
+			from: to,
+			to:   to,
+			want: &Plan{FromHash: "to", ToHash: "to", Changes: []Change{}},
+		},
+		{
+			name: "should list created, updated and deleted objects",
+			from: from,
+			to:   to,
+			want: &Plan{FromHash: "from", ToHash: "to", Changes: []Change{
+				{Kind: KindProvider, Name: "email", Action: ActionDelete, Detail: "stop"},
+				{Kind: KindProvider, Name: "slack", Action: ActionUpdate, Detail: "reconfigure"},
+				{Kind: KindProvider, Name: "sms", Action: ActionCreate, Detail: "start github.com/switchboard-org/provider-sms 2.0.0"},
+				{Kind: KindProvider, Name: "stripe", Action: ActionUpdate, Detail: "upgrade 1.0.0 to 1.1.0"},
+				{Kind: KindSchema, Name: "charge", Action: ActionUpdate},
+				{Kind: KindTrigger, Name: "hourly", Action: ActionCreate, Detail: "register"},
+				{Kind: KindTrigger, Name: "webhook", Action: ActionDelete, Detail: "deregister"},
+				{Kind: KindWorkflow, Name: "new", Action: ActionCreate},
+				{Kind: KindWorkflow, Name: "old", Action: ActionDelete},
+				{Kind: KindWorkflow, Name: "refund", Action: ActionUpdate},
+			}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Diff(tt.from, tt.to); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Diff() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
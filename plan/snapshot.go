@@ -0,0 +1,122 @@
+package plan
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/switchboard-org/switchboard/bundle"
+	"github.com/switchboard-org/switchboard/internal"
+	"github.com/switchboard-org/switchboard/parsecfg"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+)
+
+// Snapshot is the part of a configuration that plans compare. Blocks are compared by fingerprint, the hash
+// of their formatted source, so changes to whitespace and formatting are not changes.
+type Snapshot struct {
+	// Hash is the content hash of the configuration bundle
+	Hash string `json:"hash"`
+	// Workflows, Triggers and Schemas map the name of each block to its fingerprint
+	Workflows map[string]string `json:"workflows"`
+	Triggers  map[string]string `json:"triggers"`
+	Schemas   map[string]string `json:"schemas"`
+	// Providers are keyed by the name of the required_provider
+	Providers map[string]ProviderSnapshot `json:"providers"`
+}
+
+// ProviderSnapshot is a required provider, along with the provider blocks that configure it
+type ProviderSnapshot struct {
+	Source  string `json:"source"`
+	Version string `json:"version"`
+	// Fingerprint is the combined fingerprint of the provider blocks of the plugin, empty if it has none
+	Fingerprint string `json:"fingerprint"`
+}
+
+// NewSnapshot creates the snapshot of a parsed configuration, which was parsed from the files of fsys
+func NewSnapshot(config *internal.RootSwitchboardConfig, fsys fs.FS) (*Snapshot, error) {
+	hash, err := bundle.Hash(fsys)
+	if err != nil {
+		return nil, err
+	}
+	fingerprints, err := blockFingerprints(fsys)
+	if err != nil {
+		return nil, err
+	}
+	snapshot := &Snapshot{
+		Hash:      hash,
+		Workflows: make(map[string]string),
+		Triggers:  make(map[string]string),
+		Schemas:   make(map[string]string),
+		Providers: make(map[string]ProviderSnapshot),
+	}
+	for _, workflow := range config.Workflows {
+		snapshot.Workflows[workflow.Name] = fingerprints[blockKey("workflow", workflow.Name)]
+	}
+	for _, trigger := range config.Triggers {
+		snapshot.Triggers[trigger.Name] = fingerprints[blockKey("trigger", trigger.Name)]
+	}
+	for _, schema := range config.Schemas {
+		snapshot.Schemas[schema.Name] = fingerprints[blockKey("schema", schema.Name)]
+	}
+	providerFingerprints := make(map[string][]string)
+	for _, provider := range config.Providers {
+		pluginName, _ := config.ProviderPluginName(provider.BlockName)
+		providerFingerprints[pluginName] = append(providerFingerprints[pluginName], fingerprints[blockKey("provider", provider.BlockName)])
+	}
+	for _, requiredProvider := range config.Switchboard.RequiredProviders {
+		provider := ProviderSnapshot{
+			Source:  requiredProvider.Source,
+			Version: requiredProvider.Version,
+		}
+		if blocks := providerFingerprints[requiredProvider.Name]; len(blocks) > 0 {
+			sort.Strings(blocks)
+			provider.Fingerprint = fingerprint([]byte(strings.Join(blocks, ",")))
+		}
+		snapshot.Providers[requiredProvider.Name] = provider
+	}
+	return snapshot, nil
+}
+
+func blockKey(blockType string, name string) string {
+	return blockType + "." + name
+}
+
+// blockFingerprints returns the fingerprint of every labeled root block in the configuration files of fsys,
+// keyed by block type and label, such as 'workflow.charge'
+func blockFingerprints(fsys fs.FS) (map[string]string, error) {
+	output := make(map[string]string)
+	err := fs.WalkDir(fsys, ".", func(name string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() || path.Ext(name) != ".hcl" || strings.HasSuffix(name, parsecfg.TestFileSuffix) {
+			return nil
+		}
+		src, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return err
+		}
+		file, diag := hclsyntax.ParseConfig(src, name, hcl.InitialPos)
+		if diag.HasErrors() {
+			return fmt.Errorf("could not parse '%s': %w", name, diag)
+		}
+		for _, block := range file.Body.(*hclsyntax.Body).Blocks {
+			if len(block.Labels) != 1 {
+				continue
+			}
+			output[blockKey(block.Type, block.Labels[0])] = fingerprint(hclwrite.Format(block.Range().SliceBytes(src)))
+		}
+		return nil
+	})
+	return output, err
+}
+
+func fingerprint(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,101 @@
+package plan
+
+import (
+	"github.com/switchboard-org/switchboard/internal"
+	"testing"
+	"testing/fstest"
+)
+
+const testMain = `switchboard {
+  required_provider "stripe" {
+    version = "1.0.0"
+    source  = "github.com/switchboard-org/provider-stripe"
+  }
+}
+
+provider "stripe" {
+  api_key = "sk_test"
+}
+
+trigger "nightly" {
+  schedule = "0 2 * * *"
+}
+
+workflow "charge" {
+  trigger = "nightly"
+}
+`
+
+func testConfig() *internal.RootSwitchboardConfig {
+	return &internal.RootSwitchboardConfig{
+		Switchboard: internal.SwitchboardBlock{
+			RequiredProviders: []internal.RequiredProviderBlock{{Name: "stripe", Source: "github.com/switchboard-org/provider-stripe", Version: "1.0.0"}},
+		},
+		Providers: []internal.ProviderBlock{{BlockName: "stripe", ProviderName: "stripe"}},
+		Triggers:  []internal.TriggerBlock{{Name: "nightly"}},
+		Workflows: []internal.WorkflowBlock{{Name: "charge"}},
+	}
+}
+
+func TestNewSnapshot(t *testing.T) {
+	base, err := NewSnapshot(testConfig(), fstest.MapFS{"main.hcl": {Data: []byte(testMain)}})
+	if err != nil {
+		t.Fatalf("NewSnapshot() error = %v", err)
+	}
+	if base.Hash == "" || base.Workflows["charge"] == "" || base.Triggers["nightly"] == "" || base.Providers["stripe"].Fingerprint == "" {
+		t.Fatalf("NewSnapshot() = %v, want hash and fingerprints of every block", base)
+	}
+	tests := []struct {
+		name            string
+		files           fstest.MapFS
+		wantSameHash    bool
+		wantSameTrigger bool
+		wantSameFlow    bool
+	}{
+		{
+			name:            "should not change anything for the same files",
+			files:           fstest.MapFS{"main.hcl": {Data: []byte(testMain)}},
+			wantSameHash:    true,
+			wantSameTrigger: true,
+			wantSameFlow:    true,
+		},
+		{
+			name:            "should keep fingerprints when only formatting changes",
+			files:           fstest.MapFS{"main.hcl": {Data: []byte(testMain + "\n\n")}, "other.txt": {Data: []byte("ignored")}},
+			wantSameHash:    false,
+			wantSameTrigger: true,
+			wantSameFlow:    true,
+		},
+		{
+			name:            "should ignore test files",
+			files:           fstest.MapFS{"main.hcl": {Data: []byte(testMain)}, "charge_test.hcl": {Data: []byte("workflow \"charge\" {\n}\n")}},
+			wantSameHash:    false,
+			wantSameTrigger: true,
+			wantSameFlow:    true,
+		},
+		{
+			name:            "should change the fingerprint of a changed block",
+			files:           fstest.MapFS{"main.hcl": {Data: []byte(testMain[:len(testMain)-len("}\n")] + "  idempotent = true\n}\n")}},
+			wantSameHash:    false,
+			wantSameTrigger: true,
+			wantSameFlow:    false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewSnapshot(testConfig(), tt.files)
+			if err != nil {
+				t.Fatalf("NewSnapshot() error = %v", err)
+			}
+			if (got.Hash == base.Hash) != tt.wantSameHash {
+				t.Errorf("NewSnapshot() hash = %s, base hash = %s, want same %v", got.Hash, base.Hash, tt.wantSameHash)
+			}
+			if (got.Triggers["nightly"] == base.Triggers["nightly"]) != tt.wantSameTrigger {
+				t.Errorf("NewSnapshot() trigger = %s, base trigger = %s, want same %v", got.Triggers["nightly"], base.Triggers["nightly"], tt.wantSameTrigger)
+			}
+			if (got.Workflows["charge"] == base.Workflows["charge"]) != tt.wantSameFlow {
+				t.Errorf("NewSnapshot() workflow = %s, base workflow = %s, want same %v", got.Workflows["charge"], base.Workflows["charge"], tt.wantSameFlow)
+			}
+		})
+	}
+}
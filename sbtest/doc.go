@@ -0,0 +1,6 @@
+/*
+Package sbtest provides fakes for testing code that parses or runs switchboard configurations without
+provider binaries: a configurable in-process provider, a plugin manager serving it, and a provider
+downloader and os manager that do not touch the network or file system.
+*/
+package sbtest
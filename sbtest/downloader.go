@@ -0,0 +1,69 @@
+package sbtest
+
+import (
+	"github.com/switchboard-org/switchboard/internal"
+	"github.com/switchboard-org/switchboard/providers"
+	"golang.org/x/exp/slices"
+	"sync"
+)
+
+// FakeDownloader is a providers.Downloader that records downloads instead of fetching packages
+type FakeDownloader struct {
+	mu       sync.Mutex
+	packages []providers.Package
+	err      error
+}
+
+// NewFakeDownloader creates a downloader with the given packages already downloaded
+func NewFakeDownloader(packages ...providers.Package) *FakeDownloader {
+	return &FakeDownloader{packages: packages}
+}
+
+// WithError makes every download fail with err
+func (d *FakeDownloader) WithError(err error) *FakeDownloader {
+	d.err = err
+	return d
+}
+
+func (d *FakeDownloader) DownloadedProviders() ([]providers.Package, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return slices.Clone(d.packages), nil
+}
+
+func (d *FakeDownloader) DownloadProvider(source string, version string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.err != nil {
+		return d.err
+	}
+	d.packages = append(d.packages, providers.Package{Name: internal.PackageName(source), Version: version})
+	return nil
+}
+
+// FakeOsManager is an internal.OsManager that records directories instead of creating them
+type FakeOsManager struct {
+	WorkingDirectory string
+	mu               sync.Mutex
+	directories      []string
+}
+
+func (m *FakeOsManager) GetCurrentWorkingDirectory() string {
+	return m.WorkingDirectory
+}
+
+func (m *FakeOsManager) CreateDirectoryIfNotExists(path string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if !slices.Contains(m.directories, path) {
+		m.directories = append(m.directories, path)
+	}
+	return nil
+}
+
+// Directories returns the directories that were created
+func (m *FakeOsManager) Directories() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return slices.Clone(m.directories)
+}
@@ -0,0 +1,111 @@
+package sbtest
+
+import (
+	"errors"
+	"fmt"
+	"github.com/hashicorp/go-plugin"
+	"github.com/switchboard-org/plugin-sdk/sbsdk"
+	"github.com/switchboard-org/switchboard/internal"
+	"golang.org/x/exp/slices"
+	"sync"
+)
+
+// FakePluginManager is an internal.PluginManager that serves fake providers by required provider name,
+// without starting any plugin process
+type FakePluginManager struct {
+	mu        sync.Mutex
+	providers map[string]sbsdk.Provider
+	loadErrs  map[string]error
+	loaded    []string
+}
+
+// NewFakePluginManager creates a plugin manager serving the given providers, keyed by required provider name
+func NewFakePluginManager(providers map[string]sbsdk.Provider) *FakePluginManager {
+	pm := &FakePluginManager{
+		providers: make(map[string]sbsdk.Provider),
+		loadErrs:  make(map[string]error),
+	}
+	for name, provider := range providers {
+		pm.providers[name] = provider
+	}
+	return pm
+}
+
+// WithProvider adds a provider, replacing any provider with the same name
+func (pm *FakePluginManager) WithProvider(name string, provider sbsdk.Provider) *FakePluginManager {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+	pm.providers[name] = provider
+	return pm
+}
+
+// WithLoadError makes loading the named provider fail with err
+func (pm *FakePluginManager) WithLoadError(name string, err error) *FakePluginManager {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+	pm.loadErrs[name] = err
+	return pm
+}
+
+func (pm *FakePluginManager) LoadPlugin(provider internal.RequiredProviderBlock) error {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+	if err := pm.loadErrs[provider.Name]; err != nil {
+		return err
+	}
+	if _, ok := pm.providers[provider.Name]; !ok {
+		return fmt.Errorf("no fake provider named '%s'", provider.Name)
+	}
+	if slices.Contains(pm.loaded, provider.Name) {
+		return errors.New("plugin is already loaded")
+	}
+	pm.loaded = append(pm.loaded, provider.Name)
+	return nil
+}
+
+func (pm *FakePluginManager) PluginClient(_ string) (*plugin.Client, error) {
+	return nil, errors.New("fake providers have no plugin client")
+}
+
+func (pm *FakePluginManager) ProviderInstance(name string) (sbsdk.Provider, error) {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+	if !slices.Contains(pm.loaded, name) {
+		return nil, fmt.Errorf("plugin '%s' is not loaded", name)
+	}
+	return pm.providers[name], nil
+}
+
+func (pm *FakePluginManager) KillPlugin(name string) error {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+	index := slices.Index(pm.loaded, name)
+	if index < 0 {
+		return fmt.Errorf("plugin '%s' is not loaded", name)
+	}
+	pm.loaded = slices.Delete(pm.loaded, index, index+1)
+	return nil
+}
+
+func (pm *FakePluginManager) KillAllPlugins() {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+	pm.loaded = nil
+}
+
+func (pm *FakePluginManager) LoadedPlugins() []string {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+	return slices.Clone(pm.loaded)
+}
+
+// Restarts reports no restarts, as fake plugins never exit
+func (pm *FakePluginManager) Restarts() map[string]int {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+	restarts := make(map[string]int)
+	for _, name := range pm.loaded {
+		restarts[name] = 0
+	}
+	return restarts
+}
@@ -0,0 +1,186 @@
+package sbtest
+
+import (
+	"errors"
+	"fmt"
+	"github.com/switchboard-org/plugin-sdk/sbsdk"
+	"sort"
+	"sync"
+)
+
+// FakeAction is an action of a FakeProvider
+type FakeAction struct {
+	ConfigSchema sbsdk.ObjectSchema
+	OutputType   sbsdk.Type
+	// Evaluate returns the output for the marshalled action input. The input is returned as output if it is nil
+	Evaluate func(input []byte) ([]byte, error)
+	// Failures is the number of calls that fail with Err before the action succeeds. A negative number
+	// fails every call
+	Failures int
+	Err      error
+}
+
+// FakeProvider is a configurable in-process sbsdk.Provider. Use NewFakeProvider to create one.
+type FakeProvider struct {
+	mu         sync.Mutex
+	initSchema sbsdk.ObjectSchema
+	initErr    error
+	schemaErr  error
+	actions    map[string]*FakeAction
+	config     []byte
+	calls      map[string]int
+}
+
+// NewFakeProvider creates a provider without init settings or actions
+func NewFakeProvider() *FakeProvider {
+	return &FakeProvider{
+		initSchema: sbsdk.ObjectSchema{},
+		actions:    make(map[string]*FakeAction),
+		calls:      make(map[string]int),
+	}
+}
+
+// WithInitSchema sets the schema of the provider block settings
+func (p *FakeProvider) WithInitSchema(schema sbsdk.ObjectSchema) *FakeProvider {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.initSchema = schema
+	return p
+}
+
+// WithInitError makes Init fail with err
+func (p *FakeProvider) WithInitError(err error) *FakeProvider {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.initErr = err
+	return p
+}
+
+// WithSchemaError makes every schema and type method fail with err, as a broken plugin would
+func (p *FakeProvider) WithSchemaError(err error) *FakeProvider {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.schemaErr = err
+	return p
+}
+
+// WithAction adds an action to the provider, replacing any action with the same name
+func (p *FakeProvider) WithAction(name string, action FakeAction) *FakeProvider {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.actions[name] = &action
+	return p
+}
+
+// WithEchoAction adds an action that returns its input, with the given string attributes as both its
+// configuration and output
+func (p *FakeProvider) WithEchoAction(name string, attributes ...string) *FakeProvider {
+	schema := sbsdk.ObjectSchema{}
+	outputType := make(map[string]sbsdk.Type)
+	for _, attribute := range attributes {
+		schema[attribute] = sbsdk.RequiredAttrSchema(attribute, sbsdk.String)
+		outputType[attribute] = sbsdk.String
+	}
+	return p.WithAction(name, FakeAction{ConfigSchema: schema, OutputType: sbsdk.Object(outputType)})
+}
+
+// Calls returns the number of times an action was evaluated
+func (p *FakeProvider) Calls(action string) int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.calls[action]
+}
+
+// Config returns the marshalled settings the provider was initialized with, or nil if Init was not called
+func (p *FakeProvider) Config() []byte {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.config
+}
+
+func (p *FakeProvider) Init(config []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.initErr != nil {
+		return p.initErr
+	}
+	p.config = config
+	return nil
+}
+
+func (p *FakeProvider) InitSchema() (sbsdk.ObjectSchema, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.schemaErr != nil {
+		return nil, p.schemaErr
+	}
+	return p.initSchema, nil
+}
+
+func (p *FakeProvider) ActionNames() ([]string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.schemaErr != nil {
+		return nil, p.schemaErr
+	}
+	var names []string
+	for name := range p.actions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+func (p *FakeProvider) ActionEvaluate(name string, _ []byte, input []byte) ([]byte, error) {
+	p.mu.Lock()
+	action, ok := p.actions[name]
+	if !ok {
+		p.mu.Unlock()
+		return nil, fmt.Errorf("action '%s' does not exist", name)
+	}
+	p.calls[name]++
+	failing := action.Failures != 0
+	if action.Failures > 0 {
+		action.Failures--
+	}
+	p.mu.Unlock()
+	if failing {
+		if action.Err == nil {
+			return nil, errors.New("action failed")
+		}
+		return nil, action.Err
+	}
+	if action.Evaluate == nil {
+		return input, nil
+	}
+	return action.Evaluate(input)
+}
+
+func (p *FakeProvider) ActionConfigurationSchema(name string) (sbsdk.ObjectSchema, error) {
+	action, err := p.action(name)
+	if err != nil {
+		return nil, err
+	}
+	return action.ConfigSchema, nil
+}
+
+func (p *FakeProvider) ActionOutputType(name string) (sbsdk.Type, error) {
+	action, err := p.action(name)
+	if err != nil {
+		return sbsdk.Type{}, err
+	}
+	return action.OutputType, nil
+}
+
+func (p *FakeProvider) action(name string) (*FakeAction, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.schemaErr != nil {
+		return nil, p.schemaErr
+	}
+	action, ok := p.actions[name]
+	if !ok {
+		return nil, fmt.Errorf("action '%s' does not exist", name)
+	}
+	return action, nil
+}
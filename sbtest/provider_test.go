@@ -0,0 +1,79 @@
+package sbtest
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func TestFakeProvider_ActionEvaluate(t *testing.T) {
+	errDeclined := errors.New("card declined")
+	tests := []struct {
+		name     string
+		action   FakeAction
+		calls    int
+		wantErrs []error
+	}{
+		{
+			name:     "echoes the input",
+			action:   FakeAction{},
+			calls:    1,
+			wantErrs: []error{nil},
+		},
+		{
+			name:     "fails a number of times before succeeding",
+			action:   FakeAction{Failures: 2, Err: errDeclined},
+			calls:    3,
+			wantErrs: []error{errDeclined, errDeclined, nil},
+		},
+		{
+			name:     "always fails with negative failures",
+			action:   FakeAction{Failures: -1, Err: errDeclined},
+			calls:    3,
+			wantErrs: []error{errDeclined, errDeclined, errDeclined},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := NewFakeProvider().WithAction("charge", tt.action)
+			for i := 0; i < tt.calls; i++ {
+				output, err := provider.ActionEvaluate("charge", nil, []byte(`{"amount":"10"}`))
+				if !errors.Is(err, tt.wantErrs[i]) {
+					t.Errorf("ActionEvaluate() call %d error = %v, want %v", i+1, err, tt.wantErrs[i])
+				}
+				if err == nil && string(output) != `{"amount":"10"}` {
+					t.Errorf("ActionEvaluate() call %d output = %s", i+1, output)
+				}
+			}
+			if provider.Calls("charge") != tt.calls {
+				t.Errorf("Calls() = %v, want %v", provider.Calls("charge"), tt.calls)
+			}
+		})
+	}
+}
+
+func TestFakeProvider_concurrentActions(t *testing.T) {
+	provider := NewFakeProvider().WithEchoAction("charge", "amount")
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			provider.WithEchoAction(fmt.Sprintf("refund_%d", i), "amount")
+		}(i)
+		go func() {
+			defer wg.Done()
+			if _, err := provider.ActionNames(); err != nil {
+				t.Errorf("ActionNames() error = %v", err)
+			}
+			if _, err := provider.ActionEvaluate("charge", nil, []byte(`{"amount":"10"}`)); err != nil {
+				t.Errorf("ActionEvaluate() error = %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+	if names, _ := provider.ActionNames(); len(names) != 11 {
+		t.Errorf("ActionNames() = %v, want 11 actions", names)
+	}
+}
@@ -0,0 +1,231 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/gofiber/fiber/v2"
+	"github.com/switchboard-org/switchboard/api"
+	"github.com/switchboard-org/switchboard/internal"
+	"github.com/switchboard-org/switchboard/state"
+	"time"
+)
+
+// triggerPath is the path provider triggers receive their requests on, followed by the trigger name
+const triggerPath = "/api/triggers/"
+
+// maxRunsLimit is the largest number of runs returned by the runs endpoint
+const maxRunsLimit = 500
+
+func newDeploymentResponse(r *runtime) api.Deployment {
+	response := api.Deployment{
+		Local:     r.deployment == nil,
+		Workflows: []string{},
+		Triggers:  []string{},
+	}
+	if r.deployment != nil {
+		response.ID = r.deployment.ID
+		response.DeployedAt = r.deployment.DeployedAt
+		response.DeployedBy = r.deployment.DeployedBy
+		response.Hash = r.deployment.Hash
+	}
+	for _, workflow := range r.config.Workflows {
+		response.Workflows = append(response.Workflows, workflow.Name)
+	}
+	for _, trigger := range r.config.Triggers {
+		response.Triggers = append(response.Triggers, trigger.Name)
+	}
+	return response
+}
+
+// runningOrNotFound returns the running configuration, or responds with an error if nothing is deployed
+func (s *Server) runningOrNotFound(c *fiber.Ctx) (*runtime, error) {
+	current := s.running()
+	if current == nil {
+		return nil, c.Status(fiber.StatusNotFound).JSON(api.Error{Error: "nothing is deployed"})
+	}
+	return current, nil
+}
+
+func (s *Server) getDeployment(c *fiber.Ctx) error {
+	current, err := s.runningOrNotFound(c)
+	if current == nil {
+		return err
+	}
+	return c.JSON(newDeploymentResponse(current))
+}
+
+// getSnapshot returns the snapshot of the running configuration, which plans compare local configurations with
+func (s *Server) getSnapshot(c *fiber.Ctx) error {
+	current, err := s.runningOrNotFound(c)
+	if current == nil {
+		return err
+	}
+	if current.snapshot == nil {
+		return c.Status(fiber.StatusNotFound).JSON(api.Error{Error: "the files of the running configuration are not known"})
+	}
+	return c.JSON(current.snapshot)
+}
+
+func (s *Server) listTriggers(c *fiber.Ctx) error {
+	current, err := s.runningOrNotFound(c)
+	if current == nil {
+		return err
+	}
+	registrations, err := s.config.Store.TriggerRegistrations()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(api.Error{Error: err.Error()})
+	}
+	response := make([]api.Trigger, 0, len(current.config.Triggers))
+	for _, trigger := range current.config.Triggers {
+		item := api.Trigger{
+			Name:      trigger.Name,
+			Type:      "provider",
+			Provider:  trigger.Provider,
+			Function:  trigger.Function,
+			Workflows: current.config.TriggeredWorkflows(trigger.Name),
+		}
+		if item.Workflows == nil {
+			item.Workflows = []string{}
+		}
+		if trigger.Schedule != nil {
+			item.Type = "schedule"
+			item.Schedule = &api.Schedule{
+				Expression: trigger.Schedule.Expression,
+				Timezone:   trigger.Schedule.Location.String(),
+				MissedRuns: string(trigger.Schedule.MissedRuns),
+				NextRunAt:  trigger.Schedule.Next(time.Now()),
+			}
+		} else {
+			item.URL = c.BaseURL() + triggerPath + trigger.Name
+		}
+		for _, registration := range registrations {
+			if registration.Trigger == trigger.Name {
+				item.Registration = &api.Registration{ExternalID: registration.ExternalID, RegisteredAt: registration.RegisteredAt}
+			}
+		}
+		response = append(response, item)
+	}
+	return c.JSON(response)
+}
+
+func (s *Server) listWorkflows(c *fiber.Ctx) error {
+	current, err := s.runningOrNotFound(c)
+	if current == nil {
+		return err
+	}
+	response := make([]api.Workflow, 0, len(current.config.Workflows))
+	for _, workflow := range current.config.Workflows {
+		response = append(response, api.Workflow{
+			Name:      workflow.Name,
+			Trigger:   workflow.Trigger,
+			Steps:     newStepResponses(workflow.Steps),
+			OnFailure: newStepResponses(workflow.OnFailure),
+		})
+	}
+	return c.JSON(response)
+}
+
+func newStepResponses(steps []internal.StepBlock) []api.Step {
+	output := make([]api.Step, 0, len(steps))
+	for _, step := range steps {
+		item := api.Step{
+			Name:        step.Name,
+			Provider:    step.Provider,
+			Action:      step.Action,
+			DependsOn:   step.DependsOn,
+			Conditional: step.Condition != nil,
+			ForEach:     step.ForEach != nil,
+			Idempotent:  step.Idempotent,
+			OnError:     string(step.OnError),
+			OnErrorStep: step.OnErrorStep,
+		}
+		if item.DependsOn == nil {
+			item.DependsOn = []string{}
+		}
+		output = append(output, item)
+	}
+	return output
+}
+
+func (s *Server) listPlugins(c *fiber.Ctx) error {
+	current, err := s.runningOrNotFound(c)
+	if current == nil {
+		return err
+	}
+	response := []api.Plugin{}
+	for _, name := range current.pluginManager.LoadedPlugins() {
+		item := api.Plugin{Name: name}
+		for _, requiredProvider := range current.config.Switchboard.RequiredProviders {
+			if requiredProvider.Name == name {
+				item.Source = requiredProvider.Source
+				item.Version = requiredProvider.Version
+			}
+		}
+		response = append(response, item)
+	}
+	return c.JSON(response)
+}
+
+// listRuns returns the most recent runs, optionally filtered by the workflow and status query parameters.
+// The limit query parameter defaults to 50.
+func (s *Server) listRuns(c *fiber.Ctx) error {
+	filter := state.RunFilter{
+		Workflow: c.Query("workflow"),
+		Status:   state.RunStatus(c.Query("status")),
+		Limit:    c.QueryInt("limit", 50),
+	}
+	if filter.Limit <= 0 || filter.Limit > maxRunsLimit {
+		filter.Limit = maxRunsLimit
+	}
+	runs, err := s.config.Store.Runs(filter)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(api.Error{Error: err.Error()})
+	}
+	response := make([]api.Run, 0, len(runs))
+	for _, run := range runs {
+		response = append(response, newRunResponse(run))
+	}
+	return c.JSON(response)
+}
+
+// getRun returns a single run with the results of its steps
+func (s *Server) getRun(c *fiber.Ctx) error {
+	run, err := s.config.Store.Run(c.Params("id"))
+	if errors.Is(err, state.ErrNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(api.Error{Error: "run does not exist"})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(api.Error{Error: err.Error()})
+	}
+	results, err := s.config.Store.StepResults(run.ID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(api.Error{Error: err.Error()})
+	}
+	response := newRunResponse(*run)
+	response.Steps = make([]api.StepResult, 0, len(results))
+	for _, result := range results {
+		response.Steps = append(response.Steps, api.StepResult{
+			Step:       result.Step,
+			Status:     string(result.Status),
+			Input:      json.RawMessage(result.Input),
+			Output:     json.RawMessage(result.Output),
+			Error:      result.Error,
+			Attempts:   len(result.Attempts),
+			StartedAt:  result.StartedAt,
+			FinishedAt: result.FinishedAt,
+		})
+	}
+	return c.JSON(response)
+}
+
+func newRunResponse(run state.Run) api.Run {
+	return api.Run{
+		ID:           run.ID,
+		Workflow:     run.Workflow,
+		DeploymentID: run.DeploymentID,
+		Status:       string(run.Status),
+		Error:        run.Error,
+		StartedAt:    run.StartedAt,
+		FinishedAt:   run.FinishedAt,
+	}
+}
@@ -0,0 +1,148 @@
+package server
+
+import (
+	"encoding/json"
+	"github.com/switchboard-org/switchboard/api"
+	"github.com/switchboard-org/switchboard/state"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestServer_adminEndpoints(t *testing.T) {
+	store := state.NewMemoryStateStore()
+	startedAt := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	finishedAt := startedAt.Add(time.Second)
+	store.SaveRun(state.Run{ID: "run-1", Workflow: "charge", Status: state.StatusSucceeded, StartedAt: startedAt, FinishedAt: &finishedAt})
+	store.SaveRun(state.Run{ID: "run-2", Workflow: "other", Status: state.StatusFailed, Error: "boom", StartedAt: startedAt.Add(time.Minute)})
+	store.SaveStepResult(state.StepResult{RunID: "run-1", Step: "charge", Status: state.StatusSucceeded, Output: []byte(`{"amount":"10"}`), Attempts: []state.Attempt{{Number: 1}}, StartedAt: startedAt})
+	s := testServer(t, store)
+
+	tests := []struct {
+		name       string
+		path       string
+		scopes     []Scope
+		wantStatus int
+		check      func(t *testing.T, body []byte)
+	}{
+		{
+			name:       "should return the local deployment",
+			path:       "/admin/deployment",
+			scopes:     []Scope{ScopeRead},
+			wantStatus: http.StatusOK,
+			check: func(t *testing.T, body []byte) {
+				var got api.Deployment
+				json.Unmarshal(body, &got)
+				if !got.Local || len(got.Workflows) != 1 || got.Triggers[0] != "nightly" {
+					t.Errorf("deployment = %s", body)
+				}
+			},
+		},
+		{
+			name:       "should return not found for the snapshot of a configuration whose files are not known",
+			path:       "/admin/deployment/snapshot",
+			scopes:     []Scope{ScopeRead},
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "should list triggers with their schedule and workflows",
+			path:       "/admin/triggers",
+			scopes:     []Scope{ScopeRead},
+			wantStatus: http.StatusOK,
+			check: func(t *testing.T, body []byte) {
+				var got []api.Trigger
+				json.Unmarshal(body, &got)
+				if len(got) != 1 || got[0].Type != "schedule" || got[0].Schedule == nil || got[0].Schedule.Timezone != "UTC" || got[0].Workflows[0] != "charge" {
+					t.Errorf("triggers = %s", body)
+				}
+			},
+		},
+		{
+			name:       "should list workflows with their step graph",
+			path:       "/admin/workflows",
+			scopes:     []Scope{ScopeRead},
+			wantStatus: http.StatusOK,
+			check: func(t *testing.T, body []byte) {
+				var got []api.Workflow
+				json.Unmarshal(body, &got)
+				if len(got) != 1 || len(got[0].Steps) != 2 || got[0].Steps[1].Name != "notify" || got[0].Steps[1].DependsOn[0] != "charge" {
+					t.Errorf("workflows = %s", body)
+				}
+			},
+		},
+		{
+			name:       "should list loaded plugins",
+			path:       "/admin/plugins",
+			scopes:     []Scope{ScopeRead},
+			wantStatus: http.StatusOK,
+			check: func(t *testing.T, body []byte) {
+				var got []api.Plugin
+				json.Unmarshal(body, &got)
+				if len(got) != 1 || got[0].Name != "stripe" || got[0].Version != "1.0.0" {
+					t.Errorf("plugins = %s", body)
+				}
+			},
+		},
+		{
+			name:       "should list recent runs filtered by workflow",
+			path:       "/admin/runs?workflow=charge",
+			scopes:     []Scope{ScopeRead},
+			wantStatus: http.StatusOK,
+			check: func(t *testing.T, body []byte) {
+				var got []api.Run
+				json.Unmarshal(body, &got)
+				if len(got) != 1 || got[0].ID != "run-1" || got[0].Steps != nil {
+					t.Errorf("runs = %s", body)
+				}
+			},
+		},
+		{
+			name:       "should return a run with its step results",
+			path:       "/admin/runs/run-1",
+			scopes:     []Scope{ScopeRead},
+			wantStatus: http.StatusOK,
+			check: func(t *testing.T, body []byte) {
+				var got api.Run
+				json.Unmarshal(body, &got)
+				if got.Status != "succeeded" || len(got.Steps) != 1 || string(got.Steps[0].Output) != `{"amount":"10"}` || got.Steps[0].Attempts != 1 {
+					t.Errorf("run = %s", body)
+				}
+			},
+		},
+		{
+			name:       "should return not found for unknown runs",
+			path:       "/admin/runs/unknown",
+			scopes:     []Scope{ScopeRead},
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "should forbid tokens without the read scope",
+			path:       "/admin/workflows",
+			scopes:     []Scope{ScopeDeploy},
+			wantStatus: http.StatusForbidden,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token, _, err := s.config.TokenStore.Create("test", tt.scopes)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			req.Header.Set("Authorization", "Bearer "+token)
+			resp, err := s.app.Test(req, -1)
+			if err != nil {
+				t.Fatalf("Test() error = %v", err)
+			}
+			var body json.RawMessage
+			json.NewDecoder(resp.Body).Decode(&body)
+			if resp.StatusCode != tt.wantStatus {
+				t.Fatalf("GET %s status = %d, want %d: %s", tt.path, resp.StatusCode, tt.wantStatus, body)
+			}
+			if tt.check != nil {
+				tt.check(t, body)
+			}
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package server
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"github.com/gofiber/fiber/v2"
+	"github.com/switchboard-org/switchboard/api"
+	"golang.org/x/exp/slices"
+	"log"
+	"strings"
+)
+
+// principal is the authenticated caller of an admin endpoint
+type principal struct {
+	// Name is the admin user or the name of the API token
+	Name   string
+	Scopes []Scope
+}
+
+const principalKey = "principal"
+
+// authenticate accepts either an API token as a bearer token, or the admin credentials with basic auth. The
+// admin user is granted every scope.
+func (s *Server) authenticate(c *fiber.Ctx) error {
+	scheme, credentials, _ := strings.Cut(c.Get(fiber.HeaderAuthorization), " ")
+	switch {
+	case strings.EqualFold(scheme, "Bearer") && s.config.TokenStore != nil:
+		token, err := s.config.TokenStore.Verify(credentials)
+		if err == nil {
+			c.Locals(principalKey, &principal{Name: token.Name, Scopes: token.Scopes})
+			return c.Next()
+		}
+		if !errors.Is(err, ErrInvalidToken) {
+			log.Printf("WARNING: could not verify API token: %s", err)
+		}
+	case strings.EqualFold(scheme, "Basic") && s.config.AdminPassword != "":
+		raw, err := base64.StdEncoding.DecodeString(credentials)
+		if err != nil {
+			break
+		}
+		user, password, _ := strings.Cut(string(raw), ":")
+		if equalSecrets(user, s.config.AdminUser) && equalSecrets(password, s.config.AdminPassword) {
+			c.Locals(principalKey, &principal{Name: user, Scopes: Scopes})
+			return c.Next()
+		}
+	}
+	return c.Status(fiber.StatusUnauthorized).JSON(api.Error{Error: "invalid credentials"})
+}
+
+// requireScope rejects callers that were not granted scope
+func requireScope(scope Scope) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		caller, ok := c.Locals(principalKey).(*principal)
+		if !ok || !slices.Contains(caller.Scopes, scope) {
+			return c.Status(fiber.StatusForbidden).JSON(api.Error{Error: "missing scope '" + string(scope) + "'"})
+		}
+		return c.Next()
+	}
+}
+
+// callerName returns the name of the authenticated caller
+func callerName(c *fiber.Ctx) string {
+	if caller, ok := c.Locals(principalKey).(*principal); ok {
+		return caller.Name
+	}
+	return ""
+}
+
+// equalSecrets compares the hashes of two secrets in constant time, which also hides the length of the secrets
+func equalSecrets(a string, b string) bool {
+	hashA := sha256.Sum256([]byte(a))
+	hashB := sha256.Sum256([]byte(b))
+	return subtle.ConstantTimeCompare(hashA[:], hashB[:]) == 1
+}
@@ -0,0 +1,31 @@
+package server
+
+import (
+	"github.com/hashicorp/hcl/v2"
+	"github.com/switchboard-org/switchboard/api"
+)
+
+// newDiagnostics converts hcl diagnostics to their JSON representation
+func newDiagnostics(diags hcl.Diagnostics) []api.Diagnostic {
+	output := make([]api.Diagnostic, 0, len(diags))
+	for _, diag := range diags {
+		severity := "error"
+		if diag.Severity == hcl.DiagWarning {
+			severity = "warning"
+		}
+		item := api.Diagnostic{
+			Severity: severity,
+			Summary:  diag.Summary,
+			Detail:   diag.Detail,
+		}
+		if diag.Subject != nil {
+			item.Range = &api.Range{
+				Filename: diag.Subject.Filename,
+				Start:    api.Position{Line: diag.Subject.Start.Line, Column: diag.Subject.Start.Column},
+				End:      api.Position{Line: diag.Subject.End.Line, Column: diag.Subject.End.Column},
+			}
+		}
+		output = append(output, item)
+	}
+	return output
+}
@@ -0,0 +1,162 @@
+package server
+
+import (
+	"github.com/switchboard-org/switchboard/api"
+	"github.com/switchboard-org/switchboard/internal"
+	"github.com/switchboard-org/switchboard/state"
+	"sync"
+	"time"
+)
+
+// subscriptionBuffer is the number of events a slow subscriber can fall behind before events are dropped
+const subscriptionBuffer = 256
+
+// broker sends the events of the server to the subscribed event streams
+type broker struct {
+	mu            sync.Mutex
+	subscriptions map[*subscription]struct{}
+}
+
+type subscription struct {
+	filter api.EventFilter
+	events chan api.Event
+}
+
+func newBroker() *broker {
+	return &broker{subscriptions: make(map[*subscription]struct{})}
+}
+
+func (b *broker) subscribe(filter api.EventFilter) *subscription {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	sub := &subscription{filter: filter, events: make(chan api.Event, subscriptionBuffer)}
+	b.subscriptions[sub] = struct{}{}
+	return sub
+}
+
+// unsubscribe stops sending events to sub and closes its channel
+func (b *broker) unsubscribe(sub *subscription) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, ok := b.subscriptions[sub]; ok {
+		delete(b.subscriptions, sub)
+		close(sub.events)
+	}
+}
+
+// publish sends an event to every matching subscription without blocking. Events are dropped for
+// subscriptions that fell behind, so a slow stream never holds up workflow runs.
+func (b *broker) publish(event api.Event) {
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for sub := range b.subscriptions {
+		if !sub.filter.Matches(event) {
+			continue
+		}
+		select {
+		case sub.events <- event:
+		default:
+		}
+	}
+}
+
+// close unsubscribes every subscription, which ends their streams
+func (b *broker) close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for sub := range b.subscriptions {
+		delete(b.subscriptions, sub)
+		close(sub.events)
+	}
+}
+
+// eventStateStore publishes an event for every run and step result saved by the engine
+type eventStateStore struct {
+	state.StateStore
+	broker *broker
+	mu     sync.Mutex
+	// workflows maps the ids of unfinished runs to their workflow, for the events of their steps
+	workflows map[string]string
+}
+
+func newEventStateStore(store state.StateStore, broker *broker) *eventStateStore {
+	return &eventStateStore{StateStore: store, broker: broker, workflows: make(map[string]string)}
+}
+
+func (s *eventStateStore) SaveRun(run state.Run) error {
+	if err := s.StateStore.SaveRun(run); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	if run.FinishedAt == nil {
+		s.workflows[run.ID] = run.Workflow
+	} else {
+		delete(s.workflows, run.ID)
+	}
+	s.mu.Unlock()
+	s.broker.publish(api.Event{
+		Type:     api.EventRun,
+		Workflow: run.Workflow,
+		RunID:    run.ID,
+		Status:   string(run.Status),
+		Message:  run.Error,
+	})
+	return nil
+}
+
+func (s *eventStateStore) SaveStepResult(result state.StepResult) error {
+	if err := s.StateStore.SaveStepResult(result); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	workflow, ok := s.workflows[result.RunID]
+	s.mu.Unlock()
+	if !ok {
+		if run, err := s.StateStore.Run(result.RunID); err == nil {
+			workflow = run.Workflow
+		}
+	}
+	s.broker.publish(api.Event{
+		Type:     api.EventStep,
+		Workflow: workflow,
+		RunID:    result.RunID,
+		Step:     result.Step,
+		Status:   string(result.Status),
+		Message:  result.Error,
+	})
+	return nil
+}
+
+// eventPluginManager publishes an event every time a provider plugin is loaded or killed
+type eventPluginManager struct {
+	internal.PluginManager
+	broker *broker
+}
+
+func (m *eventPluginManager) LoadPlugin(provider internal.RequiredProviderBlock) error {
+	if err := m.PluginManager.LoadPlugin(provider); err != nil {
+		m.broker.publish(api.Event{Type: api.EventPlugin, Plugin: provider.Name, Status: "failed", Message: err.Error()})
+		return err
+	}
+	m.broker.publish(api.Event{Type: api.EventPlugin, Plugin: provider.Name, Status: "loaded"})
+	return nil
+}
+
+func (m *eventPluginManager) KillPlugin(name string) error {
+	if err := m.PluginManager.KillPlugin(name); err != nil {
+		return err
+	}
+	m.broker.publish(api.Event{Type: api.EventPlugin, Plugin: name, Status: "killed"})
+	return nil
+}
+
+func (m *eventPluginManager) KillAllPlugins() {
+	loaded := m.PluginManager.LoadedPlugins()
+	m.PluginManager.KillAllPlugins()
+	for _, name := range loaded {
+		m.broker.publish(api.Event{Type: api.EventPlugin, Plugin: name, Status: "killed"})
+	}
+}
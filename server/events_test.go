@@ -0,0 +1,99 @@
+package server
+
+import (
+	"github.com/switchboard-org/switchboard/api"
+	"github.com/switchboard-org/switchboard/state"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestBroker_publish(t *testing.T) {
+	events := []api.Event{
+		{Type: api.EventRun, Workflow: "charge", RunID: "run-1", Status: "running"},
+		{Type: api.EventStep, Workflow: "charge", RunID: "run-1", Step: "charge", Status: "succeeded"},
+		{Type: api.EventRun, Workflow: "notify", RunID: "run-2", Status: "running"},
+		{Type: api.EventPlugin, Plugin: "stripe", Status: "loaded"},
+	}
+	tests := []struct {
+		name   string
+		filter api.EventFilter
+		want   []int
+	}{
+		{name: "should send every event without a filter", want: []int{0, 1, 2, 3}},
+		{name: "should only send events of the workflow", filter: api.EventFilter{Workflow: "charge"}, want: []int{0, 1}},
+		{name: "should only send events of the run", filter: api.EventFilter{RunID: "run-2"}, want: []int{2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := newBroker()
+			sub := b.subscribe(tt.filter)
+			for _, event := range events {
+				b.publish(event)
+			}
+			b.unsubscribe(sub)
+			var got []int
+			for event := range sub.events {
+				for i := range events {
+					if event.Type == events[i].Type && event.RunID == events[i].RunID && event.Step == events[i].Step && event.Plugin == events[i].Plugin {
+						got = append(got, i)
+					}
+				}
+				if event.Time.IsZero() {
+					t.Errorf("publish() event time is not set")
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("publish() sent events %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBroker_publishSlowSubscriber(t *testing.T) {
+	b := newBroker()
+	sub := b.subscribe(api.EventFilter{})
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < subscriptionBuffer*2; i++ {
+			b.publish(api.Event{Type: api.EventPlugin})
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("publish() blocked on a subscriber that fell behind")
+	}
+	if len(sub.events) != subscriptionBuffer {
+		t.Errorf("publish() buffered %d events, want %d", len(sub.events), subscriptionBuffer)
+	}
+}
+
+func TestEventStateStore(t *testing.T) {
+	b := newBroker()
+	sub := b.subscribe(api.EventFilter{Workflow: "charge"})
+	store := newEventStateStore(state.NewMemoryStateStore(), b)
+	now := time.Now()
+	store.SaveRun(state.Run{ID: "run-1", Workflow: "charge", Status: state.StatusRunning, StartedAt: now})
+	store.SaveStepResult(state.StepResult{RunID: "run-1", Step: "charge", Status: state.StatusFailed, Error: "declined"})
+	store.SaveRun(state.Run{ID: "run-1", Workflow: "charge", Status: state.StatusFailed, Error: "declined", StartedAt: now, FinishedAt: &now})
+	b.unsubscribe(sub)
+
+	var got []api.Event
+	for event := range sub.events {
+		event.Time = time.Time{}
+		got = append(got, event)
+	}
+	want := []api.Event{
+		{Type: api.EventRun, Workflow: "charge", RunID: "run-1", Status: "running"},
+		{Type: api.EventStep, Workflow: "charge", RunID: "run-1", Step: "charge", Status: "failed", Message: "declined"},
+		{Type: api.EventRun, Workflow: "charge", RunID: "run-1", Status: "failed", Message: "declined"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("events = %v, want %v", got, want)
+	}
+	if _, err := store.Run("run-1"); err != nil {
+		t.Errorf("Run() error = %v, want the run saved in the wrapped store", err)
+	}
+}
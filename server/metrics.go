@@ -0,0 +1,237 @@
+package server
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/switchboard-org/switchboard/internal"
+	"github.com/switchboard-org/switchboard/state"
+	"github.com/valyala/fasthttp"
+	"github.com/valyala/fasthttp/fasthttpadaptor"
+	"strings"
+	"sync"
+)
+
+// durationBuckets are the upper bounds in seconds of the duration histograms
+var durationBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60}
+
+// serverMetrics are the metrics served on /metrics
+type serverMetrics struct {
+	registry *prometheus.Registry
+	// handler serves the metrics of the registry in the Prometheus exposition format
+	handler            fasthttp.RequestHandler
+	triggerRequests    *prometheus.CounterVec
+	triggerDuration    *prometheus.HistogramVec
+	validationFailures *prometheus.CounterVec
+	runs               *prometheus.CounterVec
+	queueDepth         *prometheus.GaugeVec
+	stepDuration       *prometheus.HistogramVec
+	stepRetries        *prometheus.CounterVec
+	// restarts are the running totals of plugin restarts. Every deploy replaces the plugin managers, so the
+	// totals are added up from the restarts each runtime reported when it was last seen, in seenRestarts.
+	restartsMu   sync.Mutex
+	restarts     map[string]float64
+	seenRestarts map[*runtime]map[string]int
+}
+
+func newServerMetrics(s *Server) *serverMetrics {
+	m := &serverMetrics{
+		registry: prometheus.NewRegistry(),
+		triggerRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "switchboard_trigger_requests_total",
+			Help: "Requests received by provider triggers, by response status code.",
+		}, []string{"trigger", "code"}),
+		triggerDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "switchboard_trigger_request_duration_seconds",
+			Help:    "Time taken to handle the requests of provider triggers.",
+			Buckets: durationBuckets,
+		}, []string{"trigger"}),
+		validationFailures: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "switchboard_trigger_validation_failures_total",
+			Help: "Trigger requests rejected because their payload did not match the trigger schema.",
+		}, []string{"trigger"}),
+		runs: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "switchboard_runs_total",
+			Help: "Finished workflow runs, by outcome.",
+		}, []string{"workflow", "status"}),
+		queueDepth: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "switchboard_run_queue_depth",
+			Help: "Workflow runs that were started and have not finished yet.",
+		}, []string{"workflow"}),
+		stepDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "switchboard_step_duration_seconds",
+			Help:    "Time taken by finished steps, including retries.",
+			Buckets: durationBuckets,
+		}, []string{"provider", "action"}),
+		stepRetries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "switchboard_step_retries_total",
+			Help: "Provider action calls made after the first attempt of a step failed.",
+		}, []string{"provider", "action"}),
+		restarts:     make(map[string]float64),
+		seenRestarts: make(map[*runtime]map[string]int),
+	}
+	m.registry.MustRegister(m.triggerRequests, m.triggerDuration, m.validationFailures, m.runs, m.queueDepth,
+		m.stepDuration, m.stepRetries, &pluginRestartsCollector{
+			desc: prometheus.NewDesc("switchboard_plugin_restarts_total",
+				"Plugin processes that exited unexpectedly and were replaced.", []string{"plugin"}, nil),
+			restarts: func() map[string]float64 {
+				return m.pluginRestarts(s)
+			},
+		})
+	m.handler = fasthttpadaptor.NewFastHTTPHandler(promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{}))
+	return m
+}
+
+// pluginRestartsCollector collects the plugin restart totals when the metrics are scraped, as the plugin
+// managers count the restarts themselves
+type pluginRestartsCollector struct {
+	desc     *prometheus.Desc
+	restarts func() map[string]float64
+}
+
+func (c *pluginRestartsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.desc
+}
+
+func (c *pluginRestartsCollector) Collect(ch chan<- prometheus.Metric) {
+	for name, restarts := range c.restarts() {
+		ch <- prometheus.MustNewConstMetric(c.desc, prometheus.CounterValue, restarts, name)
+	}
+}
+
+// pluginRestarts returns the restarts of the plugins of every configuration the server ran since it started
+func (m *serverMetrics) pluginRestarts(s *Server) map[string]float64 {
+	m.restartsMu.Lock()
+	defer m.restartsMu.Unlock()
+	s.mu.RLock()
+	runtimes := make([]*runtime, 0, len(s.retired)+1)
+	if s.current != nil {
+		runtimes = append(runtimes, s.current)
+	}
+	for r := range s.retired {
+		runtimes = append(runtimes, r)
+	}
+	s.mu.RUnlock()
+	for _, r := range runtimes {
+		m.countRestarts(r, false)
+	}
+	output := make(map[string]float64, len(m.restarts))
+	for name, restarts := range m.restarts {
+		output[name] = restarts
+	}
+	return output
+}
+
+// countRestarts adds the restarts of the plugins of a runtime since it was last seen to the totals. The runtime
+// is forgotten if done is set. It must be called with restartsMu held.
+func (m *serverMetrics) countRestarts(r *runtime, done bool) {
+	if r.pluginManager == nil {
+		return
+	}
+	seen := m.seenRestarts[r]
+	restarts := r.pluginManager.Restarts()
+	for name, count := range restarts {
+		delta := count - seen[name]
+		if delta < 0 {
+			// the plugin was killed and loaded again since it was last seen, which reset its restarts
+			delta = count
+		}
+		m.restarts[name] += float64(delta)
+	}
+	if done {
+		delete(m.seenRestarts, r)
+	} else {
+		m.seenRestarts[r] = restarts
+	}
+}
+
+// getMetrics writes the metrics of the server in the Prometheus exposition format
+func (s *Server) getMetrics(c *fiber.Ctx) error {
+	s.metrics.handler(c.Context())
+	return nil
+}
+
+// metricsStateStore records the outcome of every run and the duration of every step saved by the engine of a
+// runtime. Every runtime has its own store, so that the steps of its runs are labeled with the provider actions
+// of its configuration, even after a deploy replaced it.
+type metricsStateStore struct {
+	state.StateStore
+	metrics *serverMetrics
+	config  *internal.RootSwitchboardConfig
+	mu      sync.Mutex
+	// workflows maps the ids of unfinished runs to their workflow, and is the queue depth of each workflow
+	workflows map[string]string
+}
+
+func newMetricsStateStore(store state.StateStore, m *serverMetrics, config *internal.RootSwitchboardConfig) *metricsStateStore {
+	return &metricsStateStore{StateStore: store, metrics: m, config: config, workflows: make(map[string]string)}
+}
+
+// stepBlock returns the step of a workflow of the configuration, nil if it does not exist
+func (s *metricsStateStore) stepBlock(workflow string, step string) *internal.StepBlock {
+	w := s.config.Workflow(workflow)
+	if w == nil {
+		return nil
+	}
+	for _, steps := range [][]internal.StepBlock{w.Steps, w.OnFailure} {
+		for i := range steps {
+			if steps[i].Name == step {
+				return &steps[i]
+			}
+		}
+	}
+	return nil
+}
+
+func (s *metricsStateStore) SaveRun(run state.Run) error {
+	if err := s.StateStore.SaveRun(run); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, queued := s.workflows[run.ID]
+	if run.FinishedAt == nil {
+		if !queued {
+			s.workflows[run.ID] = run.Workflow
+			s.metrics.queueDepth.WithLabelValues(run.Workflow).Inc()
+		}
+		return nil
+	}
+	if queued {
+		delete(s.workflows, run.ID)
+		s.metrics.queueDepth.WithLabelValues(run.Workflow).Dec()
+	}
+	s.metrics.runs.WithLabelValues(run.Workflow, string(run.Status)).Inc()
+	return nil
+}
+
+func (s *metricsStateStore) SaveStepResult(result state.StepResult) error {
+	if err := s.StateStore.SaveStepResult(result); err != nil {
+		return err
+	}
+	if result.FinishedAt == nil || len(result.Attempts) == 0 {
+		return nil
+	}
+	s.mu.Lock()
+	workflow, ok := s.workflows[result.RunID]
+	s.mu.Unlock()
+	if !ok {
+		run, err := s.StateStore.Run(result.RunID)
+		if err != nil {
+			return nil
+		}
+		workflow = run.Workflow
+	}
+	// the results of for_each iterations are named after the step, followed by the iteration key
+	name, _, iteration := strings.Cut(result.Step, "[")
+	step := s.stepBlock(workflow, name)
+	// the result of a for_each step itself aggregates its iterations, which are already recorded
+	if step == nil || (step.ForEach != nil && !iteration) {
+		return nil
+	}
+	s.metrics.stepDuration.WithLabelValues(step.Provider, step.Action).Observe(result.FinishedAt.Sub(result.StartedAt).Seconds())
+	if retries := len(result.Attempts) - 1; retries > 0 {
+		s.metrics.stepRetries.WithLabelValues(step.Provider, step.Action).Add(float64(retries))
+	}
+	return nil
+}
@@ -0,0 +1,225 @@
+package server
+
+import (
+	"fmt"
+	"github.com/prometheus/common/expfmt"
+	"github.com/switchboard-org/switchboard/internal"
+	"github.com/switchboard-org/switchboard/sbtest"
+	"github.com/switchboard-org/switchboard/state"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// gatherMetrics returns the metrics of the server in the text exposition format
+func gatherMetrics(t *testing.T, m *serverMetrics) string {
+	t.Helper()
+	families, err := m.registry.Gather()
+	if err != nil {
+		t.Fatalf("Gather() error = %v", err)
+	}
+	var out strings.Builder
+	for _, family := range families {
+		if _, err = expfmt.MetricFamilyToText(&out, family); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return out.String()
+}
+
+func TestMetricsStateStore(t *testing.T) {
+	startedAt := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	finishedAt := startedAt.Add(2 * time.Second)
+	config := &internal.RootSwitchboardConfig{Workflows: []internal.WorkflowBlock{{
+		Name:  "charge",
+		Steps: []internal.StepBlock{{Name: "charge", Provider: "stripe", Action: "create_charge"}},
+	}}}
+	tests := []struct {
+		name    string
+		runs    []state.Run
+		results []state.StepResult
+		want    []string
+		notWant []string
+	}{
+		{
+			name: "should count unfinished runs in the queue depth",
+			runs: []state.Run{
+				{ID: "run-1", Workflow: "charge", Status: state.StatusRunning, StartedAt: startedAt},
+				{ID: "run-1", Workflow: "charge", Status: state.StatusRunning, StartedAt: startedAt},
+				{ID: "run-2", Workflow: "charge", Status: state.StatusRunning, StartedAt: startedAt},
+			},
+			want:    []string{`switchboard_run_queue_depth{workflow="charge"} 2`},
+			notWant: []string{`switchboard_runs_total{`},
+		},
+		{
+			name: "should count finished runs by outcome and remove them from the queue",
+			runs: []state.Run{
+				{ID: "run-1", Workflow: "charge", Status: state.StatusRunning, StartedAt: startedAt},
+				{ID: "run-1", Workflow: "charge", Status: state.StatusFailed, StartedAt: startedAt, FinishedAt: &finishedAt},
+			},
+			want: []string{
+				`switchboard_run_queue_depth{workflow="charge"} 0`,
+				`switchboard_runs_total{status="failed",workflow="charge"} 1`,
+			},
+		},
+		{
+			name: "should record the duration and retries of finished steps by provider action",
+			runs: []state.Run{{ID: "run-1", Workflow: "charge", Status: state.StatusRunning, StartedAt: startedAt}},
+			results: []state.StepResult{
+				{RunID: "run-1", Step: "charge", Status: state.StatusRunning, Attempts: []state.Attempt{{Number: 1}}, StartedAt: startedAt},
+				{RunID: "run-1", Step: "charge", Status: state.StatusSucceeded, Attempts: []state.Attempt{{Number: 1}, {Number: 2}, {Number: 3}}, StartedAt: startedAt, FinishedAt: &finishedAt},
+			},
+			want: []string{
+				`switchboard_step_duration_seconds_count{action="create_charge",provider="stripe"} 1`,
+				`switchboard_step_duration_seconds_sum{action="create_charge",provider="stripe"} 2`,
+				`switchboard_step_retries_total{action="create_charge",provider="stripe"} 2`,
+			},
+		},
+		{
+			name: "should label for_each iterations with the action of their step",
+			runs: []state.Run{{ID: "run-1", Workflow: "charge", Status: state.StatusRunning, StartedAt: startedAt}},
+			results: []state.StepResult{
+				{RunID: "run-1", Step: `charge["a"]`, Status: state.StatusSucceeded, Attempts: []state.Attempt{{Number: 1}}, StartedAt: startedAt, FinishedAt: &finishedAt},
+			},
+			want:    []string{`switchboard_step_duration_seconds_count{action="create_charge",provider="stripe"} 1`},
+			notWant: []string{`switchboard_step_retries_total{`},
+		},
+		{
+			name: "should ignore skipped steps and unknown steps",
+			runs: []state.Run{{ID: "run-1", Workflow: "charge", Status: state.StatusRunning, StartedAt: startedAt}},
+			results: []state.StepResult{
+				{RunID: "run-1", Step: "charge", Status: state.StatusSkipped, StartedAt: startedAt, FinishedAt: &finishedAt},
+				{RunID: "run-1", Step: "unknown", Status: state.StatusSucceeded, Attempts: []state.Attempt{{Number: 1}}, StartedAt: startedAt, FinishedAt: &finishedAt},
+			},
+			notWant: []string{`switchboard_step_duration_seconds_count{`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newServerMetrics(&Server{})
+			store := newMetricsStateStore(state.NewMemoryStateStore(), m, config)
+			for _, run := range tt.runs {
+				if err := store.SaveRun(run); err != nil {
+					t.Fatalf("SaveRun() error = %v", err)
+				}
+			}
+			for _, result := range tt.results {
+				if err := store.SaveStepResult(result); err != nil {
+					t.Fatalf("SaveStepResult() error = %v", err)
+				}
+			}
+			out := gatherMetrics(t, m)
+			for _, want := range tt.want {
+				if !strings.Contains(out, want) {
+					t.Errorf("metrics do not contain %q:\n%s", want, out)
+				}
+			}
+			for _, notWant := range tt.notWant {
+				if strings.Contains(out, notWant) {
+					t.Errorf("metrics contain %q:\n%s", notWant, out)
+				}
+			}
+		})
+	}
+}
+
+func TestMetricsStateStore_replacedConfiguration(t *testing.T) {
+	startedAt := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	finishedAt := startedAt.Add(time.Second)
+	config := func(action string) *internal.RootSwitchboardConfig {
+		return &internal.RootSwitchboardConfig{Workflows: []internal.WorkflowBlock{{
+			Name:  "charge",
+			Steps: []internal.StepBlock{{Name: "charge", Provider: "stripe", Action: action}},
+		}}}
+	}
+	m := newServerMetrics(&Server{})
+	store := state.NewMemoryStateStore()
+	previous := newMetricsStateStore(store, m, config("create_charge"))
+	next := newMetricsStateStore(store, m, config("create_payment_intent"))
+
+	// a run started before a deploy finishes with the configuration it started with
+	if err := previous.SaveRun(state.Run{ID: "run-1", Workflow: "charge", Status: state.StatusRunning, StartedAt: startedAt}); err != nil {
+		t.Fatalf("SaveRun() error = %v", err)
+	}
+	result := state.StepResult{RunID: "run-1", Step: "charge", Status: state.StatusSucceeded, Attempts: []state.Attempt{{Number: 1}}, StartedAt: startedAt, FinishedAt: &finishedAt}
+	if err := previous.SaveStepResult(result); err != nil {
+		t.Fatalf("SaveStepResult() error = %v", err)
+	}
+	if err := next.SaveRun(state.Run{ID: "run-2", Workflow: "charge", Status: state.StatusRunning, StartedAt: startedAt}); err != nil {
+		t.Fatalf("SaveRun() error = %v", err)
+	}
+	result.RunID = "run-2"
+	if err := next.SaveStepResult(result); err != nil {
+		t.Fatalf("SaveStepResult() error = %v", err)
+	}
+	out := gatherMetrics(t, m)
+	for _, action := range []string{"create_charge", "create_payment_intent"} {
+		want := fmt.Sprintf(`switchboard_step_duration_seconds_count{action="%s",provider="stripe"} 1`, action)
+		if !strings.Contains(out, want) {
+			t.Errorf("metrics do not contain %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestServer_getMetrics(t *testing.T) {
+	s := testServer(t, state.NewMemoryStateStore())
+	s.metrics.runs.WithLabelValues("charge", string(state.StatusSucceeded)).Inc()
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	resp, err := s.app.Test(req, -1)
+	if err != nil {
+		t.Fatalf("Test() error = %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK || !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/plain") {
+		t.Fatalf("GET /metrics status = %d, content type = %s", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+	want := `switchboard_runs_total{status="succeeded",workflow="charge"} 1`
+	if !strings.Contains(string(body), want) {
+		t.Errorf("metrics do not contain %q:\n%s", want, body)
+	}
+}
+
+// restartsPluginManager reports a fixed number of restarts for its plugins
+type restartsPluginManager struct {
+	*sbtest.FakePluginManager
+	restarts map[string]int
+}
+
+func (pm *restartsPluginManager) Restarts() map[string]int {
+	return pm.restarts
+}
+
+func TestServer_pluginRestarts(t *testing.T) {
+	s := NewServer(Config{Store: state.NewMemoryStateStore()})
+	var interrupted atomic.Bool
+	previous, _ := testRuntime(0, &interrupted)
+	previousPlugins := &restartsPluginManager{FakePluginManager: sbtest.NewFakePluginManager(nil), restarts: map[string]int{"stripe": 2}}
+	previous.pluginManager = previousPlugins
+	s.current = previous
+	if got := s.metrics.pluginRestarts(s); !reflect.DeepEqual(got, map[string]float64{"stripe": 2}) {
+		t.Errorf("pluginRestarts() got = %v, want 2 stripe restarts", got)
+	}
+
+	// a deploy replaces the runtime, whose plugin restarts once more before it stops
+	next, _ := testRuntime(0, &interrupted)
+	next.pluginManager = &restartsPluginManager{FakePluginManager: sbtest.NewFakePluginManager(nil), restarts: map[string]int{"stripe": 1}}
+	previousPlugins.restarts = map[string]int{"stripe": 3}
+	s.current = next
+	s.retire(previous)
+	s.retiring.Wait()
+	want := map[string]float64{"stripe": 4}
+	if got := s.metrics.pluginRestarts(s); !reflect.DeepEqual(got, want) {
+		t.Errorf("pluginRestarts() after deploy got = %v, want %v", got, want)
+	}
+	if got := s.metrics.pluginRestarts(s); !reflect.DeepEqual(got, want) {
+		t.Errorf("pluginRestarts() collected again got = %v, want %v", got, want)
+	}
+	if out := gatherMetrics(t, s.metrics); !strings.Contains(out, `switchboard_plugin_restarts_total{plugin="stripe"} 4`) {
+		t.Errorf("metrics do not contain the plugin restarts:\n%s", out)
+	}
+}
@@ -0,0 +1,439 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/gofiber/fiber/v2"
+	"github.com/hashicorp/hcl/v2"
+	"github.com/switchboard-org/switchboard/api"
+	"github.com/switchboard-org/switchboard/bundle"
+	"github.com/switchboard-org/switchboard/engine"
+	"github.com/switchboard-org/switchboard/internal"
+	"github.com/switchboard-org/switchboard/parsecfg"
+	"github.com/switchboard-org/switchboard/plan"
+	"github.com/switchboard-org/switchboard/state"
+	"io"
+	"io/fs"
+	"log"
+	"sync"
+	"time"
+)
+
+// MaxBundleSize is the largest configuration bundle that can be deployed
+const MaxBundleSize = 32 << 20
+
+// Config holds the dependencies of a Server
+type Config struct {
+	// Parser parses the configuration in the working directory, which is run until a bundle is deployed
+	Parser parsecfg.Parser
+	// WorkingDir is the directory Parser reads the configuration from. The snapshot of the configuration in the
+	// working directory is not available to plans if empty.
+	WorkingDir string
+	// WatchInterval is how often the working directory is checked for changes, which are then applied without
+	// a deploy. The configuration in the working directory runs instead of the active deployment when set.
+	// Watching is disabled if it is zero, and requires WorkingDir.
+	WatchInterval time.Duration
+	// DrainTimeout is how long Shutdown waits for the requests and runs in progress. Runs that do not finish
+	// in time are interrupted, and resumed when the server starts again.
+	DrainTimeout time.Duration
+	// DeregisterTriggers deletes the trigger registrations on Shutdown
+	DeregisterTriggers bool
+	// NewBundleParser creates the parser of a deployed configuration bundle
+	NewBundleParser func(fsys fs.FS) parsecfg.Parser
+	// NewPluginManager creates the plugin manager of each engine, internal.NewDefaultPluginManager if nil
+	NewPluginManager func() internal.PluginManager
+	Store            state.StateStore
+	// TokenStore holds the API tokens accepted by the admin endpoints. API tokens are not accepted if nil
+	TokenStore TokenStore
+	// AdminUser and AdminPassword are the basic auth credentials of the admin user, which has every scope.
+	// Basic auth is disabled if AdminPassword is empty.
+	AdminUser     string
+	AdminPassword string
+}
+
+// Server runs the workflows of the deployed configuration, and serves the admin endpoints used to deploy
+// new configurations
+type Server struct {
+	app    *fiber.App
+	config Config
+	// stopWatching stops watching the working directory, and watching is done once it has stopped
+	stopWatching context.CancelFunc
+	watching     sync.WaitGroup
+	// deployMu makes deploys wait for each other, so that the last deploy is the one that is running
+	deployMu sync.Mutex
+	mu       sync.RWMutex
+	current  *runtime
+	// retired are the runtimes replaced by a deploy, which stop once their runs finish
+	retired  map[*runtime]struct{}
+	retiring sync.WaitGroup
+	events   *broker
+	metrics  *serverMetrics
+}
+
+// runtime is the engine and scheduler of a parsed configuration
+type runtime struct {
+	// deployment is nil when running the configuration in the working directory
+	deployment *state.Deployment
+	config     *internal.RootSwitchboardConfig
+	// snapshot is compared with local configurations to plan deploys. It is nil if the files of the
+	// configuration are not known.
+	snapshot *plan.Snapshot
+	engine   engine.Engine
+	// pluginManager is the plugin manager of the engine
+	pluginManager internal.PluginManager
+	// cancel stops the scheduler, while cancelRuns also interrupts the runs that were already started
+	cancel     context.CancelFunc
+	cancelRuns context.CancelFunc
+	// runContext is the context of the runs of the runtime, cancelled by cancelRuns
+	runContext context.Context
+	wg         sync.WaitGroup
+}
+
+func NewServer(config Config) *Server {
+	if config.NewPluginManager == nil {
+		config.NewPluginManager = internal.NewDefaultPluginManager
+	}
+	events := newBroker()
+	// runs and step results saved by the engines are published to the event streams
+	config.Store = newEventStateStore(config.Store, events)
+	s := &Server{
+		app:     fiber.New(fiber.Config{BodyLimit: MaxBundleSize}),
+		config:  config,
+		retired: make(map[*runtime]struct{}),
+		events:  events,
+	}
+	s.metrics = newServerMetrics(s)
+	s.app.Get("/metrics", s.getMetrics)
+	s.app.Post(triggerPath+":name", s.trigger)
+	adminGroup := s.app.Group("/admin")
+	adminGroup.Use(s.authenticate)
+	adminGroup.Post("/deploy", requireScope(ScopeDeploy), s.deploy)
+	adminGroup.Post("/destroy", requireScope(ScopeDeploy), s.destroy)
+	adminGroup.Get("/deployment", requireScope(ScopeRead), s.getDeployment)
+	adminGroup.Get("/deployment/snapshot", requireScope(ScopeRead), s.getSnapshot)
+	adminGroup.Get("/triggers", requireScope(ScopeRead), s.listTriggers)
+	adminGroup.Get("/workflows", requireScope(ScopeRead), s.listWorkflows)
+	adminGroup.Get("/plugins", requireScope(ScopeRead), s.listPlugins)
+	adminGroup.Get("/runs", requireScope(ScopeRead), s.listRuns)
+	adminGroup.Get("/runs/:id", requireScope(ScopeRead), s.getRun)
+	adminGroup.Get("/stream", requireScope(ScopeRead), s.stream)
+	return s
+}
+
+// Start runs the active deployment, or the configuration in the working directory if nothing was deployed
+// yet or the working directory is watched. Runs interrupted by the last shutdown are resumed. Nothing runs
+// if the last deployment was destroyed.
+func (s *Server) Start() error {
+	next, err := s.initialRuntime()
+	if err != nil {
+		return err
+	}
+	if next == nil {
+		log.Println("nothing is deployed, waiting for a deployment")
+		return nil
+	}
+	s.mu.Lock()
+	s.current = next
+	s.mu.Unlock()
+	next.start(s.config.Store, true)
+	if s.watchEnabled() {
+		ctx, cancel := context.WithCancel(context.Background())
+		s.stopWatching = cancel
+		s.watching.Add(1)
+		go func() {
+			defer s.watching.Done()
+			s.watch(ctx, next.snapshot.Hash)
+		}()
+		log.Printf("watching %s for changes", s.config.WorkingDir)
+	}
+	return nil
+}
+
+func (s *Server) watchEnabled() bool {
+	return s.config.WatchInterval > 0 && s.config.WorkingDir != ""
+}
+
+func (s *Server) initialRuntime() (*runtime, error) {
+	deployment, err := s.config.Store.ActiveDeployment()
+	if errors.Is(err, state.ErrNotFound) || s.watchEnabled() {
+		fsys, err := s.workingDirFS()
+		if err != nil {
+			return nil, err
+		}
+		// the providers are downloaded on start only, deploys and reloads use the providers already installed
+		if diag := s.config.Parser.Init(); diag.HasErrors() {
+			return nil, fmt.Errorf("could not install providers: %w", diag)
+		}
+		next, diag := s.newRuntime(s.config.Parser, fsys)
+		if diag.HasErrors() {
+			return nil, fmt.Errorf("invalid configuration: %w", diag)
+		}
+		return next, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not get active deployment: %w", err)
+	}
+	if len(deployment.Bundle) == 0 {
+		return nil, nil
+	}
+	fsys, err := bundle.Read(bytes.NewReader(deployment.Bundle))
+	if err != nil {
+		return nil, fmt.Errorf("could not read bundle of deployment %s: %w", deployment.ID, err)
+	}
+	parser := s.config.NewBundleParser(fsys)
+	if diag := parser.Init(); diag.HasErrors() {
+		return nil, fmt.Errorf("could not install providers of deployment %s: %w", deployment.ID, diag)
+	}
+	next, diag := s.newRuntime(parser, fsys)
+	if diag.HasErrors() {
+		return nil, fmt.Errorf("invalid configuration in deployment %s: %w", deployment.ID, diag)
+	}
+	next.deployment = deployment
+	return next, nil
+}
+
+// workingDirFS returns the configuration files in the working directory, the same way they would be deployed.
+// It returns nil if the working directory is not set.
+func (s *Server) workingDirFS() (fs.FS, error) {
+	if s.config.WorkingDir == "" {
+		return nil, nil
+	}
+	archive, err := bundle.Pack(s.config.WorkingDir)
+	if err != nil {
+		return nil, fmt.Errorf("could not package configuration: %w", err)
+	}
+	return bundle.Read(bytes.NewReader(archive))
+}
+
+// Listen serves the endpoints of the server on addr, until the server is shut down
+func (s *Server) Listen(addr string) error {
+	return s.app.Listen(addr)
+}
+
+// Stop interrupts the runs in progress, which are resumed when the server starts again, then kills the
+// provider plugins and ends the event streams. Use Shutdown to let the runs finish first.
+func (s *Server) Stop() {
+	defer s.events.close()
+	s.stopWatch()
+	s.deployMu.Lock()
+	defer s.deployMu.Unlock()
+	s.stopRuntimes(0)
+}
+
+// newRuntime parses a configuration and starts an engine for it. Failures to start the engine are returned
+// as diagnostics, as they are usually caused by the configuration, such as an invalid provider api key.
+// fsys holds the files the configuration was parsed from, which are used to create its snapshot. It can be nil.
+// Providers are not downloaded, as deploys wait for each other: a provider that is not installed is reported
+// as a diagnostic by the parser.
+func (s *Server) newRuntime(parser parsecfg.Parser, fsys fs.FS) (*runtime, hcl.Diagnostics) {
+	config, diag := parser.Parse()
+	if diag.HasErrors() {
+		return nil, diag
+	}
+	var snapshot *plan.Snapshot
+	if fsys != nil {
+		var err error
+		if snapshot, err = plan.NewSnapshot(config, fsys); err != nil {
+			return nil, hcl.Diagnostics{{
+				Severity: hcl.DiagError,
+				Summary:  "could not create snapshot of configuration",
+				Detail:   err.Error(),
+			}}
+		}
+	}
+	pluginManager := &eventPluginManager{PluginManager: s.config.NewPluginManager(), broker: s.events}
+	// run outcomes and step durations are recorded from the runs and step results saved by the engine
+	store := newMetricsStateStore(s.config.Store, s.metrics, config)
+	e := engine.NewDefaultEngine(config, pluginManager, store)
+	if err := e.Start(); err != nil {
+		return nil, hcl.Diagnostics{{
+			Severity: hcl.DiagError,
+			Summary:  "could not start engine",
+			Detail:   err.Error(),
+		}}
+	}
+	return &runtime{config: config, snapshot: snapshot, engine: e, pluginManager: pluginManager}, nil
+}
+
+// swap replaces the running configuration. The scheduler of the previous configuration is stopped before the
+// new one starts, while runs that were already started finish in the background.
+func (s *Server) swap(next *runtime) {
+	s.mu.Lock()
+	previous := s.current
+	s.current = next
+	s.mu.Unlock()
+	if previous != nil {
+		previous.cancel()
+	}
+	next.start(s.config.Store, false)
+	if previous != nil {
+		s.retire(previous)
+	}
+}
+
+// retire stops a cancelled runtime in the background, once its runs have finished
+func (s *Server) retire(r *runtime) {
+	s.mu.Lock()
+	s.retired[r] = struct{}{}
+	s.mu.Unlock()
+	s.retiring.Add(1)
+	go func() {
+		defer s.retiring.Done()
+		r.wg.Wait()
+		// the last restarts are counted before the plugins are killed, as killed plugins report no restarts
+		s.metrics.restartsMu.Lock()
+		s.mu.Lock()
+		delete(s.retired, r)
+		s.mu.Unlock()
+		s.metrics.countRestarts(r, true)
+		s.metrics.restartsMu.Unlock()
+		r.stop()
+	}()
+}
+
+// running returns the runtime of the running configuration, which is nil before the server starts
+func (s *Server) running() *runtime {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.current
+}
+
+// start runs the scheduler of the runtime, and resumes interrupted runs if resume is set
+func (r *runtime) start(store state.StateStore, resume bool) {
+	ctx, cancel := context.WithCancel(context.Background())
+	runContext, cancelRuns := context.WithCancel(context.Background())
+	r.cancel, r.cancelRuns, r.runContext = cancel, cancelRuns, runContext
+	if resume {
+		r.wg.Add(1)
+		go func() {
+			defer r.wg.Done()
+			if _, err := r.engine.ResumeRuns(runContext); err != nil {
+				log.Printf("WARNING: could not resume interrupted runs: %s", err)
+			}
+		}()
+	}
+	scheduler := engine.NewDefaultScheduler(r.config, r.engine, store, engine.WithRunContext(runContext))
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		if err := scheduler.Run(ctx); err != nil {
+			log.Printf("WARNING: scheduler stopped: %s", err)
+		}
+	}()
+}
+
+// stop waits for the scheduler and runs of a cancelled runtime, then kills its provider plugins
+func (r *runtime) stop() {
+	r.wg.Wait()
+	r.cancelRuns()
+	r.engine.Stop()
+}
+
+// deploy validates the uploaded configuration bundle, and replaces the running configuration with it. The
+// bundle is either the 'config' file of a multipart form, or the request body. It is a tar archive of
+// the configuration directory, optionally gzip compressed. A bundle with the same content hash as the
+// running deployment is not deployed again.
+func (s *Server) deploy(c *fiber.Ctx) error {
+	raw, err := readBundle(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(api.Error{Error: err.Error()})
+	}
+	fsys, err := bundle.Read(bytes.NewReader(raw))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(api.Error{Error: fmt.Sprintf("invalid bundle: %s", err)})
+	}
+	hash, err := bundle.Hash(fsys)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(api.Error{Error: fmt.Sprintf("invalid bundle: %s", err)})
+	}
+
+	s.deployMu.Lock()
+	defer s.deployMu.Unlock()
+	// bundles are compared by content hash, so deploying the running configuration again changes nothing
+	if current := s.running(); current != nil && current.deployment != nil && current.deployment.Hash == hash {
+		return c.JSON(newDeploymentResponse(current))
+	}
+	next, diag := s.newRuntime(s.config.NewBundleParser(fsys), fsys)
+	if diag.HasErrors() {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(api.Error{
+			Error:       "invalid configuration",
+			Diagnostics: newDiagnostics(diag),
+		})
+	}
+	deployment := state.Deployment{
+		ID:         state.NewID(),
+		DeployedAt: time.Now(),
+		DeployedBy: callerName(c),
+		Bundle:     raw,
+		Hash:       hash,
+	}
+	if err = s.config.Store.SaveDeployment(deployment); err != nil {
+		next.engine.Stop()
+		return c.Status(fiber.StatusInternalServerError).JSON(api.Error{Error: fmt.Sprintf("could not save deployment: %s", err)})
+	}
+	next.deployment = &deployment
+	s.swap(next)
+	message := fmt.Sprintf("deployed %s (%s) by '%s'", deployment.ID, deployment.Hash, deployment.DeployedBy)
+	log.Println(message)
+	s.events.publish(api.Event{Type: api.EventDeployment, Message: message})
+
+	return c.Status(fiber.StatusCreated).JSON(newDeploymentResponse(next))
+}
+
+// destroy stops the running configuration and deregisters its triggers. It is recorded as a deployment
+// without a bundle, so that nothing runs after a restart either.
+func (s *Server) destroy(c *fiber.Ctx) error {
+	s.deployMu.Lock()
+	defer s.deployMu.Unlock()
+	deployment := state.Deployment{
+		ID:         state.NewID(),
+		DeployedAt: time.Now(),
+		DeployedBy: callerName(c),
+	}
+	if err := s.config.Store.SaveDeployment(deployment); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(api.Error{Error: fmt.Sprintf("could not save deployment: %s", err)})
+	}
+	s.mu.Lock()
+	previous := s.current
+	s.current = nil
+	s.mu.Unlock()
+
+	response := api.Destroyed{ID: deployment.ID, Workflows: []string{}, Triggers: []string{}, Plugins: []string{}}
+	if previous != nil {
+		response.Plugins = append(response.Plugins, previous.pluginManager.LoadedPlugins()...)
+		for _, workflow := range previous.config.Workflows {
+			response.Workflows = append(response.Workflows, workflow.Name)
+		}
+		// runs that were already started finish in the background before the plugins are killed, as
+		// they do when a deploy replaces the configuration
+		previous.cancel()
+		s.retire(previous)
+	}
+	triggers, err := s.deregisterTriggers()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(api.Error{Error: err.Error()})
+	}
+	response.Triggers = append(response.Triggers, triggers...)
+	message := fmt.Sprintf("destroyed by '%s'", deployment.DeployedBy)
+	log.Println(message)
+	s.events.publish(api.Event{Type: api.EventDeployment, Message: message})
+	return c.JSON(response)
+}
+
+func readBundle(c *fiber.Ctx) ([]byte, error) {
+	file, err := c.FormFile("config")
+	if err != nil {
+		if len(c.Body()) == 0 {
+			return nil, errors.New("missing configuration bundle")
+		}
+		return bytes.Clone(c.Body()), nil
+	}
+	openFile, err := file.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer openFile.Close()
+	return io.ReadAll(openFile)
+}
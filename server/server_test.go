@@ -0,0 +1,429 @@
+package server
+
+import (
+	"archive/tar"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/switchboard-org/plugin-sdk/sbsdk"
+	"github.com/switchboard-org/switchboard/api"
+	"github.com/switchboard-org/switchboard/internal"
+	"github.com/switchboard-org/switchboard/parsecfg"
+	"github.com/switchboard-org/switchboard/providers"
+	"github.com/switchboard-org/switchboard/sbtest"
+	"github.com/switchboard-org/switchboard/state"
+	"io"
+	"io/fs"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func testPluginManager() internal.PluginManager {
+	stripe := sbtest.NewFakeProvider().
+		WithInitSchema(sbsdk.ObjectSchema{"api_key": sbsdk.RequiredAttrSchema("api_key", sbsdk.String)}).
+		WithEchoAction("create_charge", "amount")
+	return sbtest.NewFakePluginManager(map[string]sbsdk.Provider{"stripe": stripe})
+}
+
+func testParserOptions() []parsecfg.ParserOption {
+	return []parsecfg.ParserOption{
+		parsecfg.WithPluginManager(testPluginManager()),
+		parsecfg.WithDownloader(sbtest.NewFakeDownloader(providers.Package{Name: "provider-stripe", Version: "1.0.0"})),
+		parsecfg.WithOsManager(&sbtest.FakeOsManager{}),
+	}
+}
+
+func testServer(t *testing.T, store state.StateStore) *Server {
+	t.Helper()
+	s := NewServer(Config{
+		Parser: parsecfg.NewDefaultParser("../fixtures/parse_config", "", "1.2.0", testParserOptions()...),
+		NewBundleParser: func(fsys fs.FS) parsecfg.Parser {
+			return parsecfg.NewDefaultParser(".", "", "1.2.0", append(testParserOptions(), parsecfg.WithFileSystem(fsys))...)
+		},
+		NewPluginManager: testPluginManager,
+		Store:            store,
+		TokenStore:       NewFileTokenStore(filepath.Join(t.TempDir(), "tokens.json")),
+		AdminUser:        "admin",
+		AdminPassword:    "password",
+	})
+	if err := s.Start(); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	t.Cleanup(s.Stop)
+	return s
+}
+
+// testBundle creates a tar archive with the files of the parse_config fixture, and any extra files
+func testBundle(t *testing.T, extra map[string]string) []byte {
+	t.Helper()
+	main, err := os.ReadFile("../fixtures/parse_config/main.hcl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{"main.hcl": string(main)}
+	for name, data := range extra {
+		files[name] = data
+	}
+	var buf bytes.Buffer
+	writer := tar.NewWriter(&buf)
+	for name, data := range files {
+		if err = writer.WriteHeader(&tar.Header{Name: name, Typeflag: tar.TypeReg, Mode: 0644, Size: int64(len(data))}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err = writer.Write([]byte(data)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func multipartRequest(t *testing.T, archive []byte) *http.Request {
+	t.Helper()
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	part, err := writer.CreateFormFile("config", "config.tar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	part.Write(archive)
+	writer.Close()
+	req := httptest.NewRequest(http.MethodPost, "/admin/deploy", &body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	return req
+}
+
+func TestServer_deploy(t *testing.T) {
+	tests := []struct {
+		name           string
+		request        func(t *testing.T) *http.Request
+		noAuth         bool
+		wantStatus     int
+		wantDeployed   bool
+		wantDiagnostic bool
+	}{
+		{
+			name: "should deploy a valid bundle from a multipart form",
+			request: func(t *testing.T) *http.Request {
+				return multipartRequest(t, testBundle(t, map[string]string{"notify.hcl": "workflow \"notify\" {\n  step \"notify\" {\n    provider = \"stripe\"\n    action = \"create_charge\"\n    amount = \"1\"\n  }\n}\n"}))
+			},
+			wantStatus:   http.StatusCreated,
+			wantDeployed: true,
+		},
+		{
+			name: "should deploy a valid bundle from the request body",
+			request: func(t *testing.T) *http.Request {
+				return httptest.NewRequest(http.MethodPost, "/admin/deploy", bytes.NewReader(testBundle(t, nil)))
+			},
+			wantStatus:   http.StatusCreated,
+			wantDeployed: true,
+		},
+		{
+			name: "should return diagnostics for an invalid configuration",
+			request: func(t *testing.T) *http.Request {
+				return multipartRequest(t, testBundle(t, map[string]string{"broken.hcl": "workflow \"broken\" {\n  trigger = \"missing\"\n}\n"}))
+			},
+			wantStatus:     http.StatusUnprocessableEntity,
+			wantDiagnostic: true,
+		},
+		{
+			name: "should return diagnostics for a provider that is not installed, without downloading it",
+			request: func(t *testing.T) *http.Request {
+				main := `switchboard {
+  version = "~> 1.0"
+
+  required_provider "stripe" {
+    version = "2.0.0"
+    source  = "github.com/switchboard-org/provider-stripe"
+  }
+}
+`
+				return multipartRequest(t, testBundle(t, map[string]string{"main.hcl": main}))
+			},
+			wantStatus:     http.StatusUnprocessableEntity,
+			wantDiagnostic: true,
+		},
+		{
+			name: "should reject data that is not a bundle",
+			request: func(t *testing.T) *http.Request {
+				return httptest.NewRequest(http.MethodPost, "/admin/deploy", bytes.NewReader([]byte("not a bundle")))
+			},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name: "should reject requests without a bundle",
+			request: func(t *testing.T) *http.Request {
+				return httptest.NewRequest(http.MethodPost, "/admin/deploy", nil)
+			},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name: "should reject unauthenticated requests",
+			request: func(t *testing.T) *http.Request {
+				return multipartRequest(t, testBundle(t, nil))
+			},
+			noAuth:     true,
+			wantStatus: http.StatusUnauthorized,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := state.NewMemoryStateStore()
+			s := testServer(t, store)
+			previous := s.running()
+			req := tt.request(t)
+			if !tt.noAuth {
+				req.SetBasicAuth("admin", "password")
+			}
+			resp, err := s.app.Test(req, -1)
+			if err != nil {
+				t.Fatalf("Test() error = %v", err)
+			}
+			body, _ := io.ReadAll(resp.Body)
+			if resp.StatusCode != tt.wantStatus {
+				t.Fatalf("deploy status = %d, want %d: %s", resp.StatusCode, tt.wantStatus, body)
+			}
+
+			deployment, err := store.ActiveDeployment()
+			if !tt.wantDeployed {
+				if err == nil {
+					t.Errorf("ActiveDeployment() = %v, want no deployment", deployment)
+				}
+				if s.running() != previous {
+					t.Errorf("running() was replaced by a failed deploy")
+				}
+				if tt.noAuth {
+					return
+				}
+				var response api.Error
+				if err = json.Unmarshal(body, &response); err != nil {
+					t.Fatalf("could not decode error response %s: %v", body, err)
+				}
+				if tt.wantDiagnostic && (len(response.Diagnostics) == 0 || response.Diagnostics[0].Range == nil) {
+					t.Errorf("deploy diagnostics = %v, want diagnostics with a range", response.Diagnostics)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ActiveDeployment() error = %v", err)
+			}
+			var response api.Deployment
+			if err = json.Unmarshal(body, &response); err != nil {
+				t.Fatalf("could not decode deploy response %s: %v", body, err)
+			}
+			if response.ID != deployment.ID || response.Hash == "" || response.Hash != deployment.Hash || deployment.DeployedBy != "admin" {
+				t.Errorf("deploy response = %v, deployment = %v", response, deployment)
+			}
+			running := s.running()
+			if running == previous || running.deployment == nil || running.deployment.ID != deployment.ID {
+				t.Errorf("running() deployment = %v, want %s", running.deployment, deployment.ID)
+			}
+			if len(running.config.Workflows) != len(response.Workflows) {
+				t.Errorf("running() workflows = %d, want %v", len(running.config.Workflows), response.Workflows)
+			}
+			if running.snapshot == nil || running.snapshot.Hash != deployment.Hash || len(running.snapshot.Workflows) != len(response.Workflows) {
+				t.Errorf("running() snapshot = %v, want snapshot of deployment %s", running.snapshot, deployment.Hash)
+			}
+		})
+	}
+}
+
+func TestServer_deploySameBundle(t *testing.T) {
+	store := state.NewMemoryStateStore()
+	s := testServer(t, store)
+	deploy := func(archive []byte) (int, api.Deployment) {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodPost, "/admin/deploy", bytes.NewReader(archive))
+		req.SetBasicAuth("admin", "password")
+		resp, err := s.app.Test(req, -1)
+		if err != nil {
+			t.Fatalf("Test() error = %v", err)
+		}
+		var deployment api.Deployment
+		json.NewDecoder(resp.Body).Decode(&deployment)
+		return resp.StatusCode, deployment
+	}
+	status, first := deploy(testBundle(t, nil))
+	if status != http.StatusCreated {
+		t.Fatalf("first deploy status = %d, want %d", status, http.StatusCreated)
+	}
+	running := s.running()
+	// the same files in a differently built archive have the same content hash
+	status, second := deploy(testBundle(t, nil))
+	if status != http.StatusOK || second.ID != first.ID || second.Hash != first.Hash {
+		t.Errorf("second deploy = %d %v, want %d %v", status, second, http.StatusOK, first)
+	}
+	if s.running() != running {
+		t.Errorf("running() was replaced by a deploy of the same bundle")
+	}
+	status, third := deploy(testBundle(t, map[string]string{"notes.hcl": "# notes\n"}))
+	if status != http.StatusCreated || third.ID == first.ID || third.Hash == first.Hash {
+		t.Errorf("third deploy = %d %v, want a new deployment", status, third)
+	}
+}
+
+func TestServer_Start(t *testing.T) {
+	store := state.NewMemoryStateStore()
+	s := testServer(t, store)
+	if running := s.running(); running.deployment != nil {
+		t.Errorf("running() deployment = %v, want the local configuration", running.deployment)
+	}
+	req := multipartRequest(t, testBundle(t, nil))
+	req.SetBasicAuth("admin", "password")
+	if resp, err := s.app.Test(req, -1); err != nil || resp.StatusCode != http.StatusCreated {
+		t.Fatalf("deploy failed: %v %v", resp, err)
+	}
+	deployment, _ := store.ActiveDeployment()
+
+	restarted := testServer(t, store)
+	if running := restarted.running(); running.deployment == nil || running.deployment.ID != deployment.ID {
+		t.Errorf("running() after restart = %v, want deployment %s", running.deployment, deployment.ID)
+	}
+}
+
+func TestServer_authenticate(t *testing.T) {
+	s := testServer(t, state.NewMemoryStateStore())
+	deployToken, _, err := s.config.TokenStore.Create("ci", []Scope{ScopeDeploy})
+	if err != nil {
+		t.Fatal(err)
+	}
+	readToken, _, err := s.config.TokenStore.Create("dashboard", []Scope{ScopeRead})
+	if err != nil {
+		t.Fatal(err)
+	}
+	revokedToken, revoked, err := s.config.TokenStore.Create("old", []Scope{ScopeDeploy})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = s.config.TokenStore.Revoke(revoked.ID); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name         string
+		authorize    func(req *http.Request)
+		wantStatus   int
+		wantDeployer string
+	}{
+		{
+			name:         "should accept a token with the deploy scope",
+			authorize:    func(req *http.Request) { req.Header.Set("Authorization", "Bearer "+deployToken) },
+			wantStatus:   http.StatusCreated,
+			wantDeployer: "ci",
+		},
+		{
+			name:       "should forbid a token without the deploy scope",
+			authorize:  func(req *http.Request) { req.Header.Set("Authorization", "Bearer "+readToken) },
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "should reject a revoked token",
+			authorize:  func(req *http.Request) { req.Header.Set("Authorization", "Bearer "+revokedToken) },
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:         "should accept the admin credentials",
+			authorize:    func(req *http.Request) { req.SetBasicAuth("admin", "password") },
+			wantStatus:   http.StatusCreated,
+			wantDeployer: "admin",
+		},
+		{
+			name:       "should reject a wrong admin password",
+			authorize:  func(req *http.Request) { req.SetBasicAuth("admin", "admin") },
+			wantStatus: http.StatusUnauthorized,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// every case deploys different files, as the running bundle is not deployed again
+			req := multipartRequest(t, testBundle(t, map[string]string{"case.hcl": fmt.Sprintf("# %s\n", tt.name)}))
+			tt.authorize(req)
+			resp, err := s.app.Test(req, -1)
+			if err != nil {
+				t.Fatalf("Test() error = %v", err)
+			}
+			if resp.StatusCode != tt.wantStatus {
+				body, _ := io.ReadAll(resp.Body)
+				t.Fatalf("deploy status = %d, want %d: %s", resp.StatusCode, tt.wantStatus, body)
+			}
+			if tt.wantDeployer == "" {
+				return
+			}
+			var response api.Deployment
+			if err = json.NewDecoder(resp.Body).Decode(&response); err != nil {
+				t.Fatal(err)
+			}
+			if response.DeployedBy != tt.wantDeployer {
+				t.Errorf("deploy deployed_by = %s, want %s", response.DeployedBy, tt.wantDeployer)
+			}
+		})
+	}
+}
+
+func TestServer_destroy_runsInProgress(t *testing.T) {
+	s := testServer(t, state.NewMemoryStateStore())
+	previous := s.running()
+	// a run in progress, which keeps the runtime from stopping until it finishes
+	previous.wg.Add(1)
+	req := httptest.NewRequest(http.MethodPost, "/admin/destroy", nil)
+	req.SetBasicAuth("admin", "password")
+	if resp, err := s.app.Test(req, 5000); err != nil || resp.StatusCode != http.StatusOK {
+		t.Fatalf("destroy failed: %v %v", resp, err)
+	}
+	s.mu.RLock()
+	_, retired := s.retired[previous]
+	s.mu.RUnlock()
+	if !retired {
+		t.Errorf("destroy did not retire the previous runtime while its runs are in progress")
+	}
+	previous.wg.Done()
+	s.retiring.Wait()
+}
+
+func TestServer_destroy(t *testing.T) {
+	store := state.NewMemoryStateStore()
+	store.SaveTriggerRegistration(state.TriggerRegistration{Trigger: "orders", Provider: "stripe", ExternalID: "we_123"})
+	s := testServer(t, store)
+	req := httptest.NewRequest(http.MethodPost, "/admin/destroy", nil)
+	req.SetBasicAuth("admin", "password")
+	resp, err := s.app.Test(req, -1)
+	if err != nil || resp.StatusCode != http.StatusOK {
+		t.Fatalf("destroy failed: %v %v", resp, err)
+	}
+	var destroyed api.Destroyed
+	if err = json.NewDecoder(resp.Body).Decode(&destroyed); err != nil {
+		t.Fatal(err)
+	}
+	if len(destroyed.Workflows) != 1 || len(destroyed.Plugins) != 1 || len(destroyed.Triggers) != 1 || destroyed.Triggers[0] != "orders" {
+		t.Errorf("destroy response = %v", destroyed)
+	}
+	if s.running() != nil {
+		t.Errorf("running() = %v, want nothing running after destroy", s.running())
+	}
+	if registrations, _ := store.TriggerRegistrations(); len(registrations) != 0 {
+		t.Errorf("TriggerRegistrations() = %v, want all triggers deregistered", registrations)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/admin/deployment", nil)
+	req.SetBasicAuth("admin", "password")
+	if resp, err = s.app.Test(req, -1); err != nil || resp.StatusCode != http.StatusNotFound {
+		t.Errorf("deployment after destroy = %v %v, want not found", resp, err)
+	}
+	if restarted := testServer(t, store); restarted.running() != nil {
+		t.Errorf("running() after restart = %v, want nothing running after destroy", restarted.running())
+	}
+
+	req = multipartRequest(t, testBundle(t, nil))
+	req.SetBasicAuth("admin", "password")
+	if resp, err = s.app.Test(req, -1); err != nil || resp.StatusCode != http.StatusCreated {
+		t.Fatalf("deploy after destroy failed: %v %v", resp, err)
+	}
+	if s.running() == nil {
+		t.Errorf("running() = nil, want the deployment running after destroy")
+	}
+}
@@ -0,0 +1,107 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+)
+
+// DefaultDrainTimeout is how long a shutdown waits for the runs in progress by default
+const DefaultDrainTimeout = 30 * time.Second
+
+// Shutdown stops the server gracefully. It stops accepting requests and scheduling runs, then waits up to
+// the drain timeout for the runs in progress. Runs that are still in progress after that are interrupted,
+// with their completed steps saved, and resumed when the server starts again. Triggers are deregistered if
+// configured, and the provider plugins are killed last. The drain timeout is shared by the requests and the
+// runs, so the runs only get the time left after the requests have finished.
+func (s *Server) Shutdown() error {
+	deadline := time.Now().Add(s.config.DrainTimeout)
+	// the event streams are ended first, as the open connections would hold up the shutdown of the app
+	s.events.close()
+	var errs []error
+	if err := s.app.ShutdownWithTimeout(s.config.DrainTimeout); err != nil {
+		errs = append(errs, fmt.Errorf("could not stop accepting requests: %w", err))
+	}
+	s.stopWatch()
+	// a deploy in progress finishes before the runtimes are stopped
+	s.deployMu.Lock()
+	defer s.deployMu.Unlock()
+	drainTimeout := time.Until(deadline)
+	if drainTimeout < 0 {
+		drainTimeout = 0
+	}
+	s.stopRuntimes(drainTimeout)
+	if s.config.DeregisterTriggers {
+		triggers, err := s.deregisterTriggers()
+		if err != nil {
+			errs = append(errs, err)
+		}
+		log.Printf("deregistered %d triggers", len(triggers))
+	}
+	return errors.Join(errs...)
+}
+
+// stopWatch stops watching the working directory, and waits for a reload in progress
+func (s *Server) stopWatch() {
+	if s.stopWatching != nil {
+		s.stopWatching()
+		s.watching.Wait()
+	}
+}
+
+// stopRuntimes stops scheduling runs, and waits up to drainTimeout for the runs in progress before
+// interrupting them. The provider plugins of every runtime are killed once its runs have stopped. It must
+// be called with deployMu held, so that no runtime is started meanwhile.
+func (s *Server) stopRuntimes(drainTimeout time.Duration) {
+	s.mu.Lock()
+	current := s.current
+	s.current = nil
+	s.mu.Unlock()
+	if current != nil {
+		current.cancel()
+		s.retire(current)
+	}
+	s.mu.RLock()
+	runtimes := make([]*runtime, 0, len(s.retired))
+	for r := range s.retired {
+		runtimes = append(runtimes, r)
+	}
+	s.mu.RUnlock()
+
+	drained := make(chan struct{})
+	go func() {
+		s.retiring.Wait()
+		close(drained)
+	}()
+	timer := time.NewTimer(drainTimeout)
+	defer timer.Stop()
+	select {
+	case <-drained:
+		return
+	case <-timer.C:
+	}
+	if drainTimeout > 0 {
+		log.Println("WARNING: interrupting the runs that did not finish within the drain timeout, they are resumed when the server starts again")
+	}
+	for _, r := range runtimes {
+		r.cancelRuns()
+	}
+	<-drained
+}
+
+// deregisterTriggers deletes every trigger registration, and returns the names of the deregistered triggers
+func (s *Server) deregisterTriggers() ([]string, error) {
+	registrations, err := s.config.Store.TriggerRegistrations()
+	if err != nil {
+		return nil, fmt.Errorf("could not get trigger registrations: %w", err)
+	}
+	var triggers []string
+	for _, registration := range registrations {
+		if err = s.config.Store.DeleteTriggerRegistration(registration.Trigger); err != nil {
+			return triggers, fmt.Errorf("could not deregister trigger '%s': %w", registration.Trigger, err)
+		}
+		triggers = append(triggers, registration.Trigger)
+	}
+	return triggers, nil
+}
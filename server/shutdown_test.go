@@ -0,0 +1,153 @@
+package server
+
+import (
+	"context"
+	"github.com/gofiber/fiber/v2"
+	"github.com/switchboard-org/switchboard/state"
+	"github.com/zclconf/go-cty/cty"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// stoppedEngine records whether the engine was stopped
+type stoppedEngine struct {
+	stopped atomic.Bool
+}
+
+func (e *stoppedEngine) Start() error {
+	return nil
+}
+
+func (e *stoppedEngine) RunWorkflow(_ context.Context, _ string, _ cty.Value) (*state.Run, error) {
+	return nil, nil
+}
+
+func (e *stoppedEngine) ResumeRuns(_ context.Context) ([]*state.Run, error) {
+	return nil, nil
+}
+
+func (e *stoppedEngine) Stop() {
+	e.stopped.Store(true)
+}
+
+// testRuntime returns a runtime with a single run in progress, which takes runDuration unless it is
+// interrupted
+func testRuntime(runDuration time.Duration, interrupted *atomic.Bool) (*runtime, *stoppedEngine) {
+	e := &stoppedEngine{}
+	runContext, cancelRuns := context.WithCancel(context.Background())
+	r := &runtime{engine: e, cancel: func() {}, cancelRuns: cancelRuns}
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		select {
+		case <-runContext.Done():
+			interrupted.Store(true)
+		case <-time.After(runDuration):
+		}
+	}()
+	return r, e
+}
+
+func TestServer_Shutdown(t *testing.T) {
+	tests := []struct {
+		name               string
+		runDuration        time.Duration
+		retiredDuration    time.Duration
+		deregisterTriggers bool
+		wantInterrupted    bool
+		wantRegistrations  int
+	}{
+		{
+			name:              "should wait for runs that finish within the drain timeout",
+			runDuration:       10 * time.Millisecond,
+			retiredDuration:   20 * time.Millisecond,
+			wantInterrupted:   false,
+			wantRegistrations: 1,
+		},
+		{
+			name:              "should interrupt runs that do not finish within the drain timeout",
+			runDuration:       10 * time.Millisecond,
+			retiredDuration:   time.Hour,
+			wantInterrupted:   true,
+			wantRegistrations: 1,
+		},
+		{
+			name:               "should deregister triggers",
+			runDuration:        10 * time.Millisecond,
+			retiredDuration:    10 * time.Millisecond,
+			deregisterTriggers: true,
+			wantInterrupted:    false,
+			wantRegistrations:  0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := state.NewMemoryStateStore()
+			if err := store.SaveTriggerRegistration(state.TriggerRegistration{Trigger: "payments", Provider: "stripe", RegisteredAt: time.Now()}); err != nil {
+				t.Fatal(err)
+			}
+			s := NewServer(Config{Store: store, DrainTimeout: time.Second, DeregisterTriggers: tt.deregisterTriggers})
+			var interrupted atomic.Bool
+			current, currentEngine := testRuntime(tt.runDuration, &interrupted)
+			// a runtime replaced by a deploy, which is still finishing its runs
+			retired, retiredEngine := testRuntime(tt.retiredDuration, &interrupted)
+			s.current = current
+			s.retire(retired)
+
+			startedAt := time.Now()
+			if err := s.Shutdown(); err != nil {
+				t.Fatalf("Shutdown() error = %v", err)
+			}
+			if interrupted.Load() != tt.wantInterrupted {
+				t.Errorf("Shutdown() interrupted runs = %v, want %v", interrupted.Load(), tt.wantInterrupted)
+			}
+			if elapsed := time.Since(startedAt); elapsed > 2*time.Second {
+				t.Errorf("Shutdown() took %s, want at most the drain timeout", elapsed)
+			}
+			if !currentEngine.stopped.Load() || !retiredEngine.stopped.Load() {
+				t.Errorf("Shutdown() did not stop every engine")
+			}
+			if s.running() != nil {
+				t.Errorf("running() = %v, want nothing running", s.running())
+			}
+			if registrations, _ := store.TriggerRegistrations(); len(registrations) != tt.wantRegistrations {
+				t.Errorf("TriggerRegistrations() = %v, want %d", registrations, tt.wantRegistrations)
+			}
+		})
+	}
+}
+
+func TestServer_Shutdown_sharedDrainTimeout(t *testing.T) {
+	s := NewServer(Config{Store: state.NewMemoryStateStore(), DrainTimeout: 500 * time.Millisecond})
+	handling := make(chan struct{})
+	s.app.Get("/slow", func(c *fiber.Ctx) error {
+		close(handling)
+		time.Sleep(400 * time.Millisecond)
+		return nil
+	})
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go s.app.Listener(listener)
+	go http.Get("http://" + listener.Addr().String() + "/slow")
+	<-handling
+
+	var interrupted atomic.Bool
+	current, _ := testRuntime(time.Hour, &interrupted)
+	s.current = current
+	startedAt := time.Now()
+	if err := s.Shutdown(); err != nil {
+		t.Fatalf("Shutdown() error = %v", err)
+	}
+	if !interrupted.Load() {
+		t.Errorf("Shutdown() did not interrupt the run in progress")
+	}
+	// the request takes most of the drain timeout, which leaves little time for the run
+	if elapsed := time.Since(startedAt); elapsed > 750*time.Millisecond {
+		t.Errorf("Shutdown() took %s, want at most the drain timeout", elapsed)
+	}
+}
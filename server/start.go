@@ -1,46 +1,36 @@
 package server
 
 import (
-	"github.com/gofiber/fiber/v2"
-	"github.com/gofiber/fiber/v2/middleware/basicauth"
-	"github.com/switchboard-org/switchboard/parsecfg"
-	"io"
+	"context"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
 )
 
-func StartServer(parser parsecfg.Parser) error {
-	app := fiber.New()
-	adminGroup := app.Group("/admin")
-	adminGroup.Use(basicauth.New(basicauth.Config{
-		Users: map[string]string{
-			"admin": "password",
-		},
-	}))
-	apiGroup := app.Group("/api")
-
-	adminGroup.Get("/")
-	apiGroup.Put("/upload_blob", func(c *fiber.Ctx) error {
-		parser.Parse()
-		file, err := c.FormFile("config")
-		if err != nil {
-			return err
-		}
-		openFile, err := file.Open()
-		if err != nil {
-			return err
-		}
-		io.ReadAll(openFile)
-		return nil
-	})
-
-	return nil
-	//TODO: Register admin endpoints (deploy, log stream, trigger list, workflow list, deployed sha)
-
-	//TODO: Init config if present
-	//TODO: parse config if present
-
-	//TODO: register all plugins and add to shared map
-
-	//TODO: register all workflow processors
-
-	//TODO: register all triggers (webhooks) - pass list of workflows that rely on them
+// StartServer runs the configuration and serves the admin endpoints on addr, until the server fails or the
+// process receives SIGINT or SIGTERM, which shut the server down gracefully. A second signal during the
+// shutdown exits the process immediately.
+func StartServer(config Config, addr string) error {
+	s := NewServer(config)
+	if err := s.Start(); err != nil {
+		s.Stop()
+		return err
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	listenErr := make(chan error, 1)
+	go func() {
+		listenErr <- s.Listen(addr)
+	}()
+	select {
+	case err := <-listenErr:
+		s.Stop()
+		return err
+	case <-ctx.Done():
+	}
+	// restores the default behaviour of the signals, so that another one exits immediately
+	stop()
+	log.Printf("shutting down, waiting up to %s for runs in progress", config.DrainTimeout)
+	return s.Shutdown()
 }
@@ -0,0 +1,51 @@
+package server
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"github.com/gofiber/fiber/v2"
+	"github.com/switchboard-org/switchboard/api"
+	"time"
+)
+
+// keepAliveInterval is how often a comment is sent on an idle event stream, so that proxies keep it open
+const keepAliveInterval = 15 * time.Second
+
+// stream sends the events of the server as Server-Sent Events, optionally filtered by the workflow and run_id
+// query parameters. It ends when the client disconnects or the server stops.
+func (s *Server) stream(c *fiber.Ctx) error {
+	sub := s.events.subscribe(api.EventFilter{Workflow: c.Query("workflow"), RunID: c.Query("run_id")})
+	c.Set(fiber.HeaderContentType, "text/event-stream")
+	c.Set(fiber.HeaderCacheControl, "no-cache")
+	c.Set(fiber.HeaderConnection, "keep-alive")
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		defer s.events.unsubscribe(sub)
+		// the headers are sent with the first flush, which tells the client that the stream is connected
+		fmt.Fprint(w, ": connected\n\n")
+		if err := w.Flush(); err != nil {
+			return
+		}
+		ticker := time.NewTicker(keepAliveInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case event, ok := <-sub.events:
+				if !ok {
+					return
+				}
+				data, err := json.Marshal(event)
+				if err != nil {
+					continue
+				}
+				fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Type, data)
+			case <-ticker.C:
+				fmt.Fprint(w, ": keep-alive\n\n")
+			}
+			if err := w.Flush(); err != nil {
+				return
+			}
+		}
+	})
+	return nil
+}
@@ -0,0 +1,68 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"github.com/switchboard-org/switchboard/api"
+	"github.com/switchboard-org/switchboard/client"
+	"github.com/switchboard-org/switchboard/state"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestServer_stream(t *testing.T) {
+	s := testServer(t, state.NewMemoryStateStore())
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go s.app.Listener(listener)
+	t.Cleanup(func() {
+		// stopping the server ends the event stream, which would otherwise keep the listener open
+		s.Stop()
+		s.app.Shutdown()
+	})
+	token, _, err := s.config.TokenStore.Create("test", []Scope{ScopeRead})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	received := make(chan api.Event, 10)
+	streamErr := make(chan error, 1)
+	go func() {
+		c := client.NewClient("http://"+listener.Addr().String(), token)
+		streamErr <- c.Stream(ctx, api.EventFilter{Workflow: "charge"}, func(event api.Event) error {
+			received <- event
+			return nil
+		})
+	}()
+
+	// events are published until the stream is subscribed, as the client connects in the background
+	want := api.Event{Type: api.EventRun, Workflow: "charge", RunID: "run-1", Status: "running"}
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			s.events.publish(api.Event{Type: api.EventRun, Workflow: "other", RunID: "run-2", Status: "running"})
+			s.events.publish(want)
+			continue
+		case got := <-received:
+			if got.Workflow != want.Workflow || got.RunID != want.RunID || got.Status != want.Status {
+				t.Fatalf("Stream() event = %v, want %v", got, want)
+			}
+		case err := <-streamErr:
+			t.Fatalf("Stream() error = %v", err)
+		case <-ctx.Done():
+			t.Fatal("Stream() received no events")
+		}
+		break
+	}
+	cancel()
+	if err := <-streamErr; !errors.Is(err, context.Canceled) {
+		t.Errorf("Stream() error = %v, want context.Canceled", err)
+	}
+}
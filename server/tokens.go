@@ -0,0 +1,196 @@
+package server
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/switchboard-org/switchboard/state"
+	"golang.org/x/exp/slices"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultTokenFile is where the API tokens of the server are kept, relative to the working directory
+const DefaultTokenFile = "./.switchboard/tokens.json"
+
+// tokenPrefix starts every API token, so that leaked tokens are easy to recognize
+const tokenPrefix = "sbt_"
+
+// Scope is a permission granted to an API token
+type Scope string
+
+const (
+	// ScopeDeploy allows deploying configuration bundles
+	ScopeDeploy Scope = "deploy"
+	// ScopeRead allows reading the deployment, workflows, triggers and runs of the server
+	ScopeRead Scope = "read"
+	// ScopeRunsWrite allows starting and cancelling workflow runs
+	ScopeRunsWrite Scope = "runs:write"
+)
+
+// Scopes are all scopes a token can be granted
+var Scopes = []Scope{ScopeDeploy, ScopeRead, ScopeRunsWrite}
+
+// ErrInvalidToken is returned by a TokenStore when a token does not exist or its secret does not match
+var ErrInvalidToken = errors.New("invalid token")
+
+// APIToken is a token that authenticates requests to the admin endpoints. Only the hash of its secret is kept.
+type APIToken struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	Hash      string    `json:"hash"`
+	Scopes    []Scope   `json:"scopes"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// HasScope reports whether the token was granted scope
+func (t *APIToken) HasScope(scope Scope) bool {
+	return slices.Contains(t.Scopes, scope)
+}
+
+type TokenStore interface {
+	// Create creates a token with the given scopes, and returns it along with the secret used to authenticate
+	// with it. The secret can not be recovered later.
+	Create(name string, scopes []Scope) (string, *APIToken, error)
+	// Revoke deletes a token, or returns state.ErrNotFound if it does not exist
+	Revoke(id string) error
+	List() ([]APIToken, error)
+	// Verify returns the token of a secret, or ErrInvalidToken
+	Verify(secret string) (*APIToken, error)
+}
+
+// FileTokenStore keeps API tokens in a JSON file. The file is read on every call, so tokens can be managed
+// with 'switchboard server token' while the server is running.
+type FileTokenStore struct {
+	path string
+	mu   sync.Mutex
+}
+
+func NewFileTokenStore(path string) TokenStore {
+	return &FileTokenStore{path: path}
+}
+
+func (s *FileTokenStore) Create(name string, scopes []Scope) (string, *APIToken, error) {
+	if name == "" {
+		return "", nil, errors.New("token name must not be empty")
+	}
+	if len(scopes) == 0 {
+		return "", nil, errors.New("token must have at least one scope")
+	}
+	for _, scope := range scopes {
+		if !slices.Contains(Scopes, scope) {
+			return "", nil, fmt.Errorf("unknown scope '%s'", scope)
+		}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	tokens, err := s.read()
+	if err != nil {
+		return "", nil, err
+	}
+	raw := make([]byte, 32)
+	if _, err = rand.Read(raw); err != nil {
+		return "", nil, err
+	}
+	token := APIToken{
+		ID:        state.NewID()[:16],
+		Name:      name,
+		Scopes:    scopes,
+		CreatedAt: time.Now(),
+	}
+	secret := hex.EncodeToString(raw)
+	token.Hash = hashSecret(secret)
+	tokens = append(tokens, token)
+	if err = s.write(tokens); err != nil {
+		return "", nil, err
+	}
+	return tokenPrefix + token.ID + "_" + secret, &token, nil
+}
+
+func (s *FileTokenStore) Revoke(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	tokens, err := s.read()
+	if err != nil {
+		return err
+	}
+	index := slices.IndexFunc(tokens, func(token APIToken) bool { return token.ID == id })
+	if index < 0 {
+		return state.ErrNotFound
+	}
+	return s.write(slices.Delete(tokens, index, index+1))
+}
+
+func (s *FileTokenStore) List() ([]APIToken, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.read()
+}
+
+func (s *FileTokenStore) Verify(value string) (*APIToken, error) {
+	id, secret, ok := strings.Cut(strings.TrimPrefix(value, tokenPrefix), "_")
+	if !ok || !strings.HasPrefix(value, tokenPrefix) {
+		return nil, ErrInvalidToken
+	}
+	tokens, err := s.List()
+	if err != nil {
+		return nil, err
+	}
+	for _, token := range tokens {
+		if token.ID == id && subtle.ConstantTimeCompare([]byte(token.Hash), []byte(hashSecret(secret))) == 1 {
+			return &token, nil
+		}
+	}
+	return nil, ErrInvalidToken
+}
+
+func (s *FileTokenStore) read() ([]APIToken, error) {
+	raw, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not read token file: %w", err)
+	}
+	var tokens []APIToken
+	if err = json.Unmarshal(raw, &tokens); err != nil {
+		return nil, fmt.Errorf("could not decode token file: %w", err)
+	}
+	return tokens, nil
+}
+
+// write replaces the token file, renaming a temporary file so that the server never reads a partial file
+func (s *FileTokenStore) write(tokens []APIToken) error {
+	raw, err := json.MarshalIndent(tokens, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(s.path), 0o700); err != nil {
+		return fmt.Errorf("could not create token directory: %w", err)
+	}
+	temp, err := os.CreateTemp(filepath.Dir(s.path), ".tokens-*.json")
+	if err != nil {
+		return fmt.Errorf("could not write token file: %w", err)
+	}
+	defer os.Remove(temp.Name())
+	if _, err = temp.Write(raw); err != nil {
+		temp.Close()
+		return fmt.Errorf("could not write token file: %w", err)
+	}
+	if err = temp.Close(); err != nil {
+		return fmt.Errorf("could not write token file: %w", err)
+	}
+	return os.Rename(temp.Name(), s.path)
+}
+
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
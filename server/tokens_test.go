@@ -0,0 +1,108 @@
+package server
+
+import (
+	"errors"
+	"github.com/switchboard-org/switchboard/state"
+	"path/filepath"
+	"testing"
+)
+
+func TestFileTokenStore_Create(t *testing.T) {
+	tests := []struct {
+		name      string
+		tokenName string
+		scopes    []Scope
+		wantErr   bool
+	}{
+		{
+			name:      "should create a token with scopes",
+			tokenName: "ci",
+			scopes:    []Scope{ScopeDeploy, ScopeRead},
+		},
+		{
+			name:      "should fail without a name",
+			tokenName: "",
+			scopes:    []Scope{ScopeRead},
+			wantErr:   true,
+		},
+		{
+			name:      "should fail without scopes",
+			tokenName: "ci",
+			wantErr:   true,
+		},
+		{
+			name:      "should fail for unknown scopes",
+			tokenName: "ci",
+			scopes:    []Scope{"admin"},
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := NewFileTokenStore(filepath.Join(t.TempDir(), "tokens.json"))
+			secret, token, err := store.Create(tt.tokenName, tt.scopes)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Create() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			verified, err := store.Verify(secret)
+			if err != nil || verified.ID != token.ID || !verified.HasScope(ScopeDeploy) {
+				t.Errorf("Verify() = %v, %v, want token %s", verified, err, token.ID)
+			}
+			if token.Hash == "" || token.Hash == secret {
+				t.Errorf("Create() hash = %s, want the hash of the secret", token.Hash)
+			}
+		})
+	}
+}
+
+func TestFileTokenStore_Verify(t *testing.T) {
+	store := NewFileTokenStore(filepath.Join(t.TempDir(), "tokens.json"))
+	secret, token, err := store.Create("ci", []Scope{ScopeRead})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name   string
+		secret string
+	}{
+		{name: "should reject a wrong secret", secret: tokenPrefix + token.ID + "_wrong"},
+		{name: "should reject an unknown token", secret: tokenPrefix + "unknown_" + secret[len(tokenPrefix)+len(token.ID)+1:]},
+		{name: "should reject a value without the token prefix", secret: secret[len(tokenPrefix):]},
+		{name: "should reject an empty value", secret: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, err := store.Verify(tt.secret); !errors.Is(err, ErrInvalidToken) {
+				t.Errorf("Verify() = %v, %v, want ErrInvalidToken", got, err)
+			}
+		})
+	}
+}
+
+func TestFileTokenStore_Revoke(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tokens.json")
+	store := NewFileTokenStore(path)
+	secret, token, err := store.Create("ci", []Scope{ScopeRead})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err = store.Create("other", []Scope{ScopeRead}); err != nil {
+		t.Fatal(err)
+	}
+	if err = store.Revoke(token.ID); err != nil {
+		t.Fatalf("Revoke() error = %v", err)
+	}
+	if err = store.Revoke(token.ID); !errors.Is(err, state.ErrNotFound) {
+		t.Errorf("Revoke() of a revoked token error = %v, want ErrNotFound", err)
+	}
+	if _, err = NewFileTokenStore(path).Verify(secret); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("Verify() of a revoked token error = %v, want ErrInvalidToken", err)
+	}
+	tokens, err := store.List()
+	if err != nil || len(tokens) != 1 || tokens[0].Name != "other" {
+		t.Errorf("List() = %v, %v, want only the other token", tokens, err)
+	}
+}
@@ -0,0 +1,58 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"github.com/gofiber/fiber/v2"
+	"github.com/switchboard-org/switchboard/api"
+	"github.com/switchboard-org/switchboard/engine"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
+	"log"
+	"strconv"
+	"time"
+)
+
+// trigger starts the workflows of a provider trigger with the JSON payload of the request, once the payload
+// is validated against the schema of the trigger. The workflows run in the background.
+func (s *Server) trigger(c *fiber.Ctx) error {
+	startedAt := time.Now()
+	name := c.Params("name")
+	// the read lock keeps the runtime from being replaced before its runs are added to its wait group
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	current := s.current
+	if current == nil {
+		return c.Status(fiber.StatusNotFound).JSON(api.Error{Error: "nothing is deployed"})
+	}
+	trigger := current.config.Trigger(name)
+	if trigger == nil || trigger.Schedule != nil {
+		return c.Status(fiber.StatusNotFound).JSON(api.Error{Error: fmt.Sprintf("trigger '%s' does not exist", name)})
+	}
+	defer func() {
+		s.metrics.triggerRequests.WithLabelValues(name, strconv.Itoa(c.Response().StatusCode())).Inc()
+		s.metrics.triggerDuration.WithLabelValues(name).Observe(time.Since(startedAt).Seconds())
+	}()
+	var payload ctyjson.SimpleJSONValue
+	if err := payload.UnmarshalJSON(c.Body()); err != nil {
+		s.metrics.validationFailures.WithLabelValues(name).Inc()
+		return c.Status(fiber.StatusBadRequest).JSON(api.Error{Error: fmt.Sprintf("could not parse payload: %s", err)})
+	}
+	if errs := trigger.ValidatePayload(payload.Value); len(errs) > 0 {
+		s.metrics.validationFailures.WithLabelValues(name).Inc()
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(api.Error{
+			Error: fmt.Sprintf("payload does not match trigger '%s': %s", name, errors.Join(errs...)),
+		})
+	}
+	workflows := current.config.TriggeredWorkflows(name)
+	for _, workflow := range workflows {
+		current.wg.Add(1)
+		go func(workflow string) {
+			defer current.wg.Done()
+			_, err := current.engine.RunWorkflow(current.runContext, workflow, payload.Value)
+			if err != nil && !errors.Is(err, engine.ErrRunInterrupted) {
+				log.Printf("WARNING: could not run workflow '%s' of trigger '%s': %s", workflow, name, err)
+			}
+		}(workflow)
+	}
+	return c.Status(fiber.StatusAccepted).JSON(api.Triggered{Trigger: name, Workflows: append([]string{}, workflows...)})
+}
@@ -0,0 +1,122 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/switchboard-org/switchboard/api"
+	"github.com/switchboard-org/switchboard/internal"
+	"github.com/switchboard-org/switchboard/state"
+	"github.com/zclconf/go-cty/cty"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// triggeredEngine records the workflows it runs
+type triggeredEngine struct {
+	stoppedEngine
+	mu        sync.Mutex
+	workflows []string
+}
+
+func (e *triggeredEngine) RunWorkflow(_ context.Context, workflow string, _ cty.Value) (*state.Run, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.workflows = append(e.workflows, workflow)
+	return &state.Run{Workflow: workflow, Status: state.StatusSucceeded}, nil
+}
+
+func TestServer_trigger(t *testing.T) {
+	config := &internal.RootSwitchboardConfig{
+		Triggers: []internal.TriggerBlock{
+			{Name: "order_created", Provider: "shop", Function: "order_created"},
+			{Name: "nightly", Schedule: &internal.ScheduleBlock{}},
+		},
+		Workflows: []internal.WorkflowBlock{
+			{Name: "charge", Trigger: "order_created"},
+			{Name: "report", Trigger: "nightly"},
+		},
+	}
+	tests := []struct {
+		name          string
+		trigger       string
+		body          string
+		wantStatus    int
+		wantWorkflows []string
+		wantMetrics   []string
+	}{
+		{
+			name:          "should start the workflows of the trigger",
+			trigger:       "order_created",
+			body:          `{"id":"1"}`,
+			wantStatus:    http.StatusAccepted,
+			wantWorkflows: []string{"charge"},
+			wantMetrics:   []string{`switchboard_trigger_requests_total{code="202",trigger="order_created"} 1`},
+		},
+		{
+			name:        "should reject payloads that are not JSON",
+			trigger:     "order_created",
+			body:        `{`,
+			wantStatus:  http.StatusBadRequest,
+			wantMetrics: []string{`switchboard_trigger_validation_failures_total{trigger="order_created"} 1`},
+		},
+		{
+			name:       "should reject payloads that do not match the trigger",
+			trigger:    "order_created",
+			body:       `null`,
+			wantStatus: http.StatusUnprocessableEntity,
+			wantMetrics: []string{
+				`switchboard_trigger_requests_total{code="422",trigger="order_created"} 1`,
+				`switchboard_trigger_validation_failures_total{trigger="order_created"} 1`,
+			},
+		},
+		{
+			name:       "should return not found for schedule triggers",
+			trigger:    "nightly",
+			body:       `{}`,
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "should return not found for unknown triggers",
+			trigger:    "unknown",
+			body:       `{}`,
+			wantStatus: http.StatusNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewServer(Config{Store: state.NewMemoryStateStore(), NewPluginManager: testPluginManager})
+			e := &triggeredEngine{}
+			r := &runtime{config: config, engine: e, runContext: context.Background()}
+			s.current = r
+			req := httptest.NewRequest(http.MethodPost, triggerPath+tt.trigger, strings.NewReader(tt.body))
+			resp, err := s.app.Test(req, -1)
+			if err != nil {
+				t.Fatalf("Test() error = %v", err)
+			}
+			r.wg.Wait()
+			if resp.StatusCode != tt.wantStatus {
+				t.Fatalf("POST %s status = %d, want %d", req.URL.Path, resp.StatusCode, tt.wantStatus)
+			}
+			if tt.wantWorkflows != nil {
+				var got api.Triggered
+				json.NewDecoder(resp.Body).Decode(&got)
+				if !reflect.DeepEqual(got.Workflows, tt.wantWorkflows) {
+					t.Errorf("response workflows = %v, want %v", got.Workflows, tt.wantWorkflows)
+				}
+			}
+			if !reflect.DeepEqual(e.workflows, tt.wantWorkflows) {
+				t.Errorf("workflows run = %v, want %v", e.workflows, tt.wantWorkflows)
+			}
+			out := gatherMetrics(t, s.metrics)
+			for _, want := range tt.wantMetrics {
+				if !strings.Contains(out, want) {
+					t.Errorf("metrics do not contain %q:\n%s", want, out)
+				}
+			}
+		})
+	}
+}
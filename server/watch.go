@@ -0,0 +1,66 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"github.com/switchboard-org/switchboard/api"
+	"github.com/switchboard-org/switchboard/bundle"
+	"log"
+	"time"
+)
+
+// DefaultWatchInterval is how often the working directory is checked for changes when watching it
+const DefaultWatchInterval = time.Second
+
+// watch reloads the configuration in the working directory every time its files change, until ctx is
+// cancelled. The files are compared by the content hash of their bundle, starting from hash.
+func (s *Server) watch(ctx context.Context, hash string) {
+	ticker := time.NewTicker(s.config.WatchInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		next, err := s.reload(hash)
+		if err != nil {
+			log.Printf("WARNING: could not reload configuration: %s", err)
+			continue
+		}
+		hash = next
+	}
+}
+
+// reload replaces the running configuration with the configuration in the working directory, if its content
+// hash is not the given one. Runs that were already started finish with the previous configuration. An
+// invalid configuration is logged, and the previous configuration keeps running. It returns the content hash
+// of the files in the working directory.
+func (s *Server) reload(hash string) (string, error) {
+	fsys, err := s.workingDirFS()
+	if err != nil {
+		return hash, err
+	}
+	next, err := bundle.Hash(fsys)
+	if err != nil || next == hash {
+		return hash, err
+	}
+
+	s.deployMu.Lock()
+	defer s.deployMu.Unlock()
+	log.Println("configuration changed, reloading")
+	reloaded, diag := s.newRuntime(s.config.NewBundleParser(fsys), fsys)
+	if diag.HasErrors() {
+		for _, err := range diag.Errs() {
+			log.Println(err)
+		}
+		log.Println("WARNING: the configuration is invalid, the previous configuration keeps running")
+		// the same invalid files are not parsed again until they change
+		return next, nil
+	}
+	s.swap(reloaded)
+	message := fmt.Sprintf("reloaded configuration (%s)", next)
+	log.Println(message)
+	s.events.publish(api.Event{Type: api.EventDeployment, Message: message})
+	return next, nil
+}
@@ -0,0 +1,105 @@
+package server
+
+import (
+	"github.com/switchboard-org/switchboard/parsecfg"
+	"github.com/switchboard-org/switchboard/state"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestServer_reload(t *testing.T) {
+	dir := t.TempDir()
+	main, err := os.ReadFile("../fixtures/parse_config/main.hcl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeFile := func(name string, data string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeFile("main.hcl", string(main))
+	store := state.NewMemoryStateStore()
+	// an active deployment is not run while the working directory is watched
+	if err = store.SaveDeployment(state.Deployment{ID: "deployed", DeployedAt: time.Now()}); err != nil {
+		t.Fatal(err)
+	}
+	s := NewServer(Config{
+		Parser:     parsecfg.NewDefaultParser(dir, "", "1.2.0", testParserOptions()...),
+		WorkingDir: dir,
+		// changes are applied by calling reload, rather than waiting for the watcher
+		WatchInterval: time.Hour,
+		NewBundleParser: func(fsys fs.FS) parsecfg.Parser {
+			return parsecfg.NewDefaultParser(".", "", "1.2.0", append(testParserOptions(), parsecfg.WithFileSystem(fsys))...)
+		},
+		NewPluginManager: testPluginManager,
+		Store:            store,
+	})
+	if err = s.Start(); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	t.Cleanup(s.Stop)
+	if s.running() == nil || s.running().deployment != nil || s.running().snapshot == nil {
+		t.Fatalf("Start() did not run the configuration in the working directory")
+	}
+
+	tests := []struct {
+		name          string
+		edit          func()
+		wantReload    bool
+		wantWorkflows int
+	}{
+		{
+			name:          "should keep running when nothing changed",
+			edit:          func() {},
+			wantReload:    false,
+			wantWorkflows: 1,
+		},
+		{
+			name: "should apply a valid change",
+			edit: func() {
+				writeFile("notify.hcl", "workflow \"notify\" {\n  step \"notify\" {\n    provider = \"stripe\"\n    action = \"create_charge\"\n    amount = \"1\"\n  }\n}\n")
+			},
+			wantReload:    true,
+			wantWorkflows: 2,
+		},
+		{
+			name:          "should keep the previous configuration after an invalid change",
+			edit:          func() { writeFile("broken.hcl", "workflow \"broken\" {\n  trigger = \"missing\"\n}\n") },
+			wantReload:    false,
+			wantWorkflows: 2,
+		},
+		{
+			name:          "should apply the next valid change",
+			edit:          func() { os.Remove(filepath.Join(dir, "broken.hcl")); os.Remove(filepath.Join(dir, "notify.hcl")) },
+			wantReload:    true,
+			wantWorkflows: 1,
+		},
+	}
+	hash := s.running().snapshot.Hash
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			previous := s.running()
+			tt.edit()
+			next, err := s.reload(hash)
+			if err != nil {
+				t.Fatalf("reload() error = %v", err)
+			}
+			hash = next
+			current := s.running()
+			if (current != previous) != tt.wantReload {
+				t.Errorf("reload() replaced running configuration = %v, want %v", current != previous, tt.wantReload)
+			}
+			if len(current.config.Workflows) != tt.wantWorkflows {
+				t.Errorf("reload() workflows = %d, want %d", len(current.config.Workflows), tt.wantWorkflows)
+			}
+			if tt.wantReload && current.snapshot.Hash != hash {
+				t.Errorf("reload() snapshot hash = %s, want %s", current.snapshot.Hash, hash)
+			}
+		})
+	}
+}
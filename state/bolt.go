@@ -0,0 +1,353 @@
+package state
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	bolt "go.etcd.io/bbolt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+var (
+	metaBucket          = []byte("meta")
+	deploymentsBucket   = []byte("deployments")
+	runsBucket          = []byte("runs")
+	stepResultsBucket   = []byte("step_results")
+	registrationsBucket = []byte("trigger_registrations")
+	kvBucket            = []byte("kv")
+	// bundlesBucket holds the bundle of every deployment, so that deployments can be read without their bundle
+	bundlesBucket = []byte("bundles")
+	// deploymentsByTimeBucket and runsByTimeBucket map time ordered keys to the ids of deployments and
+	// runs, so the newest can be read with a cursor instead of decoding every record
+	deploymentsByTimeBucket = []byte("deployments_by_time")
+	runsByTimeBucket        = []byte("runs_by_time")
+
+	schemaVersionKey = []byte("schema_version")
+)
+
+// migrations are applied in order when the store is opened, and the number of applied migrations
+// is kept as the schema version of the database. Existing migrations must never be changed, new
+// ones are appended to the end of the list.
+var migrations = []func(tx *bolt.Tx) error{
+	// 1: initial schema
+	func(tx *bolt.Tx) error {
+		for _, bucket := range [][]byte{deploymentsBucket, runsBucket, stepResultsBucket, registrationsBucket, kvBucket} {
+			if _, err := tx.CreateBucketIfNotExists(bucket); err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+	// 2: bundles are stored apart from their deployment, and deployments and runs are indexed by time
+	func(tx *bolt.Tx) error {
+		for _, bucket := range [][]byte{bundlesBucket, deploymentsByTimeBucket, runsByTimeBucket} {
+			if _, err := tx.CreateBucketIfNotExists(bucket); err != nil {
+				return err
+			}
+		}
+		var deployments []Deployment
+		err := tx.Bucket(deploymentsBucket).ForEach(func(_, raw []byte) error {
+			var deployment Deployment
+			if err := json.Unmarshal(raw, &deployment); err != nil {
+				return err
+			}
+			deployments = append(deployments, deployment)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		for _, deployment := range deployments {
+			if err := putDeployment(tx, deployment); err != nil {
+				return err
+			}
+		}
+		return tx.Bucket(runsBucket).ForEach(func(_, raw []byte) error {
+			var run Run
+			if err := json.Unmarshal(raw, &run); err != nil {
+				return err
+			}
+			return tx.Bucket(runsByTimeBucket).Put(timeKey(run.StartedAt, run.ID), []byte(run.ID))
+		})
+	},
+}
+
+// BoltStateStore is a StateStore kept in a single bbolt database file. Values are stored as JSON.
+type BoltStateStore struct {
+	db *bolt.DB
+}
+
+// NewBoltStateStore opens (or creates) the state database in dir and migrates it to the latest schema
+func NewBoltStateStore(dir string) (StateStore, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("could not create state directory: %w", err)
+	}
+	db, err := bolt.Open(filepath.Join(dir, "state.db"), 0o600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("could not open state database: %w", err)
+	}
+	if err := migrate(db, migrations); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &BoltStateStore{db: db}, nil
+}
+
+func migrate(db *bolt.DB, migrations []func(tx *bolt.Tx) error) error {
+	return db.Update(func(tx *bolt.Tx) error {
+		meta, err := tx.CreateBucketIfNotExists(metaBucket)
+		if err != nil {
+			return err
+		}
+		version := 0
+		if raw := meta.Get(schemaVersionKey); raw != nil {
+			version = int(binary.BigEndian.Uint64(raw))
+		}
+		if version > len(migrations) {
+			return fmt.Errorf("state database schema version %d is newer than the supported version %d", version, len(migrations))
+		}
+		for i := version; i < len(migrations); i++ {
+			if err := migrations[i](tx); err != nil {
+				return fmt.Errorf("could not apply state migration %d: %w", i+1, err)
+			}
+		}
+		raw := make([]byte, 8)
+		binary.BigEndian.PutUint64(raw, uint64(len(migrations)))
+		return meta.Put(schemaVersionKey, raw)
+	})
+}
+
+func put(tx *bolt.Tx, bucket []byte, key string, value any) error {
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return tx.Bucket(bucket).Put([]byte(key), raw)
+}
+
+func get(tx *bolt.Tx, bucket []byte, key string, value any) error {
+	raw := tx.Bucket(bucket).Get([]byte(key))
+	if raw == nil {
+		return ErrNotFound
+	}
+	return json.Unmarshal(raw, value)
+}
+
+// timeKey orders the keys of an index by time, then by id
+func timeKey(t time.Time, id string) []byte {
+	return []byte(t.UTC().Format("20060102T150405.000000000") + "/" + id)
+}
+
+// newestFirst calls fn with the ids of an index, from the newest to the oldest, until fn returns false
+func newestFirst(tx *bolt.Tx, index []byte, fn func(id string) (bool, error)) error {
+	cursor := tx.Bucket(index).Cursor()
+	for key, id := cursor.Last(); key != nil; key, id = cursor.Prev() {
+		next, err := fn(string(id))
+		if err != nil || !next {
+			return err
+		}
+	}
+	return nil
+}
+
+// stepResultKey groups step results by run, so they can be listed with a prefix scan
+func stepResultKey(runID string, step string) string {
+	return runID + "/" + step
+}
+
+func (s *BoltStateStore) SaveDeployment(deployment Deployment) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return putDeployment(tx, deployment)
+	})
+}
+
+// putDeployment stores the deployment without its bundle, which is stored in the bundles bucket
+func putDeployment(tx *bolt.Tx, deployment Deployment) error {
+	var previous Deployment
+	if err := get(tx, deploymentsBucket, deployment.ID, &previous); err == nil {
+		if err = tx.Bucket(deploymentsByTimeBucket).Delete(timeKey(previous.DeployedAt, previous.ID)); err != nil {
+			return err
+		}
+	}
+	if err := tx.Bucket(bundlesBucket).Put([]byte(deployment.ID), deployment.Bundle); err != nil {
+		return err
+	}
+	if err := tx.Bucket(deploymentsByTimeBucket).Put(timeKey(deployment.DeployedAt, deployment.ID), []byte(deployment.ID)); err != nil {
+		return err
+	}
+	deployment.Bundle = nil
+	return put(tx, deploymentsBucket, deployment.ID, deployment)
+}
+
+func getDeployment(tx *bolt.Tx, id string) (Deployment, error) {
+	var deployment Deployment
+	if err := get(tx, deploymentsBucket, id, &deployment); err != nil {
+		return deployment, err
+	}
+	if bundle := tx.Bucket(bundlesBucket).Get([]byte(id)); len(bundle) > 0 {
+		deployment.Bundle = append([]byte{}, bundle...)
+	}
+	return deployment, nil
+}
+
+func (s *BoltStateStore) ActiveDeployment() (*Deployment, error) {
+	var active *Deployment
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return newestFirst(tx, deploymentsByTimeBucket, func(id string) (bool, error) {
+			deployment, err := getDeployment(tx, id)
+			active = &deployment
+			return false, err
+		})
+	})
+	if err == nil && active == nil {
+		err = ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return active, nil
+}
+
+func (s *BoltStateStore) Deployments() ([]Deployment, error) {
+	var output []Deployment
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return newestFirst(tx, deploymentsByTimeBucket, func(id string) (bool, error) {
+			deployment, err := getDeployment(tx, id)
+			output = append(output, deployment)
+			return true, err
+		})
+	})
+	return output, err
+}
+
+func (s *BoltStateStore) SaveRun(run Run) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		var previous Run
+		if err := get(tx, runsBucket, run.ID, &previous); err == nil && !previous.StartedAt.Equal(run.StartedAt) {
+			if err = tx.Bucket(runsByTimeBucket).Delete(timeKey(previous.StartedAt, previous.ID)); err != nil {
+				return err
+			}
+		}
+		if err := tx.Bucket(runsByTimeBucket).Put(timeKey(run.StartedAt, run.ID), []byte(run.ID)); err != nil {
+			return err
+		}
+		return put(tx, runsBucket, run.ID, run)
+	})
+}
+
+func (s *BoltStateStore) Run(id string) (*Run, error) {
+	var run Run
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return get(tx, runsBucket, id, &run)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &run, nil
+}
+
+// Runs reads runs from the newest, and stops as soon as the limit of the filter is reached
+func (s *BoltStateStore) Runs(filter RunFilter) ([]Run, error) {
+	var runs []Run
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return newestFirst(tx, runsByTimeBucket, func(id string) (bool, error) {
+			var run Run
+			if err := get(tx, runsBucket, id, &run); err != nil {
+				return false, err
+			}
+			if filter.matches(run) {
+				runs = append(runs, run)
+			}
+			return filter.Limit <= 0 || len(runs) < filter.Limit, nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return runs, nil
+}
+
+func (s *BoltStateStore) SaveStepResult(result StepResult) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return put(tx, stepResultsBucket, stepResultKey(result.RunID, result.Step), result)
+	})
+}
+
+func (s *BoltStateStore) StepResults(runID string) ([]StepResult, error) {
+	var output []StepResult
+	prefix := []byte(stepResultKey(runID, ""))
+	err := s.db.View(func(tx *bolt.Tx) error {
+		cursor := tx.Bucket(stepResultsBucket).Cursor()
+		for key, raw := cursor.Seek(prefix); key != nil && bytes.HasPrefix(key, prefix); key, raw = cursor.Next() {
+			var result StepResult
+			if err := json.Unmarshal(raw, &result); err != nil {
+				return err
+			}
+			output = append(output, result)
+		}
+		return nil
+	})
+	sortStepResults(output)
+	return output, err
+}
+
+func (s *BoltStateStore) SaveTriggerRegistration(registration TriggerRegistration) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return put(tx, registrationsBucket, registration.Trigger, registration)
+	})
+}
+
+func (s *BoltStateStore) DeleteTriggerRegistration(trigger string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(registrationsBucket).Delete([]byte(trigger))
+	})
+}
+
+func (s *BoltStateStore) TriggerRegistrations() ([]TriggerRegistration, error) {
+	var output []TriggerRegistration
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(registrationsBucket).ForEach(func(_, raw []byte) error {
+			var registration TriggerRegistration
+			if err := json.Unmarshal(raw, &registration); err != nil {
+				return err
+			}
+			output = append(output, registration)
+			return nil
+		})
+	})
+	sortTriggerRegistrations(output)
+	return output, err
+}
+
+func (s *BoltStateStore) Get(key string) ([]byte, error) {
+	var value []byte
+	err := s.db.View(func(tx *bolt.Tx) error {
+		raw := tx.Bucket(kvBucket).Get([]byte(key))
+		if raw == nil {
+			return ErrNotFound
+		}
+		// values returned by bbolt are only valid for the life of the transaction
+		value = append([]byte{}, raw...)
+		return nil
+	})
+	return value, err
+}
+
+func (s *BoltStateStore) Put(key string, value []byte) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(kvBucket).Put([]byte(key), value)
+	})
+}
+
+func (s *BoltStateStore) Delete(key string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(kvBucket).Delete([]byte(key))
+	})
+}
+
+func (s *BoltStateStore) Close() error {
+	return s.db.Close()
+}
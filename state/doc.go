@@ -0,0 +1,6 @@
+/*
+Package state provides persistence for everything switchboard needs to remember between restarts,
+such as deployed configurations, workflow runs and their step results, trigger registrations and
+arbitrary key/value data used by the runner.
+*/
+package state
@@ -0,0 +1,187 @@
+package state
+
+import (
+	"golang.org/x/exp/slices"
+	"sync"
+)
+
+// MemoryStateStore keeps all state in memory. It is meant for tests and short-lived commands
+// where nothing needs to survive a restart. Values are copied when saved and returned, so callers
+// never share them with the store.
+type MemoryStateStore struct {
+	mu            sync.RWMutex
+	deployments   map[string]Deployment
+	runs          map[string]Run
+	stepResults   map[string]map[string]StepResult
+	registrations map[string]TriggerRegistration
+	values        map[string][]byte
+}
+
+func NewMemoryStateStore() StateStore {
+	return &MemoryStateStore{
+		deployments:   make(map[string]Deployment),
+		runs:          make(map[string]Run),
+		stepResults:   make(map[string]map[string]StepResult),
+		registrations: make(map[string]TriggerRegistration),
+		values:        make(map[string][]byte),
+	}
+}
+
+func (s *MemoryStateStore) SaveDeployment(deployment Deployment) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.deployments[deployment.ID] = cloneDeployment(deployment)
+	return nil
+}
+
+func (s *MemoryStateStore) ActiveDeployment() (*Deployment, error) {
+	deployments, _ := s.Deployments()
+	if len(deployments) == 0 {
+		return nil, ErrNotFound
+	}
+	return &deployments[0], nil
+}
+
+func (s *MemoryStateStore) Deployments() ([]Deployment, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var output []Deployment
+	for _, deployment := range s.deployments {
+		output = append(output, cloneDeployment(deployment))
+	}
+	sortDeployments(output)
+	return output, nil
+}
+
+func (s *MemoryStateStore) SaveRun(run Run) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.runs[run.ID] = cloneRun(run)
+	return nil
+}
+
+func (s *MemoryStateStore) Run(id string) (*Run, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	run, ok := s.runs[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	run = cloneRun(run)
+	return &run, nil
+}
+
+func (s *MemoryStateStore) Runs(filter RunFilter) ([]Run, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var runs []Run
+	for _, run := range s.runs {
+		runs = append(runs, cloneRun(run))
+	}
+	sortRuns(runs)
+	return filterRuns(runs, filter), nil
+}
+
+func (s *MemoryStateStore) SaveStepResult(result StepResult) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.stepResults[result.RunID]; !ok {
+		s.stepResults[result.RunID] = make(map[string]StepResult)
+	}
+	s.stepResults[result.RunID][result.Step] = cloneStepResult(result)
+	return nil
+}
+
+func (s *MemoryStateStore) StepResults(runID string) ([]StepResult, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var output []StepResult
+	for _, result := range s.stepResults[runID] {
+		output = append(output, cloneStepResult(result))
+	}
+	sortStepResults(output)
+	return output, nil
+}
+
+func (s *MemoryStateStore) SaveTriggerRegistration(registration TriggerRegistration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.registrations[registration.Trigger] = cloneTriggerRegistration(registration)
+	return nil
+}
+
+func (s *MemoryStateStore) DeleteTriggerRegistration(trigger string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.registrations, trigger)
+	return nil
+}
+
+func (s *MemoryStateStore) TriggerRegistrations() ([]TriggerRegistration, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var output []TriggerRegistration
+	for _, registration := range s.registrations {
+		output = append(output, cloneTriggerRegistration(registration))
+	}
+	sortTriggerRegistrations(output)
+	return output, nil
+}
+
+func (s *MemoryStateStore) Get(key string) ([]byte, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	value, ok := s.values[key]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return slices.Clone(value), nil
+}
+
+func (s *MemoryStateStore) Put(key string, value []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.values[key] = slices.Clone(value)
+	return nil
+}
+
+func (s *MemoryStateStore) Delete(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.values, key)
+	return nil
+}
+
+func (s *MemoryStateStore) Close() error {
+	return nil
+}
+
+func cloneDeployment(deployment Deployment) Deployment {
+	deployment.Bundle = slices.Clone(deployment.Bundle)
+	return deployment
+}
+
+func cloneRun(run Run) Run {
+	run.Payload = slices.Clone(run.Payload)
+	if run.FinishedAt != nil {
+		finishedAt := *run.FinishedAt
+		run.FinishedAt = &finishedAt
+	}
+	return run
+}
+
+func cloneStepResult(result StepResult) StepResult {
+	result.Input = slices.Clone(result.Input)
+	result.Output = slices.Clone(result.Output)
+	result.Attempts = slices.Clone(result.Attempts)
+	if result.FinishedAt != nil {
+		finishedAt := *result.FinishedAt
+		result.FinishedAt = &finishedAt
+	}
+	return result
+}
+
+func cloneTriggerRegistration(registration TriggerRegistration) TriggerRegistration {
+	registration.Workflows = slices.Clone(registration.Workflows)
+	return registration
+}
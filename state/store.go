@@ -0,0 +1,171 @@
+package state
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"sort"
+	"time"
+)
+
+// ErrNotFound is returned by a StateStore when the requested item does not exist
+var ErrNotFound = errors.New("not found")
+
+// DefaultDirectory is where the persistent state store keeps its data, relative to the working directory
+const DefaultDirectory = "./.switchboard/state"
+
+type StateStore interface {
+	// SaveDeployment creates or updates a deployment. The deployment with the latest DeployedAt
+	// value is considered the active deployment.
+	SaveDeployment(Deployment) error
+	ActiveDeployment() (*Deployment, error)
+	// Deployments returns all deployments, newest first
+	Deployments() ([]Deployment, error)
+
+	SaveRun(Run) error
+	Run(id string) (*Run, error)
+	// Runs returns all runs matching the filter, newest first
+	Runs(filter RunFilter) ([]Run, error)
+
+	// SaveStepResult creates or updates the result of a single step in a run
+	SaveStepResult(StepResult) error
+	// StepResults returns all step results of a run, in the order they were started
+	StepResults(runID string) ([]StepResult, error)
+
+	SaveTriggerRegistration(TriggerRegistration) error
+	DeleteTriggerRegistration(trigger string) error
+	TriggerRegistrations() ([]TriggerRegistration, error)
+
+	// Get returns the value of a key, or ErrNotFound if it is not set
+	Get(key string) ([]byte, error)
+	Put(key string, value []byte) error
+	Delete(key string) error
+
+	Close() error
+}
+
+// Deployment is a configuration that was deployed to the runner
+type Deployment struct {
+	ID         string
+	DeployedAt time.Time
+	DeployedBy string
+	// Bundle is the packaged configuration that was deployed
+	Bundle []byte
+	// Hash is the content hash of the files in the bundle
+	Hash string
+}
+
+type RunStatus string
+
+const (
+	StatusPending   RunStatus = "pending"
+	StatusRunning   RunStatus = "running"
+	StatusSucceeded RunStatus = "succeeded"
+	StatusFailed    RunStatus = "failed"
+	// StatusSkipped is only used for step results, when the condition of a step was false or it depended on a skipped step
+	StatusSkipped RunStatus = "skipped"
+)
+
+// Run is a single execution of a workflow
+type Run struct {
+	ID           string
+	Workflow     string
+	DeploymentID string
+	Status       RunStatus
+	// Payload is the cty/json encoded trigger payload that started the run
+	Payload    []byte
+	Error      string
+	StartedAt  time.Time
+	FinishedAt *time.Time
+}
+
+// RunFilter limits the runs returned by StateStore.Runs. Zero values are ignored
+type RunFilter struct {
+	Workflow string
+	Status   RunStatus
+	Limit    int
+}
+
+func (f RunFilter) matches(run Run) bool {
+	return (f.Workflow == "" || f.Workflow == run.Workflow) && (f.Status == "" || f.Status == run.Status)
+}
+
+// StepResult is the result of a single step in a workflow run
+type StepResult struct {
+	RunID  string
+	Step   string
+	Status RunStatus
+	// Input and Output are cty/json encoded values
+	Input      []byte
+	Output     []byte
+	Error      string
+	Attempts   []Attempt
+	StartedAt  time.Time
+	FinishedAt *time.Time
+}
+
+// Attempt is a single call to a provider action made while processing a step
+type Attempt struct {
+	Number     int
+	Error      string
+	StartedAt  time.Time
+	FinishedAt time.Time
+}
+
+// TriggerRegistration records a trigger that was registered with its integration
+type TriggerRegistration struct {
+	Trigger  string
+	Provider string
+	// ExternalID is the identifier returned by the integration when the trigger was registered
+	ExternalID   string
+	Workflows    []string
+	RegisteredAt time.Time
+}
+
+// NewID generates a random identifier for runs and deployments
+func NewID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
+
+func sortDeployments(deployments []Deployment) {
+	sort.SliceStable(deployments, func(i, j int) bool {
+		return deployments[i].DeployedAt.After(deployments[j].DeployedAt)
+	})
+}
+
+func sortRuns(runs []Run) {
+	sort.SliceStable(runs, func(i, j int) bool {
+		return runs[i].StartedAt.After(runs[j].StartedAt)
+	})
+}
+
+func sortStepResults(results []StepResult) {
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].StartedAt.Before(results[j].StartedAt)
+	})
+}
+
+// filterRuns applies the filter to runs that are already sorted
+func filterRuns(runs []Run, filter RunFilter) []Run {
+	var output []Run
+	for _, run := range runs {
+		if !filter.matches(run) {
+			continue
+		}
+		output = append(output, run)
+		if filter.Limit > 0 && len(output) == filter.Limit {
+			break
+		}
+	}
+	return output
+}
+
+func sortTriggerRegistrations(registrations []TriggerRegistration) {
+	sort.SliceStable(registrations, func(i, j int) bool {
+		return registrations[i].Trigger < registrations[j].Trigger
+	})
+}
@@ -0,0 +1,233 @@
+package state
+
+import (
+	"errors"
+	bolt "go.etcd.io/bbolt"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func testStores(t *testing.T) map[string]StateStore {
+	bolt, err := NewBoltStateStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewBoltStateStore() error = %v", err)
+	}
+	t.Cleanup(func() { bolt.Close() })
+	return map[string]StateStore{
+		"bolt":   bolt,
+		"memory": NewMemoryStateStore(),
+	}
+}
+
+func TestStateStore_Runs(t *testing.T) {
+	now := time.Now().UTC().Truncate(time.Second)
+	runs := []Run{
+		{ID: "1", Workflow: "charge", Status: StatusSucceeded, StartedAt: now.Add(-3 * time.Minute)},
+		{ID: "2", Workflow: "refund", Status: StatusFailed, StartedAt: now.Add(-2 * time.Minute)},
+		{ID: "3", Workflow: "charge", Status: StatusFailed, StartedAt: now.Add(-1 * time.Minute)},
+	}
+	tests := []struct {
+		name    string
+		filter  RunFilter
+		wantIDs []string
+	}{
+		{
+			name:    "returns all runs newest first",
+			filter:  RunFilter{},
+			wantIDs: []string{"3", "2", "1"},
+		},
+		{
+			name:    "filters by workflow",
+			filter:  RunFilter{Workflow: "charge"},
+			wantIDs: []string{"3", "1"},
+		},
+		{
+			name:    "filters by status",
+			filter:  RunFilter{Status: StatusFailed},
+			wantIDs: []string{"3", "2"},
+		},
+		{
+			name:    "applies limit after filtering",
+			filter:  RunFilter{Workflow: "charge", Limit: 1},
+			wantIDs: []string{"3"},
+		},
+	}
+	for storeName, store := range testStores(t) {
+		for _, run := range runs {
+			if err := store.SaveRun(run); err != nil {
+				t.Fatalf("%s: SaveRun() error = %v", storeName, err)
+			}
+		}
+		for _, tt := range tests {
+			t.Run(storeName+"/"+tt.name, func(t *testing.T) {
+				got, err := store.Runs(tt.filter)
+				if err != nil {
+					t.Fatalf("Runs() error = %v", err)
+				}
+				var gotIDs []string
+				for _, run := range got {
+					gotIDs = append(gotIDs, run.ID)
+				}
+				if !reflect.DeepEqual(gotIDs, tt.wantIDs) {
+					t.Errorf("Runs() got = %v, want %v", gotIDs, tt.wantIDs)
+				}
+			})
+		}
+	}
+}
+
+func TestStateStore_roundTrip(t *testing.T) {
+	now := time.Now().UTC().Truncate(time.Second)
+	for storeName, store := range testStores(t) {
+		t.Run(storeName, func(t *testing.T) {
+			if _, err := store.ActiveDeployment(); !errors.Is(err, ErrNotFound) {
+				t.Errorf("ActiveDeployment() on empty store error = %v, want ErrNotFound", err)
+			}
+			old := Deployment{ID: "old", DeployedAt: now.Add(-time.Hour), Bundle: []byte("old")}
+			current := Deployment{ID: "current", DeployedAt: now, DeployedBy: "ci", Bundle: []byte("current")}
+			_ = store.SaveDeployment(current)
+			_ = store.SaveDeployment(old)
+			active, err := store.ActiveDeployment()
+			if err != nil || !reflect.DeepEqual(*active, current) {
+				t.Errorf("ActiveDeployment() got = %v, %v, want %v", active, err, current)
+			}
+
+			finished := now.Add(time.Second)
+			results := []StepResult{
+				{RunID: "run", Step: "second", Status: StatusRunning, StartedAt: now.Add(time.Second)},
+				{RunID: "run", Step: "first", Status: StatusSucceeded, Output: []byte(`"ok"`), StartedAt: now, FinishedAt: &finished,
+					Attempts: []Attempt{{Number: 1, StartedAt: now, FinishedAt: finished}}},
+				{RunID: "other", Step: "first", StartedAt: now},
+			}
+			for _, result := range results {
+				_ = store.SaveStepResult(result)
+			}
+			gotResults, err := store.StepResults("run")
+			if err != nil || !reflect.DeepEqual(gotResults, []StepResult{results[1], results[0]}) {
+				t.Errorf("StepResults() got = %v, %v", gotResults, err)
+			}
+
+			registration := TriggerRegistration{Trigger: "stripe_charge", Provider: "stripe", ExternalID: "we_1", Workflows: []string{"charge"}, RegisteredAt: now}
+			_ = store.SaveTriggerRegistration(registration)
+			gotRegistrations, _ := store.TriggerRegistrations()
+			if !reflect.DeepEqual(gotRegistrations, []TriggerRegistration{registration}) {
+				t.Errorf("TriggerRegistrations() got = %v", gotRegistrations)
+			}
+			_ = store.DeleteTriggerRegistration("stripe_charge")
+			if gotRegistrations, _ = store.TriggerRegistrations(); len(gotRegistrations) != 0 {
+				t.Errorf("TriggerRegistrations() after delete got = %v", gotRegistrations)
+			}
+
+			_ = store.Put("key", []byte("value"))
+			if value, err := store.Get("key"); err != nil || string(value) != "value" {
+				t.Errorf("Get() got = %s, %v", value, err)
+			}
+			_ = store.Delete("key")
+			if _, err := store.Get("key"); !errors.Is(err, ErrNotFound) {
+				t.Errorf("Get() after delete error = %v, want ErrNotFound", err)
+			}
+		})
+	}
+}
+
+func TestNewBoltStateStore_reopen(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewBoltStateStore(dir)
+	if err != nil {
+		t.Fatalf("NewBoltStateStore() error = %v", err)
+	}
+	run := Run{ID: "1", Workflow: "charge", Status: StatusRunning, StartedAt: time.Now().UTC().Truncate(time.Second)}
+	_ = store.SaveRun(run)
+	store.Close()
+
+	store, err = NewBoltStateStore(dir)
+	if err != nil {
+		t.Fatalf("NewBoltStateStore() reopen error = %v", err)
+	}
+	defer store.Close()
+	got, err := store.Run("1")
+	if err != nil || !reflect.DeepEqual(*got, run) {
+		t.Errorf("Run() after reopen got = %v, %v, want %v", got, err, run)
+	}
+}
+
+func TestNewBoltStateStore_migrateIndexes(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now().UTC().Truncate(time.Second)
+	deployments := []Deployment{
+		{ID: "old", DeployedAt: now.Add(-time.Hour), Bundle: []byte("old")},
+		{ID: "current", DeployedAt: now, Bundle: []byte("current")},
+	}
+	runs := []Run{
+		{ID: "1", Workflow: "charge", StartedAt: now.Add(-time.Minute)},
+		{ID: "2", Workflow: "charge", StartedAt: now},
+	}
+	// deployments and runs are written with the initial schema, which kept the bundle in the deployment
+	db, err := bolt.Open(filepath.Join(dir, "state.db"), 0o600, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = migrate(db, migrations[:1]); err != nil {
+		t.Fatalf("migrate() error = %v", err)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, deployment := range deployments {
+			if err := put(tx, deploymentsBucket, deployment.ID, deployment); err != nil {
+				return err
+			}
+		}
+		for _, run := range runs {
+			if err := put(tx, runsBucket, run.ID, run); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	db.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	store, err := NewBoltStateStore(dir)
+	if err != nil {
+		t.Fatalf("NewBoltStateStore() error = %v", err)
+	}
+	defer store.Close()
+	active, err := store.ActiveDeployment()
+	if err != nil || !reflect.DeepEqual(*active, deployments[1]) {
+		t.Errorf("ActiveDeployment() got = %v, %v, want %v", active, err, deployments[1])
+	}
+	gotRuns, err := store.Runs(RunFilter{Limit: 1})
+	if err != nil || !reflect.DeepEqual(gotRuns, []Run{runs[1]}) {
+		t.Errorf("Runs() got = %v, %v, want %v", gotRuns, err, runs[1:])
+	}
+}
+
+func TestMemoryStateStore_copies(t *testing.T) {
+	store := NewMemoryStateStore()
+	bundle := []byte("bundle")
+	_ = store.SaveDeployment(Deployment{ID: "1", Bundle: bundle})
+	bundle[0] = 'x'
+	active, _ := store.ActiveDeployment()
+	active.Bundle[1] = 'x'
+	if active, _ = store.ActiveDeployment(); string(active.Bundle) != "bundle" {
+		t.Errorf("ActiveDeployment() bundle = %s, want bundle", active.Bundle)
+	}
+
+	_ = store.SaveStepResult(StepResult{RunID: "run", Step: "charge", Output: []byte(`"ok"`), Attempts: []Attempt{{Number: 1}}})
+	results, _ := store.StepResults("run")
+	results[0].Output[1] = 'x'
+	results[0].Attempts[0].Number = 2
+	if results, _ = store.StepResults("run"); string(results[0].Output) != `"ok"` || results[0].Attempts[0].Number != 1 {
+		t.Errorf("StepResults() got = %v", results)
+	}
+
+	_ = store.Put("key", []byte("value"))
+	value, _ := store.Get("key")
+	value[0] = 'x'
+	if value, _ = store.Get("key"); string(value) != "value" {
+		t.Errorf("Get() got = %s, want value", value)
+	}
+}
@@ -0,0 +1,5 @@
+/*
+Package testrunner runs the declarative workflow tests in '*.sbtest.hcl' files. Every test runs a workflow
+with a payload against mocked provider actions, and checks the outcome with assertions.
+*/
+package testrunner
@@ -0,0 +1,91 @@
+package testrunner
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Time     string           `xml:"time,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name     string          `xml:"name,attr"`
+	Tests    int             `xml:"tests,attr"`
+	Failures int             `xml:"failures,attr"`
+	Time     string          `xml:"time,attr"`
+	Cases    []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// WriteJUnit writes test results as JUnit XML, with a test suite for every test file
+func WriteJUnit(w io.Writer, results []TestResult) error {
+	var output junitTestSuites
+	var total time.Duration
+	var durations []time.Duration
+	suites := make(map[string]int)
+	for _, result := range results {
+		index, ok := suites[result.File]
+		if !ok {
+			index = len(output.Suites)
+			suites[result.File] = index
+			output.Suites = append(output.Suites, junitTestSuite{Name: result.File})
+			durations = append(durations, 0)
+		}
+		suite := &output.Suites[index]
+		testCase := junitTestCase{
+			Name:      result.Name,
+			ClassName: result.Workflow,
+			Time:      junitTime(result.Duration),
+		}
+		if !result.Passed() {
+			testCase.Failure = &junitFailure{
+				Message: result.Failures[0],
+				Text:    strings.Join(result.Failures, "\n"),
+			}
+			suite.Failures++
+			output.Failures++
+		}
+		suite.Cases = append(suite.Cases, testCase)
+		suite.Tests++
+		output.Tests++
+		durations[index] += result.Duration
+		total += result.Duration
+	}
+	output.Time = junitTime(total)
+	for i := range output.Suites {
+		output.Suites[i].Time = junitTime(durations[i])
+	}
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(output); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+func junitTime(duration time.Duration) string {
+	return fmt.Sprintf("%.3f", duration.Seconds())
+}
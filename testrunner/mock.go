@@ -0,0 +1,44 @@
+package testrunner
+
+import (
+	"errors"
+	"fmt"
+	"github.com/switchboard-org/switchboard/internal"
+	"github.com/switchboard-org/switchboard/sbtest"
+)
+
+// mockProviders creates a fake provider for every required provider of a configuration. The actions
+// called by its steps fail until they are mocked.
+func mockProviders(config *internal.RootSwitchboardConfig) map[string]*sbtest.FakeProvider {
+	providers := make(map[string]*sbtest.FakeProvider)
+	for _, requiredProvider := range config.Switchboard.RequiredProviders {
+		providers[requiredProvider.Name] = sbtest.NewFakeProvider()
+	}
+	for _, workflow := range config.Workflows {
+		for _, steps := range [][]internal.StepBlock{workflow.Steps, workflow.OnFailure} {
+			for _, step := range steps {
+				pluginName, ok := config.ProviderPluginName(step.Provider)
+				if !ok || providers[pluginName] == nil {
+					continue
+				}
+				providers[pluginName].WithAction(step.Action, sbtest.FakeAction{
+					Failures: -1,
+					Err:      fmt.Errorf("action '%s' of provider '%s' is not mocked", step.Action, pluginName),
+				})
+			}
+		}
+	}
+	return providers
+}
+
+// mockAction returns a fake action that returns output, or fails with err if it is not empty
+func mockAction(output []byte, err string) sbtest.FakeAction {
+	if err != "" {
+		return sbtest.FakeAction{Failures: -1, Err: errors.New(err)}
+	}
+	return sbtest.FakeAction{
+		Evaluate: func(_ []byte) ([]byte, error) {
+			return output, nil
+		},
+	}
+}
@@ -0,0 +1,279 @@
+package testrunner
+
+import (
+	"context"
+	"fmt"
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/gohcl"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/switchboard-org/plugin-sdk/sbsdk"
+	"github.com/switchboard-org/switchboard/engine"
+	"github.com/switchboard-org/switchboard/internal"
+	"github.com/switchboard-org/switchboard/sbtest"
+	"github.com/switchboard-org/switchboard/state"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/convert"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
+	"strings"
+	"time"
+)
+
+type Runner interface {
+	// Run runs every test in a test file. Diagnostics are returned for invalid test files, while failed
+	// tests are reported in the results.
+	Run(ctx context.Context, path string) ([]TestResult, hcl.Diagnostics)
+}
+
+// TestResult is the outcome of a single test
+type TestResult struct {
+	Name     string
+	File     string
+	Workflow string
+	Duration time.Duration
+	// Run is nil if the workflow could not be run
+	Run *state.Run
+	// Failures are the messages of the failed assertions, or the run error if the test has no assertions
+	Failures []string
+}
+
+func (r *TestResult) Passed() bool {
+	return len(r.Failures) == 0
+}
+
+type testFileConfig struct {
+	Tests  []testConfig `hcl:"test,block"`
+	Remain hcl.Body     `hcl:",remain"`
+}
+
+type testConfig struct {
+	Name     string `hcl:"name,label"`
+	Workflow string `hcl:"workflow"`
+	// Payload is the trigger payload, an empty object if not set
+	Payload *hcl.Attribute `hcl:"payload"`
+	Mocks   []mockConfig   `hcl:"mock,block"`
+	Asserts []assertConfig `hcl:"assert,block"`
+	Remain  hcl.Body       `hcl:",remain"`
+}
+
+// mockConfig mocks a provider action, labeled with the provider name used in steps and the action name,
+// such as 'stripe.create_charge'. Mocks either return a value or fail with an error.
+type mockConfig struct {
+	Action  string         `hcl:"action,label"`
+	Returns *hcl.Attribute `hcl:"returns"`
+	Error   *string        `hcl:"error"`
+	Remain  hcl.Body       `hcl:",remain"`
+}
+
+// assertConfig is a condition that must be true after the workflow ran. It can reference the trigger
+// payload, the step values, and the status and error of the run. The condition is required.
+type assertConfig struct {
+	Condition *hcl.Attribute `hcl:"condition"`
+	Message   *string        `hcl:"message"`
+	Remain    hcl.Body       `hcl:",remain"`
+}
+
+type DefaultRunner struct {
+	config *internal.RootSwitchboardConfig
+}
+
+// NewDefaultRunner creates a runner for tests of a parsed configuration. Only the mocked actions are called,
+// so the configuration is usually parsed with parsecfg.WithMockedProviders.
+func NewDefaultRunner(config *internal.RootSwitchboardConfig) Runner {
+	return &DefaultRunner{config: mockConfiguration(config)}
+}
+
+func (r *DefaultRunner) Run(ctx context.Context, path string) ([]TestResult, hcl.Diagnostics) {
+	file, diag := hclparse.NewParser().ParseHCLFile(path)
+	if diag.HasErrors() {
+		return nil, diag
+	}
+	var config testFileConfig
+	diag = gohcl.DecodeBody(file.Body, nil, &config)
+	if diag.HasErrors() {
+		return nil, diag
+	}
+	var results []TestResult
+	var diagnostics hcl.Diagnostics
+	for _, test := range config.Tests {
+		result, diag := r.runTest(ctx, path, test)
+		diagnostics = diagnostics.Extend(diag)
+		if diag.HasErrors() {
+			continue
+		}
+		results = append(results, result)
+	}
+	return results, diagnostics
+}
+
+func (r *DefaultRunner) runTest(ctx context.Context, path string, test testConfig) (TestResult, hcl.Diagnostics) {
+	hclRange := test.Remain.MissingItemRange()
+	result := TestResult{
+		Name:     test.Name,
+		File:     path,
+		Workflow: test.Workflow,
+	}
+	workflow := r.config.Workflow(test.Workflow)
+	if workflow == nil {
+		return result, hcl.Diagnostics{diagnostic("unknown workflow", fmt.Sprintf("test '%s' runs workflow '%s', which does not exist", test.Name, test.Workflow), &hclRange)}
+	}
+	for _, assert := range test.Asserts {
+		if assert.Condition == nil {
+			assertRange := assert.Remain.MissingItemRange()
+			return result, hcl.Diagnostics{diagnostic("invalid assert", fmt.Sprintf("assert blocks of test '%s' must set a condition", test.Name), &assertRange)}
+		}
+	}
+	evalContext := r.config.EvalContext()
+	payload := cty.EmptyObjectVal
+	if test.Payload != nil {
+		var diag hcl.Diagnostics
+		payload, diag = test.Payload.Expr.Value(evalContext)
+		if diag.HasErrors() {
+			return result, diag
+		}
+	}
+	pluginManager, diag := r.mockPluginManager(test.Mocks, evalContext)
+	if diag.HasErrors() {
+		return result, diag
+	}
+
+	startedAt := time.Now()
+	store := state.NewMemoryStateStore()
+	e := engine.NewDefaultEngine(r.config, pluginManager, store)
+	if err := e.Start(); err != nil {
+		return result, hcl.Diagnostics{diagnostic("could not start engine", err.Error(), &hclRange)}
+	}
+	defer e.Stop()
+	run, err := e.RunWorkflow(ctx, workflow.Name, payload)
+	result.Duration = time.Since(startedAt)
+	if err != nil {
+		result.Failures = append(result.Failures, err.Error())
+		return result, nil
+	}
+	result.Run = run
+	stepResults, err := store.StepResults(run.ID)
+	if err != nil {
+		result.Failures = append(result.Failures, err.Error())
+		return result, nil
+	}
+	if len(test.Asserts) == 0 {
+		if run.Status != state.StatusSucceeded {
+			result.Failures = append(result.Failures, fmt.Sprintf("run %s: %s", run.Status, run.Error))
+		}
+		return result, nil
+	}
+	assertContext := internal.StepEvalContext(evalContext, payload, engine.StepValues(workflow, stepResults)).NewChild()
+	assertContext.Variables = map[string]cty.Value{
+		"run": cty.ObjectVal(map[string]cty.Value{
+			"status": cty.StringVal(string(run.Status)),
+			"error":  cty.StringVal(run.Error),
+		}),
+	}
+	for _, assert := range test.Asserts {
+		if failure := checkAssert(assert, assertContext); failure != "" {
+			result.Failures = append(result.Failures, failure)
+		}
+	}
+	return result, nil
+}
+
+// mockPluginManager creates a plugin manager that serves the mocked actions of a test
+func (r *DefaultRunner) mockPluginManager(mocks []mockConfig, evalContext *hcl.EvalContext) (*sbtest.FakePluginManager, hcl.Diagnostics) {
+	providers := mockProviders(r.config)
+	for _, mock := range mocks {
+		hclRange := mock.Remain.MissingItemRange()
+		providerName, actionName, ok := strings.Cut(mock.Action, ".")
+		if !ok {
+			return nil, hcl.Diagnostics{diagnostic("invalid mock", fmt.Sprintf("mock '%s' must be labeled with a provider and action, such as 'stripe.create_charge'", mock.Action), &hclRange)}
+		}
+		pluginName, ok := r.config.ProviderPluginName(providerName)
+		if !ok {
+			return nil, hcl.Diagnostics{diagnostic("invalid mock", fmt.Sprintf("mock '%s' uses provider '%s', which is not a provider or required_provider", mock.Action, providerName), &hclRange)}
+		}
+		if (mock.Returns == nil) == (mock.Error == nil) {
+			return nil, hcl.Diagnostics{diagnostic("invalid mock", fmt.Sprintf("mock '%s' must set either returns or error", mock.Action), &hclRange)}
+		}
+		var output []byte
+		var mockErr string
+		if mock.Error != nil {
+			mockErr = *mock.Error
+		} else {
+			value, diag := mock.Returns.Expr.Value(evalContext)
+			if diag.HasErrors() {
+				return nil, diag
+			}
+			var err error
+			output, err = ctyjson.SimpleJSONValue{Value: value}.MarshalJSON()
+			if err != nil {
+				return nil, hcl.Diagnostics{diagnostic("invalid mock", fmt.Sprintf("could not marshal the return value of mock '%s': %s", mock.Action, err), &hclRange)}
+			}
+		}
+		if providers[pluginName] == nil {
+			providers[pluginName] = sbtest.NewFakeProvider()
+		}
+		providers[pluginName].WithAction(actionName, mockAction(output, mockErr))
+	}
+	pluginManager := sbtest.NewFakePluginManager(nil)
+	for name, provider := range providers {
+		pluginManager.WithProvider(name, sbsdk.Provider(provider))
+	}
+	return pluginManager, nil
+}
+
+// checkAssert returns the failure message of an assertion, or an empty string if it passed
+func checkAssert(assert assertConfig, evalContext *hcl.EvalContext) string {
+	message := fmt.Sprintf("assertion at %s failed", assert.Condition.Range)
+	if assert.Message != nil {
+		message = *assert.Message
+	}
+	value, diag := assert.Condition.Expr.Value(evalContext)
+	if diag.HasErrors() {
+		return fmt.Sprintf("%s: %s", message, diag.Error())
+	}
+	value, err := convert.Convert(value, cty.Bool)
+	if err != nil || value.IsNull() || !value.IsKnown() {
+		return fmt.Sprintf("%s: condition must be true or false", message)
+	}
+	if value.False() {
+		return message
+	}
+	return ""
+}
+
+// mockConfiguration returns a copy of a configuration to run with mocked providers. Provider blocks are
+// initialized without their configuration, as mocked providers have no init schema, and failed actions
+// are retried without waiting.
+func mockConfiguration(config *internal.RootSwitchboardConfig) *internal.RootSwitchboardConfig {
+	output := *config
+	output.Providers = make([]internal.ProviderBlock, len(config.Providers))
+	for i, provider := range config.Providers {
+		provider.InitPayload = cty.EmptyObjectVal
+		output.Providers[i] = provider
+	}
+	output.Workflows = make([]internal.WorkflowBlock, len(config.Workflows))
+	for i, workflow := range config.Workflows {
+		workflow.Steps = withoutBackoff(workflow.Steps)
+		workflow.OnFailure = withoutBackoff(workflow.OnFailure)
+		output.Workflows[i] = workflow
+	}
+	return &output
+}
+
+func withoutBackoff(steps []internal.StepBlock) []internal.StepBlock {
+	output := make([]internal.StepBlock, len(steps))
+	for i, step := range steps {
+		step.Retry.InitialInterval = 0
+		step.Retry.MaxInterval = 0
+		step.Retry.Jitter = 0
+		output[i] = step
+	}
+	return output
+}
+
+func diagnostic(summary string, detail string, subject *hcl.Range) *hcl.Diagnostic {
+	return &hcl.Diagnostic{
+		Severity: hcl.DiagError,
+		Summary:  summary,
+		Detail:   detail,
+		Subject:  subject,
+	}
+}
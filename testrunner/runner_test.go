@@ -0,0 +1,118 @@
+package testrunner
+
+import (
+	"bytes"
+	"context"
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/switchboard-org/plugin-sdk/sbsdk"
+	"github.com/switchboard-org/switchboard/internal"
+	"github.com/switchboard-org/switchboard/parsecfg"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func testStep(t *testing.T, name string, action string, config string, inputType sbsdk.Type) internal.StepBlock {
+	file, diag := hclparse.NewParser().ParseHCL([]byte(config), "step.hcl")
+	if diag.HasErrors() {
+		t.Fatalf("could not parse step config: %v", diag)
+	}
+	outputType := sbsdk.Object(map[string]sbsdk.Type{"id": sbsdk.String})
+	return internal.StepBlock{
+		Name:         name,
+		Provider:     "stripe",
+		Action:       action,
+		Config:       file.Body,
+		ConfigSchema: sbsdk.ObjectSchema{"value": sbsdk.RequiredAttrSchema("value", inputType)},
+		OutputType:   outputType.ToCty(),
+		Retry:        internal.DefaultRetryBlock(),
+	}
+}
+
+func testConfiguration(t *testing.T) *internal.RootSwitchboardConfig {
+	receipt := testStep(t, "receipt", "send_receipt", `value = steps.charge.output.id`, sbsdk.String)
+	receipt.DependsOn = []string{"charge"}
+	return &internal.RootSwitchboardConfig{
+		Switchboard: internal.SwitchboardBlock{
+			RequiredProviders: []internal.RequiredProviderBlock{{Name: "stripe"}},
+		},
+		Workflows: []internal.WorkflowBlock{
+			{
+				Name: "checkout",
+				Steps: []internal.StepBlock{
+					testStep(t, "charge", "create_charge", `value = trigger.amount`, sbsdk.Number),
+					receipt,
+				},
+			},
+		},
+	}
+}
+
+func TestDefaultRunner_Run(t *testing.T) {
+	results, diag := NewDefaultRunner(testConfiguration(t)).Run(context.Background(), "../fixtures/workflow_tests/checkout.sbtest.hcl")
+	var summaries []string
+	for _, err := range diag.Errs() {
+		summaries = append(summaries, err.(*hcl.Diagnostic).Summary)
+	}
+	if want := []string{"unknown workflow", "invalid mock", "invalid assert"}; !reflect.DeepEqual(summaries, want) {
+		t.Errorf("Run() diagnostics = %v, want %v", summaries, want)
+	}
+	want := map[string][]string{
+		"charges the customer":                        nil,
+		"reports failed assertions":                   {"wrong receipt"},
+		"fails when the run fails without assertions": {"run failed: step 'charge' failed: card declined"},
+		"asserts on run failures":                     nil,
+	}
+	if len(results) != len(want) {
+		t.Fatalf("Run() got %d results, want %d", len(results), len(want))
+	}
+	for _, result := range results {
+		if !reflect.DeepEqual(result.Failures, want[result.Name]) {
+			t.Errorf("%s failures = %v, want %v", result.Name, result.Failures, want[result.Name])
+		}
+	}
+
+	var junit bytes.Buffer
+	if err := WriteJUnit(&junit, results); err != nil {
+		t.Fatalf("WriteJUnit() error = %v", err)
+	}
+	for _, expected := range []string{`<testsuites tests="4" failures="2"`, `<testcase name="charges the customer" classname="checkout"`, `<failure message="wrong receipt">wrong receipt</failure>`} {
+		if !strings.Contains(junit.String(), expected) {
+			t.Errorf("WriteJUnit() = %s, want it to contain %s", junit.String(), expected)
+		}
+	}
+}
+
+func TestDefaultRunner_Run_mockedProviders(t *testing.T) {
+	config, diag := parsecfg.NewDefaultParser("../fixtures/parse_config", "", "1.2.0", parsecfg.WithMockedProviders()).Parse()
+	if diag.HasErrors() {
+		t.Fatalf("Parse() diagnostics = %v", diag)
+	}
+	path := filepath.Join(t.TempDir(), "charge.sbtest.hcl")
+	src := `
+test "passes the mocked output on" {
+  workflow = "charge"
+
+  mock "stripe.create_charge" {
+    returns = { amount = "10", id = "ch_1" }
+  }
+
+  assert {
+    condition = steps.notify.output.amount == "10" && steps.notify.output.id == "ch_1"
+  }
+}
+`
+	if err := os.WriteFile(path, []byte(src), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	results, diag := NewDefaultRunner(config).Run(context.Background(), path)
+	if diag.HasErrors() {
+		t.Fatalf("Run() diagnostics = %v", diag)
+	}
+	if len(results) != 1 || !results[0].Passed() {
+		t.Errorf("Run() results = %+v, want a passed test", results)
+	}
+}